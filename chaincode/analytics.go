@@ -0,0 +1,398 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// views of voteanalytics
+const (
+	viewparticipation = "participation" // ballots and votes of every voter
+	vieworgturnout    = "orgturnout"    // turnout of orgs by month of end date
+	viewdecision      = "decision"      // votes of every voter the same as decision of closed ballot
+	vieworgagreement  = "orgagreement"  // closed ballots where majorities of two orgs are the same
+)
+
+// period of ballots with end date in unknown format
+const unknownperiod = "unknown"
+
+// row of analytics - key of row is after option of next page
+type analyticsrow interface {
+	key() string
+	record() []string
+}
+
+// struct for participation of voter
+type participationrow struct {
+	Voter   string  `json:"voter"`
+	Ballots int     `json:"ballots"` // ballots with voter in list of voters
+	Voted   int     `json:"voted"`
+	Percent float64 `json:"percent"`
+}
+
+// struct for turnout of org in period
+type orgperiodrow struct {
+	Period     string  `json:"period"` // yyyy-mm of end date
+	Org        string  `json:"org"`
+	Ballots    int     `json:"ballots"`    // ballots with voters of org
+	Registered int     `json:"registered"` // voters of org in lists of ballots
+	Voted      int     `json:"voted"`
+	Percent    float64 `json:"percent"`
+}
+
+// struct for agreement of voter with decisions
+type decisionrow struct {
+	Voter   string  `json:"voter"`
+	Decided int     `json:"decided"` // closed ballots of yes \ no decision with vote of voter
+	Agreed  int     `json:"agreed"`  // votes the same as decision
+	Percent float64 `json:"percent"`
+}
+
+// struct for agreement of two orgs, org1 < org2
+type orgpairrow struct {
+	Org1    string  `json:"org1"`
+	Org2    string  `json:"org2"`
+	Common  int     `json:"common"` // closed ballots with votes of both orgs
+	Agreed  int     `json:"agreed"` // ballots with the same majority of orgs
+	Percent float64 `json:"percent"`
+}
+
+func (r participationrow) key() string { return r.Voter }
+func (r orgperiodrow) key() string     { return r.Period + "|" + r.Org }
+func (r decisionrow) key() string      { return r.Voter }
+func (r orgpairrow) key() string       { return r.Org1 + "|" + r.Org2 }
+
+func (r participationrow) record() []string {
+	return []string{r.Voter, strconv.Itoa(r.Ballots), strconv.Itoa(r.Voted), formatpercent(r.Percent)}
+}
+func (r orgperiodrow) record() []string {
+	return []string{r.Period, r.Org, strconv.Itoa(r.Ballots), strconv.Itoa(r.Registered), strconv.Itoa(r.Voted), formatpercent(r.Percent)}
+}
+func (r decisionrow) record() []string {
+	return []string{r.Voter, strconv.Itoa(r.Decided), strconv.Itoa(r.Agreed), formatpercent(r.Percent)}
+}
+func (r orgpairrow) record() []string {
+	return []string{r.Org1, r.Org2, strconv.Itoa(r.Common), strconv.Itoa(r.Agreed), formatpercent(r.Percent)}
+}
+
+// csv header of views
+var analyticsheaders = map[string][]string{
+	viewparticipation: {"voter", "ballots", "voted", "percent"},
+	vieworgturnout:    {"period", "org", "ballots", "registered", "voted", "percent"},
+	viewdecision:      {"voter", "decided", "agreed", "percent"},
+	vieworgagreement:  {"org1", "org2", "common", "agreed", "percent"},
+}
+
+// struct for answer of voteanalytics in json
+type analyticspage struct {
+	View string         `json:"view"`
+	Rows []analyticsrow `json:"rows"`
+	Next string         `json:"next,omitempty"` // after option of next page, empty on last page
+}
+
+// options of voteanalytics
+type analyticsopts struct {
+	limit  int
+	after  string
+	format string // json \ csv
+	csv    csvopts
+}
+
+// parse voteanalytics args after view
+func analyticsoptsfromargs(args []string) (analyticsopts, error) {
+	opts := analyticsopts{format: "json", csv: defaultcsvopts()}
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		key, value := strings.ToLower(kv[0]), kv[1]
+
+		switch key {
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("limit %q is not positive number", value)
+			}
+			opts.limit = n
+		case "after":
+			opts.after = value
+		case "format":
+			format := strings.ToLower(value)
+			if format != "json" && format != "csv" {
+				return opts, fmt.Errorf("unknown format %q", value)
+			}
+			opts.format = format
+		default:
+			known, err := opts.csv.set(key, value)
+			if err != nil {
+				return opts, err
+			}
+			if !known {
+				return opts, fmt.Errorf("unknown option %q", kv[0])
+			}
+		}
+	}
+	return opts, nil
+}
+
+// analytics over all ballots of ledger in order of keys of rows, options limit=N and after=key give pages
+func (t *SimpleChaincode) voteanalytics(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	view := strings.ToLower(args[0])
+	header, ok := analyticsheaders[view]
+	if !ok {
+		return errbadargs("voteanalytics", fmt.Errorf("unknown view %q", args[0])).response()
+	}
+	opts, err := analyticsoptsfromargs(args[1:])
+	if err != nil {
+		return errbadargs("voteanalytics", err).response()
+	}
+
+	ballots, err := collectballots(stub)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	var rows []analyticsrow
+	switch view {
+	case viewparticipation:
+		rows = participation(ballots)
+	case vieworgturnout:
+		rows = orgturnoutbyperiod(ballots)
+	case viewdecision:
+		rows = decisionagreement(ballots)
+	case vieworgagreement:
+		rows = orgagreement(ballots)
+	}
+
+	page := analyticspage{View: view, Rows: []analyticsrow{}}
+	for _, r := range rows {
+		if r.key() <= opts.after {
+			continue
+		}
+		if opts.limit > 0 && len(page.Rows) == opts.limit {
+			page.Next = page.Rows[len(page.Rows)-1].key()
+			break
+		}
+		page.Rows = append(page.Rows, r)
+	}
+
+	if opts.format == "csv" {
+		records := [][]string{}
+		for _, r := range page.Rows {
+			records = append(records, r.record())
+		}
+		answer, err := writerecords(header, records, opts.csv)
+		if err != nil {
+			return errinternal(err).response()
+		}
+		return shim.Success(answer)
+	}
+
+	answer, _ := json.Marshal(page)
+	return shim.Success(answer)
+}
+
+// struct for ballot with its votes
+type ballotvotes struct {
+	voteid string
+	ballot *votelist
+	votes  []voterslist
+}
+
+// all ballots of ledger with votes, in order of ids
+func collectballots(stub shim.ChaincodeStubInterface) ([]ballotvotes, error) {
+	iter, err := stub.GetStateByRange("", string(utf8.MaxRune))
+	if err != nil {
+		return nil, errledger("", err)
+	}
+	defer iter.Close()
+
+	voteids := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, errledger("", err)
+		}
+		if !strings.Contains(kv.Key, "|") { // vote or result otherwise
+			voteids = append(voteids, kv.Key)
+		}
+	}
+
+	ballots := []ballotvotes{}
+	for _, voteid := range voteids {
+		votestruct, votearr, err := collectvotes(stub, voteid)
+		if err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ballotvotes{voteid: voteid, ballot: votestruct, votes: votearr})
+	}
+	return ballots, nil
+}
+
+// decision of closed ballot, ok is false for open ballots and for equal decision
+func (b ballotvotes) decision() (string, bool) {
+	if !b.ballot.Closed || b.ballot.cancelled() {
+		return "", false
+	}
+	decision, err := tally.Decide(countvotes(b.votes))
+	return decision, err == nil && decision != "equal"
+}
+
+func percent(part int, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
+
+func formatpercent(p float64) string {
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+// participation of every voter in order of voters
+func participation(ballots []ballotvotes) []analyticsrow {
+	byvoter := map[string]*participationrow{}
+	for _, b := range ballots {
+		for _, voter := range b.ballot.Voters {
+			if byvoter[voter] == nil {
+				byvoter[voter] = &participationrow{Voter: voter}
+			}
+			byvoter[voter].Ballots++
+		}
+		for _, v := range b.votes {
+			byvoter[v.Voter].Voted++
+		}
+	}
+
+	rows := []analyticsrow{}
+	for _, r := range byvoter {
+		r.Percent = percent(r.Voted, r.Ballots)
+		rows = append(rows, *r)
+	}
+	return sortrows(rows)
+}
+
+// turnout of orgs by month of end date of ballots, in order of periods and orgs
+func orgturnoutbyperiod(ballots []ballotvotes) []analyticsrow {
+	bykey := map[string]*orgperiodrow{}
+	for _, b := range ballots {
+		period := unknownperiod
+		if deadline, ok := tally.Deadline(b.ballot.EndDate); ok {
+			period = deadline.Format("2006-01")
+		}
+
+		voted := map[string]bool{}
+		for _, v := range b.votes {
+			voted[v.Voter] = true
+		}
+		orgs := voterorgs(b.ballot, b.votes)
+		seen := map[string]bool{}
+		for _, voter := range b.ballot.Voters {
+			org := orgs[voter]
+			r := bykey[period+"|"+org]
+			if r == nil {
+				r = &orgperiodrow{Period: period, Org: org}
+				bykey[period+"|"+org] = r
+			}
+			if !seen[org] {
+				seen[org] = true
+				r.Ballots++
+			}
+			r.Registered++
+			if voted[voter] {
+				r.Voted++
+			}
+		}
+	}
+
+	rows := []analyticsrow{}
+	for _, r := range bykey {
+		r.Percent = percent(r.Voted, r.Registered)
+		rows = append(rows, *r)
+	}
+	return sortrows(rows)
+}
+
+// votes of every voter against decisions of closed ballots, in order of voters
+func decisionagreement(ballots []ballotvotes) []analyticsrow {
+	byvoter := map[string]*decisionrow{}
+	for _, b := range ballots {
+		decision, ok := b.decision()
+		if !ok {
+			continue
+		}
+		for _, v := range b.votes {
+			if byvoter[v.Voter] == nil {
+				byvoter[v.Voter] = &decisionrow{Voter: v.Voter}
+			}
+			byvoter[v.Voter].Decided++
+			if v.Vote == decision {
+				byvoter[v.Voter].Agreed++
+			}
+		}
+	}
+
+	rows := []analyticsrow{}
+	for _, r := range byvoter {
+		r.Percent = percent(r.Agreed, r.Decided)
+		rows = append(rows, *r)
+	}
+	return sortrows(rows)
+}
+
+// agreement of every pair of orgs on closed ballots: majority of votes of org, yes minus no
+// as for decision of ballot, is the same for both orgs. In order of pairs
+func orgagreement(ballots []ballotvotes) []analyticsrow {
+	bypair := map[string]*orgpairrow{}
+	for _, b := range ballots {
+		if !b.ballot.Closed || b.ballot.cancelled() {
+			continue
+		}
+
+		votesbyorg := map[string][]string{}
+		for _, v := range b.votes {
+			votesbyorg[v.Org] = append(votesbyorg[v.Org], v.Vote)
+		}
+		orgs, majority := []string{}, map[string]string{}
+		for org, votes := range votesbyorg {
+			orgs = append(orgs, org)
+			majority[org], _ = tally.Decide(tally.Count(votes))
+		}
+		sort.Strings(orgs)
+
+		for i, org1 := range orgs {
+			for _, org2 := range orgs[i+1:] {
+				r := bypair[org1+"|"+org2]
+				if r == nil {
+					r = &orgpairrow{Org1: org1, Org2: org2}
+					bypair[org1+"|"+org2] = r
+				}
+				r.Common++
+				if majority[org1] == majority[org2] {
+					r.Agreed++
+				}
+			}
+		}
+	}
+
+	rows := []analyticsrow{}
+	for _, r := range bypair {
+		r.Percent = percent(r.Agreed, r.Common)
+		rows = append(rows, *r)
+	}
+	return sortrows(rows)
+}
+
+// rows in order of keys
+func sortrows(rows []analyticsrow) []analyticsrow {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].key() < rows[j].key() })
+	return rows
+}
@@ -0,0 +1,108 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestVoteAnalytics(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	// Vote1 of April: yes by org1 and org2, no by org3 - closed with yes
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "Vote1", voter1, voter2, voter3)
+	castvote(t, stub, "Org1MSP", stubsert1, "Vote1", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "Vote1", "yes", "")
+	castvote(t, stub, "Org3MSP", stubsert3, "Vote1", "no", "")
+	// Vote2 of May: no by org1, org2 does not vote - closed with no
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote2", "https://git.repo", "02.05.2099", voter1, voter2)); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	castvote(t, stub, "Org1MSP", stubsert1, "Vote2", "no", "")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	for _, voteid := range []string{"Vote1", "Vote2"} {
+		if res := stub.MockInvoke("1", argsbytes("voteend", voteid)); res.Status != shim.OK {
+			t.Fatal(res.Message)
+		}
+	}
+	// Vote3 is open, it has no decision yet
+	startvoting(t, stub, "Vote3", voter2, voter3, "org=Org3MSP:"+voter3)
+	castvote(t, stub, "Org2MSP", stubsert2, "Vote3", "no", "")
+
+	var participation struct{ Rows []participationrow }
+	query(t, stub, &participation, "voteanalytics", "participation")
+	expected := []participationrow{{voter1, 2, 2, 100}, {voter2, 3, 2, 200.0 / 3}, {voter3, 2, 1, 50}}
+	if len(participation.Rows) != len(expected) {
+		t.Fatalf("participation %+v", participation.Rows)
+	}
+	for i, r := range expected {
+		if participation.Rows[i] != r {
+			t.Errorf("participation of %s: %+v, expected %+v", r.Voter, participation.Rows[i], r)
+		}
+	}
+
+	var turnout struct{ Rows []orgperiodrow }
+	query(t, stub, &turnout, "voteanalytics", "orgturnout")
+	keys := []string{}
+	for _, r := range turnout.Rows {
+		keys = append(keys, r.key())
+	}
+	if strings.Join(keys, " ") != "2099-04|Org1MSP 2099-04|Org2MSP 2099-04|Org3MSP 2099-05|Org1MSP 2099-05|unknown" {
+		t.Errorf("periods of orgs %v", keys)
+	}
+	if r := turnout.Rows[1]; r.Ballots != 2 || r.Registered != 2 || r.Voted != 2 {
+		t.Errorf("turnout of Org2MSP in April %+v", r) // Vote1 and Vote3
+	}
+	if r := turnout.Rows[4]; r.Registered != 1 || r.Voted != 0 || r.Percent != 0 {
+		t.Errorf("turnout of voter without org %+v", r)
+	}
+
+	var decision struct{ Rows []decisionrow }
+	query(t, stub, &decision, "voteanalytics", "decision")
+	if len(decision.Rows) != 3 || decision.Rows[0] != (decisionrow{voter1, 2, 2, 100}) || decision.Rows[2] != (decisionrow{voter3, 1, 0, 0}) {
+		t.Errorf("agreement with decision %+v", decision.Rows)
+	}
+
+	var pairs struct{ Rows []orgpairrow }
+	query(t, stub, &pairs, "voteanalytics", "orgagreement")
+	if len(pairs.Rows) != 3 || pairs.Rows[0] != (orgpairrow{"Org1MSP", "Org2MSP", 1, 1, 100}) || pairs.Rows[1] != (orgpairrow{"Org1MSP", "Org3MSP", 1, 0, 0}) {
+		t.Errorf("agreement of orgs %+v", pairs.Rows)
+	}
+
+	// pages
+	var page struct {
+		Rows []participationrow
+		Next string
+	}
+	query(t, stub, &page, "voteanalytics", "participation", "limit=2")
+	if len(page.Rows) != 2 || page.Next != voter2 {
+		t.Fatalf("first page %+v", page)
+	}
+	page.Next = ""
+	query(t, stub, &page, "voteanalytics", "participation", "limit=2", "after="+voter2)
+	if len(page.Rows) != 1 || page.Rows[0].Voter != voter3 || page.Next != "" {
+		t.Errorf("last page %+v", page)
+	}
+
+	// csv
+	res := stub.MockInvoke("1", argsbytes("voteanalytics", "orgagreement", "format=csv", "header=true", "delimiter=comma", "lineend=lf"))
+	if res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	csv := "org1,org2,common,agreed,percent\nOrg1MSP,Org2MSP,1,1,100.00\nOrg1MSP,Org3MSP,1,0,0.00\nOrg2MSP,Org3MSP,1,0,0.00\n"
+	if string(res.Payload) != csv {
+		t.Errorf("csv of orgagreement:\n%s", res.Payload)
+	}
+
+	for _, args := range [][]string{{"voteanalytics", "favourites"}, {"voteanalytics", "decision", "limit=0"}, {"voteanalytics", "decision", "format=xml"}, {"voteanalytics", "decision", "voter=x"}} {
+		if res := stub.MockInvoke("1", argsbytes(args...)); res.Status != statusbadrequest {
+			t.Errorf("%v: %d %s", args, res.Status, res.Message)
+		}
+	}
+}
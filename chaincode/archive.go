@@ -0,0 +1,102 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// struct for counted vote in archived record, without comment and ack
+type archivedvote struct {
+	Voter string `json:"voter"`
+	Org   string `json:"org,omitempty"`
+	Vote  string `json:"vote"`
+	TxID  string `json:"txid"`
+}
+
+// struct for compact record of archived ballot - vote keys are deleted from state, ledger history keeps them
+type ballotarchive struct {
+	Counts     map[string]int `json:"counts"`
+	Decision   string         `json:"decision"`
+	Algorithm  string         `json:"algorithm"`
+	MerkleRoot string         `json:"merkleroot"` // root over votes as they were counted before archive
+	Votes      []archivedvote `json:"votes"`      // in order of leaves
+	Pruned     int            `json:"pruned"`     // keys deleted from state
+}
+
+// compact record of ballot over its counted votes
+func newarchive(voteid string, votearr []voterslist) (*ballotarchive, error) {
+	counts := countvotes(votearr)
+	decision, err := tally.Decide(counts)
+	if err != nil {
+		return nil, errcountfailed(voteid, err)
+	}
+
+	cert := certifyvotes(voteid, votearr)
+	a := &ballotarchive{Counts: counts, Decision: decision, Algorithm: cert.Algorithm, MerkleRoot: cert.MerkleRoot, Votes: []archivedvote{}}
+	for _, v := range votearr {
+		a.Votes = append(a.Votes, archivedvote{Voter: v.Voter, Org: v.Org, Vote: v.Vote, TxID: v.TxID})
+	}
+	return a, nil
+}
+
+// votes of archived record, as collectvotes reads them from state before archive
+func (a *ballotarchive) votes() []voterslist {
+	votearr := []voterslist{}
+	for _, v := range a.Votes {
+		votearr = append(votearr, voterslist{Voter: v.Voter, Org: v.Org, Vote: v.Vote, TxID: v.TxID})
+	}
+	return votearr
+}
+
+// certificate of archived votes, root is the one counted before archive
+func (a *ballotarchive) certificate() votecertificate {
+	cert := votecertificate{Algorithm: a.Algorithm, MerkleRoot: a.MerkleRoot, Leaves: len(a.Votes), TxIDs: []string{}}
+	for _, v := range a.Votes {
+		cert.TxIDs = append(cert.TxIDs, v.TxID)
+	}
+	return cert
+}
+
+// archived vote of voter by tx, nil if archive has no such vote
+func (a *ballotarchive) vote(voter string, txid string) *archivedvote {
+	for i, v := range a.Votes {
+		if v.Voter == voter && v.TxID == txid {
+			return &a.Votes[i]
+		}
+	}
+	return nil
+}
+
+// delete vote keys and result of ballot from state, returns number of deleted keys.
+// Vote key endorsed by org of its voter is handed over to endorsers of ballot, which endorse archive, before delete
+func prunevotes(stub shim.ChaincodeStubInterface, voteid string, votestruct *votelist) (int, error) {
+	keys := []string{voteid + "|result"}
+	for _, voter := range votestruct.Voters {
+		keys = append(keys, voteid+"|"+voter)
+	}
+
+	pruned := 0
+	for _, key := range keys {
+		value, err := stub.GetState(key)
+		if err != nil {
+			return pruned, errledger(key, err)
+		}
+		if value == nil {
+			continue
+		}
+		ep, err := stub.GetStateValidationParameter(key)
+		if err != nil {
+			return pruned, errledger(key, err)
+		}
+		if ep != nil {
+			if err := resetendorsement(stub, key, votestruct.endorsers()...); err != nil {
+				return pruned, err
+			}
+		}
+		if err := stub.DelState(key); err != nil {
+			return pruned, errledger(key, err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
@@ -0,0 +1,77 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestVoteArchive(t *testing.T) {
+	stub := newhistorystub()
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.invoke("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, voter2, voter3); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	res := stub.invoke("vote", "VoteHash", "yes", "ship it")
+	var receipt votereceipt
+	json.Unmarshal(res.Payload, &receipt)
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	stub.invoke("vote", "VoteHash", "no", "")
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.invoke("votearchive", "VoteHash"); res.Status != statusconflict {
+		t.Errorf("archive of open: %d %s", res.Status, res.Message)
+	}
+	stub.invoke("voteend", "VoteHash")
+	res = stub.invoke("voteresult", "VoteHash")
+	var published votereport
+	json.Unmarshal(res.Payload, &published)
+
+	if res := stub.invoke("votearchive", "VoteHash", "end of quarter"); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+
+	// vote keys and result are deleted, history keeps them
+	for _, key := range []string{"VoteHash|" + voter1, "VoteHash|" + voter2, "VoteHash|result"} {
+		if v, _ := stub.GetState(key); v != nil {
+			t.Errorf("key %s is not deleted", key)
+		}
+		if h := stub.history[key]; len(h) == 0 || !h[len(h)-1].IsDelete {
+			t.Errorf("history of %s has no delete", key)
+		}
+	}
+
+	var b ballotview
+	query(t, stub.MockStub, &b, "voteinfo", "VoteHash")
+	a := b.Archive
+	if a == nil || a.Pruned != 3 || a.Decision != "equal" || a.Counts["yes"] != 1 || a.MerkleRoot != published.Certificate.MerkleRoot || len(a.Votes) != 2 {
+		t.Fatalf("archived record %+v", a)
+	}
+
+	// queries of archived ballot answer by its record, voteresult writes nothing
+	res = stub.invoke("voteresult", "VoteHash")
+	var rep votereport
+	if err := json.Unmarshal(res.Payload, &rep); err != nil {
+		t.Fatal(res.Message)
+	}
+	if rep.Certificate.MerkleRoot != published.Certificate.MerkleRoot || rep.Turnout.Voted != 2 || len(rep.Abstained) != 1 || rep.State != statearchived {
+		t.Errorf("report of archived %+v", rep)
+	}
+	if v, _ := stub.GetState("VoteHash|result"); v != nil {
+		t.Errorf("voteresult of archived writes result")
+	}
+	var st votestatus
+	query(t, stub.MockStub, &st, "votestatus", "VoteHash")
+	if st.Status != statearchived || st.Turnout.Voted != 2 {
+		t.Errorf("status of archived %+v", st)
+	}
+	if res := stub.invoke("voteproof", "VoteHash", voter1); res.Status != statusconflict || !strings.HasPrefix(res.Message, codeinvalidstate) {
+		t.Errorf("voteproof of archived: %d %s", res.Status, res.Message)
+	}
+
+	checkreceiptstatus(t, stub, receipt, constreceiptarchived, true)
+}
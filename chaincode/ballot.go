@@ -0,0 +1,258 @@
+package chaincode
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// struct for artifact of proposal, pinned by sha256 of content or git commit
+type artifact struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"` // hex of sha256 of content
+	Commit string `json:"commit,omitempty"` // hash of git commit
+}
+
+// parse artifact of votestart: name@sha256:<64 hex> or name@git:<40 or 64 hex>
+func artifactfromarg(value string) (artifact, error) {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return artifact{}, fmt.Errorf("artifact %q is not name@sha256:digest or name@git:commit", value)
+	}
+
+	a := artifact{Name: value[:at]}
+	kind := strings.SplitN(value[at+1:], ":", 2)
+	if len(kind) != 2 {
+		return artifact{}, fmt.Errorf("artifact %q is not name@sha256:digest or name@git:commit", value)
+	}
+	digest := strings.ToLower(kind[1])
+	if _, err := hex.DecodeString(digest); err != nil {
+		return artifact{}, fmt.Errorf("artifact %s: digest is not hex", a.Name)
+	}
+
+	switch strings.ToLower(kind[0]) {
+	case "sha256":
+		if len(digest) != 64 {
+			return artifact{}, fmt.Errorf("artifact %s: sha256 is to be 64 hex symbols", a.Name)
+		}
+		a.SHA256 = digest
+	case "git":
+		if len(digest) != 40 && len(digest) != 64 {
+			return artifact{}, fmt.Errorf("artifact %s: git commit is to be 40 or 64 hex symbols", a.Name)
+		}
+		a.Commit = digest
+	default:
+		return artifact{}, fmt.Errorf("artifact %s: unknown digest %q", a.Name, kind[0])
+	}
+
+	return a, nil
+}
+
+// digest which voters acknowledge - sha256 or commit
+func (a artifact) digest() string {
+	if a.SHA256 != "" {
+		return a.SHA256
+	}
+	return a.Commit
+}
+
+// set metadata of ballot by key=value arg of votestart
+func (v *votelist) setmeta(arg string) error {
+	kv := strings.SplitN(arg, "=", 2)
+	key, value := strings.ToLower(kv[0]), kv[1]
+
+	switch key {
+	case "title":
+		v.Title = value
+	case "description":
+		v.Description = value
+	case "proposer":
+		v.Proposer = value
+	case "tag":
+		v.Tags = append(v.Tags, value)
+	case "tags":
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				v.Tags = append(v.Tags, tag)
+			}
+		}
+	case "artifact":
+		a, err := artifactfromarg(value)
+		if err != nil {
+			return err
+		}
+		v.Artifacts = append(v.Artifacts, a)
+	case "requireack":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("option requireack: %s", err)
+		}
+		v.RequireAck = flag
+	case "quorum":
+		quorum, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || quorum <= 0 || quorum > 100 {
+			return fmt.Errorf("option quorum: %q is not percent in (0, 100]", value)
+		}
+		v.Quorum = quorum
+	case "draft":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("option draft: %s", err)
+		}
+		v.draft = flag
+	case "reason":
+		v.reason = value
+	case "start":
+		if _, ok := tally.Opening(value); !ok {
+			return fmt.Errorf("option start: %q is not date", value)
+		}
+		v.StartDate = value
+	case "majority":
+		majority, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || majority <= 0 || majority > 100 {
+			return fmt.Errorf("option majority: %q is not percent in (0, 100]", value)
+		}
+		v.Majority = majority
+	case "runoff":
+		if err := checkrunoffend(value); err != nil {
+			return err
+		}
+		v.RunoffEnd = value
+	case "maxextend":
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("option maxextend: %q is not positive duration", value)
+		}
+		v.MaxExtend = value
+	case "sponsor":
+		v.Sponsors = append(v.Sponsors, value)
+	case "approvals":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("option approvals: %q is not positive number", value)
+		}
+		v.Approvals = n
+	case "endorser":
+		if value == "" {
+			return fmt.Errorf("option endorser: mspid is empty")
+		}
+		v.Endorsers = append(v.Endorsers, value)
+	case "group":
+		if err := checkgroupname(value); err != nil {
+			return fmt.Errorf("option group: %s", err)
+		}
+		v.Groups = append(v.Groups, groupref{Name: value})
+	case "org":
+		kv := strings.SplitN(value, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("option org: %q is not mspid:voter,voter", value)
+		}
+		if v.Orgs == nil {
+			v.Orgs = map[string]string{}
+		}
+		for _, voter := range strings.Split(kv[1], ",") {
+			if voter = strings.TrimSpace(voter); voter != "" {
+				v.Orgs[voter] = kv[0]
+			}
+		}
+	default:
+		return fmt.Errorf("unknown option %q", kv[0])
+	}
+
+	return nil
+}
+
+// check metadata after all args of votestart
+func (v *votelist) checkmeta() error {
+	if v.RequireAck && len(v.Artifacts) == 0 {
+		return fmt.Errorf("requireack without artifacts to acknowledge")
+	}
+	if len(v.Groups) == 0 { // members of groups are voters after resolvegroups
+		if err := v.checkorgs(); err != nil {
+			return err
+		}
+	}
+	if v.Approvals > len(v.Sponsors)+1 {
+		return fmt.Errorf("option approvals: %d is more than orgs of proposer and sponsors", v.Approvals)
+	}
+	opening, _ := tally.Opening(v.StartDate)
+	if deadline, ok := tally.Deadline(v.EndDate); ok && v.StartDate != "" && !opening.Before(deadline) {
+		return fmt.Errorf("option start: %s is not before end date %s", v.StartDate, v.EndDate)
+	}
+	if runoff, ok := v.runoffdeadline(); ok {
+		if deadline, _ := tally.Deadline(v.EndDate); !runoff.After(deadline) {
+			return fmt.Errorf("option runoff: %s is not after end date %s", v.RunoffEnd, v.EndDate)
+		}
+	}
+	return nil
+}
+
+// check that voters of orgs option are voters of ballot, and key orgs by spelling of list of voters
+func (v *votelist) checkorgs() error {
+	orgs := make(map[string]string, len(v.Orgs))
+	for voter, org := range v.Orgs {
+		listed, ok := v.listedvoter(voter)
+		if !ok {
+			return fmt.Errorf("option org: %s is not in list of voters", voter)
+		}
+		orgs[listed] = org
+	}
+	if v.Orgs != nil {
+		v.Orgs = orgs
+	}
+	return nil
+}
+
+// orgs whose peers endorse updates of ballot key: org of creator and orgs of endorser option
+func (v *votelist) endorsers() []string {
+	orgs := []string{}
+	if len(v.Transitions) > 0 {
+		orgs = append(orgs, v.Transitions[0].Org)
+	}
+	return append(orgs, v.Endorsers...)
+}
+
+// digests of vote arg, comma separated, as hex without sha256: or git: prefix
+func ackfromarg(arg string) []string {
+	ack := []string{}
+	for _, digest := range strings.Split(arg, ",") {
+		digest = strings.ToLower(strings.TrimSpace(digest))
+		if i := strings.Index(digest, ":"); i >= 0 { // sha256:... or git:...
+			digest = digest[i+1:]
+		}
+		if digest != "" {
+			ack = append(ack, digest)
+		}
+	}
+	return ack
+}
+
+// check digests acknowledged by voter: each is digest of artifact,
+// and every artifact is acknowledged if ballot requires it
+func (v *votelist) checkack(ack []string) error {
+	known := map[string]bool{}
+	for _, a := range v.Artifacts {
+		known[a.digest()] = true
+	}
+
+	acked := map[string]bool{}
+	for _, digest := range ack {
+		if !known[digest] {
+			return fmt.Errorf("digest %s is not a digest of artifact", digest)
+		}
+		acked[digest] = true
+	}
+
+	if !v.RequireAck {
+		return nil
+	}
+	for _, a := range v.Artifacts {
+		if !acked[a.digest()] {
+			return fmt.Errorf("artifact %s is not acknowledged, expected digest %s", a.Name, a.digest())
+		}
+	}
+	return nil
+}
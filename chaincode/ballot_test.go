@@ -0,0 +1,78 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+const specdigest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+const repocommit = "3f786850e387550fdab836ed7e6dc881de23001b"
+
+func TestBallot_Metadata(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	voter := certvoter(t, stubsert1)
+
+	startvoting(t, stub, "VoteHash", voter,
+		"title=Release 2.0", "description=Merge release branch", "tags=release, q2", "tag=infra",
+		"artifact=spec.pdf@sha256:"+specdigest, "artifact=repo@git:"+repocommit)
+
+	rep := queryreport(t, stub)
+	if rep.Title != "Release 2.0" || rep.Description != "Merge release branch" || rep.Proposer != voter {
+		t.Errorf("wrong metadata: %+v", rep)
+	}
+	if len(rep.Tags) != 3 || rep.Tags[2] != "infra" {
+		t.Errorf("wrong tags: %v", rep.Tags)
+	}
+	if len(rep.Artifacts) != 2 || rep.Artifacts[0].SHA256 != specdigest || rep.Artifacts[1].Commit != repocommit {
+		t.Errorf("wrong artifacts: %+v", rep.Artifacts)
+	}
+	if rep.Turnout.Registered != 1 {
+		t.Errorf("metadata is counted as voters: %+v", rep.Turnout)
+	}
+
+	for _, meta := range []string{"artifact=spec.pdf", "artifact=spec.pdf@md5:abc", "artifact=spec.pdf@sha256:abc", "requireack=true", "colour=red"} {
+		res := stub.MockInvoke("1", argsbytes("votestart", "Bad", "https://git.repo", "10.04.2099.10.00", voter, meta))
+		if res.Status == shim.OK {
+			t.Errorf("votestart with %s is accepted", meta)
+		}
+	}
+}
+
+func TestBallot_RequireAck(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+
+	startvoting(t, stub, "VoteHash", certvoter(t, stubsert1), "requireack=true",
+		"artifact=spec.pdf@sha256:"+specdigest, "artifact=repo@git:"+repocommit)
+
+	for _, ack := range []string{"", specdigest, specdigest + ",0000"} {
+		res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes", "ok", ack))
+		if res.Status != 403 {
+			t.Errorf("vote with ack %q: status %d, expected 403", ack, res.Status)
+		}
+	}
+
+	res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes", "ok", "sha256:"+specdigest+", git:"+repocommit))
+	if res.Status != shim.OK {
+		t.Fatalf("vote with ack failed: %s", res.Message)
+	}
+
+	val, _ := stub.GetState("VoteHash|" + certvoter(t, stubsert1))
+	var v voterslist
+	if err := json.Unmarshal(val, &v); err != nil || len(v.Ack) != 2 || v.Ack[1] != repocommit {
+		t.Errorf("ack is not written with vote: %s", val)
+	}
+}
+
+func TestBytesToVoteList_Legacy(t *testing.T) {
+	v := bytesToVoteList([]byte("{https://git.repo 10.04.2099.10.00 [ca.org1.example.com org2.smple.for.test]}"))
+	if v.RepoURL != "https://git.repo" || v.EndDate != "10.04.2099.10.00" || len(v.Voters) != 2 || v.Voters[1] != "org2.smple.for.test" {
+		t.Errorf("legacy voting parsed wrong: %+v", v)
+	}
+}
@@ -0,0 +1,219 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	id "github.com/s7techlab/cckit/identity"
+)
+
+// reserved key of configuration - ballot ids can't have |, so it is not a ballot and not a vote
+const configkey = "|config"
+
+// identity modes - which common name of certificate of invoker is its name as voter
+const (
+	identityissuer  = "issuer" // common name of issuer, as voters were named before config
+	identitysubject = "subject"
+)
+
+// tally rules of ballots which set no majority
+const (
+	tallyplurality = "plurality" // option of most votes decides
+	tallymajority  = "majority"  // option of decision needs majority percent of counted votes
+)
+
+// struct for migration applied by Init, in log of config
+type appliedmigration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Records int    `json:"records"` // keys rewritten
+	At      string `json:"at"`
+}
+
+// struct for configuration of chaincode, written by Init under configkey
+type ccconfig struct {
+	Version      int                `json:"version"`             // schema version of state
+	Admins       []string           `json:"admins,omitempty"`    // MSP IDs of admin orgs, they approve extensions of any ballot
+	Options      []string           `json:"options,omitempty"`   // default options of vote of ballots, all if empty
+	TallyRule    string             `json:"tallyrule"`           // plurality \ majority
+	Majority     float64            `json:"majority,omitempty"`  // percent of majority rule
+	IdentityMode string             `json:"identitymode"`        // issuer \ subject
+	MaxVoters    int                `json:"maxvoters,omitempty"` // maximum voters of ballot, no limit if 0
+	Registry     bool               `json:"registry,omitempty"`  // voters of ballots are to be registered
+	Migrations   []appliedmigration `json:"migrations,omitempty"`
+}
+
+// configuration of chaincode which was not configured by Init
+func defaultconfig() *ccconfig {
+	return &ccconfig{TallyRule: tallyplurality, IdentityMode: identityissuer}
+}
+
+// configuration from ledger, default one if Init has not written it
+func getconfig(stub shim.ChaincodeStubInterface) (*ccconfig, error) {
+	value, err := stub.GetState(configkey)
+	if err != nil {
+		return nil, errledger(configkey, err)
+	}
+	cfg := defaultconfig()
+	if value == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(value, cfg); err != nil {
+		return nil, errinternal(fmt.Errorf("can't parse config: %s", err))
+	}
+	return cfg, nil
+}
+
+// configuration of key=value args of Init over schema version and migrations of current one
+func configfromargs(current *ccconfig, args []string) (*ccconfig, error) {
+	cfg := defaultconfig()
+	cfg.Version, cfg.Migrations = current.Version, current.Migrations
+
+	for _, arg := range args {
+		if err := cfg.set(arg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// set option of configuration of key=value arg
+func (c *ccconfig) set(arg string) error {
+	kv := strings.SplitN(arg, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("config option %q is not key=value", arg)
+	}
+	key, value := strings.ToLower(kv[0]), kv[1]
+
+	switch key {
+	case "admin":
+		if value == "" {
+			return fmt.Errorf("admin is empty")
+		}
+		c.Admins = append(c.Admins, value)
+	case "options":
+		options := []string{}
+		for _, option := range strings.Split(value, ",") {
+			option = strings.ToLower(strings.TrimSpace(option))
+			if !checkvotes(option) {
+				return fmt.Errorf("option %q is not yes, no or neutral", option)
+			}
+			for _, o := range options {
+				if o == option {
+					return fmt.Errorf("option %s is repeated", option)
+				}
+			}
+			options = append(options, option)
+		}
+		if len(options) < 2 {
+			return fmt.Errorf("options %q have less than two options", value)
+		}
+		c.Options = options
+	case "tally": // plurality or majority:percent
+		rule := strings.SplitN(value, ":", 2)
+		switch rule[0] {
+		case tallyplurality:
+			if len(rule) > 1 {
+				return fmt.Errorf("tally rule %s has no percent", tallyplurality)
+			}
+			c.TallyRule, c.Majority = tallyplurality, 0
+		case tallymajority:
+			if len(rule) < 2 {
+				return fmt.Errorf("tally rule %s needs percent, majority:60", tallymajority)
+			}
+			p, err := strconv.ParseFloat(rule[1], 64)
+			if err != nil || p <= 0 || p > 100 {
+				return fmt.Errorf("majority %q is not percent", rule[1])
+			}
+			c.TallyRule, c.Majority = tallymajority, p
+		default:
+			return fmt.Errorf("tally rule %q is not plurality or majority:percent", value)
+		}
+	case "identity":
+		if value != identityissuer && value != identitysubject {
+			return fmt.Errorf("identity mode %q is not issuer or subject", value)
+		}
+		c.IdentityMode = value
+	case "maxvoters":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("maxvoters %q is not count", value)
+		}
+		c.MaxVoters = n
+	case "registry":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("option registry: %s", err)
+		}
+		c.Registry = flag
+	default:
+		return fmt.Errorf("unknown config option %q", key)
+	}
+	return nil
+}
+
+// org of MSP ID is admin of chaincode
+func (c *ccconfig) admin(org string) bool {
+	for _, a := range c.Admins {
+		if a == org {
+			return true
+		}
+	}
+	return false
+}
+
+// name of invoker as voter by identity mode
+func (c *ccconfig) name(certident *id.CertIdentity) string {
+	if c.IdentityMode == identitysubject {
+		return certident.Cert.Subject.CommonName
+	}
+	return certident.Cert.Issuer.CommonName
+}
+
+// name of invoker by identity mode of configuration of chaincode. Registered voter is named by its id
+// and its certificate is to be the one it is bound to
+func invokername(stub shim.ChaincodeStubInterface, certident *id.CertIdentity) (string, error) {
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return "", err
+	}
+	name := cfg.name(certident)
+
+	r, err := findvoter(stub, name)
+	if err != nil || r == nil {
+		return name, err
+	}
+	if err := r.checkcert(certident); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+// check ballot against configuration and set defaults of options and tally rule it has not set
+func (c *ccconfig) apply(v *votelist) error {
+	if c.MaxVoters > 0 && len(v.Voters) > c.MaxVoters {
+		return fmt.Errorf("%d voters in list, maximum of ballot is %d", len(v.Voters), c.MaxVoters)
+	}
+	if len(v.Options) == 0 {
+		v.Options = c.Options
+	}
+	if v.Majority == 0 && c.TallyRule == tallymajority {
+		v.Majority = c.Majority
+	}
+	return nil
+}
+
+// configuration of chaincode with schema version and log of migrations
+func (t *SimpleChaincode) config(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, _ := json.Marshal(cfg)
+	return shim.Success(banswer)
+}
@@ -0,0 +1,151 @@
+package chaincode
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	id "github.com/s7techlab/cckit/identity"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestInit_Config(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	res := stub.MockInit("1", argsbytes("init", "admin=Org3MSP", "options=yes,no", "tally=majority:60", "maxvoters=2"))
+	if res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var cfg ccconfig
+	query(t, stub, &cfg, "config")
+	if cfg.Version != len(migrations) || len(cfg.Migrations) != len(migrations) || cfg.Majority != 60 || cfg.IdentityMode != identityissuer || cfg.Admins[0] != "Org3MSP" {
+		t.Fatalf("config %+v", cfg)
+	}
+
+	for _, arg := range []string{"tally=supermajority", "options=yes", "identity=email", "maxvoters=many", "quorum=50"} {
+		if res := stub.MockInit("2", argsbytes("init", arg)); res.Status != statusbadrequest {
+			t.Errorf("init with %s: %d %s", arg, res.Status, res.Message)
+		}
+	}
+
+	// ballots take maximum size, options and majority of config
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, voter2, voter3)); res.Status != statusbadrequest {
+		t.Errorf("ballot over maxvoters: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote|Hash", "https://git.repo", "10.04.2099.10.00", voter1)); res.Status != statusbadrequest {
+		t.Errorf("vote id with |: %d %s", res.Status, res.Message)
+	}
+	startvoting(t, stub, "VoteHash", voter1, voter2, "maxextend=48h")
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if strings.Join(b.Options, ",") != "yes,no" || b.Majority != 60 {
+		t.Errorf("defaults of ballot %+v", b.votelist)
+	}
+	if res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "neutral", "")); res.Status != statusbadrequest {
+		t.Errorf("vote out of options of config: %d %s", res.Status, res.Message)
+	}
+
+	// report decides by majority of config
+	if res := stub.MockInit("2", argsbytes("init", "tally=majority:70")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	startvoting(t, stub, "Majority", voter1, voter2, voter3)
+	castvote(t, stub, "Org1MSP", stubsert1, "Majority", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "Majority", "yes", "")
+	castvote(t, stub, "Org3MSP", stubsert3, "Majority", "no", "")
+	var rep votereport
+	query(t, stub, &rep, "voteresult", "Majority")
+	if rep.Decision != "yes" || rep.Decided {
+		t.Errorf("report of ballot without majority of config: %s, decided %v", rep.Decision, rep.Decided)
+	}
+	if res := stub.MockInit("3", argsbytes("init", "admin=Org3MSP", "options=yes,no", "tally=majority:60", "maxvoters=2")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+
+	// admin org approves extension of any ballot, request of admin alone is not applied either
+	if ext, status, msg := extend(stub, "Org3MSP", stubsert3, "11.04.2099.10.00"); status != shim.OK || ext.AppliedAt != "" {
+		t.Errorf("extension by admin alone %+v: %s", ext, msg)
+	}
+	if ext, status, msg := extend(stub, "Org1MSP", stubsert1, "11.04.2099.10.00"); status != shim.OK || ext.AppliedAt == "" {
+		t.Errorf("extension approved by admin %+v: %s", ext, msg)
+	}
+
+	// upgrade without args keeps config, migrations are not applied again
+	if res := stub.MockInit("3", argsbytes("init")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	query(t, stub, &cfg, "config")
+	if len(cfg.Admins) != 1 || len(cfg.Migrations) != len(migrations) || cfg.MaxVoters != 2 {
+		t.Errorf("config after upgrade %+v", cfg)
+	}
+}
+
+func TestInit_IdentitySubject(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	certident, err := id.New("Org2MSP", []byte(stubsert2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := certident.Cert.Subject.CommonName
+
+	if res := stub.MockInit("1", argsbytes("identity=subject")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", subject)
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "yes", "")
+
+	if v, _ := stub.GetState("VoteHash|" + subject); v == nil {
+		t.Errorf("vote is not written by subject %s", subject)
+	}
+
+	cert := &id.CertIdentity{Cert: &x509.Certificate{Subject: pkix.Name{CommonName: "voter"}, Issuer: pkix.Name{CommonName: "ca"}}}
+	for mode, name := range map[string]string{identityissuer: "ca", identitysubject: "voter"} {
+		if n := (&ccconfig{IdentityMode: mode}).name(cert); n != name {
+			t.Errorf("name of %s identity mode %s, expected %s", mode, n, name)
+		}
+	}
+}
+
+func TestInit_Migrations(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+
+	// state of chaincode before json records and states
+	closed, _ := json.Marshal(votelist{RepoURL: "https://git.repo", EndDate: "10.04.2099.10.00", Voters: []string{"ca.org1.example.com"}, Closed: true})
+	stub.MockTransactionStart("0")
+	stub.PutState("Legacy", []byte("{https://git.repo 10.04.2099.10.00 [ca.org1.example.com org2.smple.for.test]}"))
+	stub.PutState("Legacy|ca.org1.example.com", []byte("{ca.org1.example.com No Because I can}"))
+	stub.PutState("Closed", closed)
+	stub.MockTransactionEnd("0")
+
+	res := stub.MockInit("1", argsbytes("init"))
+	if res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var cfg ccconfig
+	json.Unmarshal(res.Payload, &cfg)
+	if len(cfg.Migrations) != 2 || cfg.Migrations[0].Records != 2 || cfg.Migrations[1].Records != 2 || cfg.Version != 2 {
+		t.Errorf("log of migrations %+v", cfg.Migrations)
+	}
+
+	var v voterslist
+	value, _ := stub.GetState("Legacy|ca.org1.example.com")
+	if err := json.Unmarshal(value, &v); err != nil || v.Vote != "no" || v.Comment != "Because I can" {
+		t.Errorf("migrated vote %s", value)
+	}
+	for voteid, state := range map[string]string{"Legacy": stateopen, "Closed": stateclosed} {
+		var b votelist
+		value, _ := stub.GetState(voteid)
+		if err := json.Unmarshal(value, &b); err != nil || b.State != state {
+			t.Errorf("migrated ballot %s: %s", voteid, value)
+		}
+	}
+}
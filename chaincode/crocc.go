@@ -0,0 +1,782 @@
+// Package chaincode - voting of organizations on proposals, chaincode of cc_voting
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/tally"
+
+	id "github.com/s7techlab/cckit/identity" // s7 techlab MIT license
+)
+
+// SimpleChaincode example simple Chaincode implementation
+type SimpleChaincode struct{}
+
+// struct for voters in chain.  VoteID - key for this value
+type votelist struct {
+	RepoURL     string            `json:"repourl"`
+	EndDate     string            `json:"enddate"`             // TODO - Date. TxTime is needed to
+	StartDate   string            `json:"startdate,omitempty"` // opening of vote, votes before it are rejected
+	Voters      []string          `json:"voters"`
+	Groups      []groupref        `json:"groups,omitempty"` // groups which members are in voters, at versions of votestart
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Proposer    string            `json:"proposer,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Artifacts   []artifact        `json:"artifacts,omitempty"`  // pinned content of proposal
+	RequireAck  bool              `json:"requireack,omitempty"` // vote is to acknowledge digests of artifacts
+	Quorum      float64           `json:"quorum,omitempty"`     // percent of voters to vote for valid result
+	Orgs        map[string]string `json:"orgs,omitempty"`       // MSP ID by voter, for turnout of orgs before they vote
+	Majority    float64           `json:"majority,omitempty"`   // percent of counted votes for option of decision
+	RunoffEnd   string            `json:"runoff,omitempty"`     // duration after close or end date of runoff
+	Options     []string          `json:"options,omitempty"`    // options of vote of runoff, all if empty
+	MaxExtend   string            `json:"maxextend,omitempty"`  // duration, maximum total extension of end date
+	Sponsors    []string          `json:"sponsors,omitempty"`   // MSP IDs of orgs which approve extensions with proposer
+	Approvals   int               `json:"approvals,omitempty"`  // orgs to approve extension, 1 if 0
+	Endorsers   []string          `json:"endorsers,omitempty"`  // MSP IDs of orgs which endorse amendments of ballot with org of creator
+	Extensions  []extension       `json:"extensions,omitempty"` // log of requests of extension
+	Archive     *ballotarchive    `json:"archive,omitempty"`    // record of votes of archived ballot
+	Round       int               `json:"round,omitempty"`      // round of runoff, 0 for first round
+	PrevRound   string            `json:"prevround,omitempty"`  // ballot of previous round
+	NextRound   string            `json:"nextround,omitempty"`  // runoff opened by close of ballot
+	Closed      bool              `json:"closed,omitempty"`     // voteend or votecancel was called, no more votes
+	ClosedAt    string            `json:"closedat,omitempty"`   // tx time of voteend, RFC3339
+	State       string            `json:"state,omitempty"`      // draft \ open \ closed \ cancelled \ archived
+	Transitions []transition      `json:"transitions,omitempty"`
+	draft       bool              // votestart starts draft
+	reason      string            // reason of votestart in transition
+}
+
+// struct for ballot with its key, answer of voteinfo \ votelist
+type ballotview struct {
+	VoteID string `json:"voteid"`
+	votelist
+}
+
+// struct for voting
+type voterslist struct {
+	Voter     string   `json:"voter"`         // owner of vote - it.s certname is in key
+	Org       string   `json:"org"`           // MSP ID of voter
+	Vote      string   `json:"vote"`          // vote
+	Comment   string   `json:"comment"`       // comment to voter
+	Ack       []string `json:"ack,omitempty"` // digests of artifacts reviewed by voter
+	TxID      string   `json:"txid"`          // tx of vote writing
+	Timestamp string   `json:"timestamp"`     // tx time, RFC3339
+}
+
+// Init - run on instantiate \ update. key=value args configure chaincode, without them configuration is kept;
+// migrations bring state to current schema version
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+
+	function, args := stub.GetFunctionAndParameters()
+	if strings.Contains(function, "=") { // args without name of function
+		args = append([]string{function}, args...)
+	}
+
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if len(args) > 0 {
+		if cfg, err = configfromargs(cfg, args); err != nil {
+			return errbadargs("init", err).response()
+		}
+	}
+
+	if err := migrate(stub, cfg); err != nil {
+		return errorresponse(err)
+	}
+
+	value, _ := json.Marshal(cfg)
+	if err := stub.PutState(configkey, value); err != nil {
+		return errledger(configkey, err).response()
+	}
+
+	return shim.Success(value)
+
+}
+
+// Invoke - Our entry point for Invocations
+// ========================================
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+
+	function, args := stub.GetFunctionAndParameters()
+
+	f := lookupfunction(function) // registry of functions in router.go
+	if f == nil {
+		return errunknownfunction(function).response()
+	}
+
+	if err := f.checkargs(args); err != nil {
+		return errbadargs(f.Name, err).response()
+	}
+
+	return f.handler(t, stub, args)
+}
+
+// func inside Invoke Routing
+func (t *SimpleChaincode) votestart(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0] // Hash ID of voting
+
+	if strings.Contains(voteid, "|") { // keys of votes and reserved keys have it
+		return errbadargs("votestart", fmt.Errorf("vote id %q has |", voteid)).response()
+	}
+
+	votelist, err := votelistfromargs(args) // parsing to struct from args - for convience
+	if err != nil {
+		return errbadargs("votestart", err).response()
+	}
+
+	if err := resolvegroups(stub, votelist); err != nil { // snapshot of members of groups
+		return errorresponse(err)
+	}
+
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := cfg.apply(votelist); err != nil {
+		return errbadargs("votestart", err).response()
+	}
+
+	if err := checkregistry(stub, cfg, votelist); err != nil {
+		return errorresponse(err)
+	}
+
+	// invoker proposes, proposer closes, cancels and archives ballot - proposer option can name only invoker
+	certident, _ := id.FromStub(stub) // identity of invoker, nil if it is unknown
+	if certident != nil {
+		invoker, err := invokername(stub, certident)
+		if err != nil {
+			return errorresponse(err)
+		}
+		if votelist.Proposer != "" && normalizevoter(votelist.Proposer) != normalizevoter(invoker) {
+			return errbadargs("votestart", fmt.Errorf("proposer %s is not invoker %s", votelist.Proposer, invoker)).response()
+		}
+		votelist.Proposer = invoker
+	} else if votelist.Proposer != "" {
+		return errbadargs("votestart", fmt.Errorf("proposer %s of unknown invoker", votelist.Proposer)).response()
+	}
+
+	if err := checkuniqvoters(votelist.Voters); err != nil {
+		return errduplicatevoters(voteid, err).response()
+	}
+
+	// new ballot starts as draft or open, votestart of proposer opens draft with args given
+	existing, err := stub.GetState(voteid)
+	if err != nil {
+		return errledger(voteid, err).response()
+	}
+	tr, err := newtransition(stub, certident, votelist.reason)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if existing == nil {
+		state := stateopen
+		if votelist.draft {
+			state = statedraft
+		}
+		votelist.begin(state, tr)
+	} else {
+		draft := bytesToVoteList(existing)
+		if draft.state() != statedraft || votelist.draft {
+			return errinvalidstate(voteid, draft.state(), "start").response()
+		}
+		if tr.By != draft.Proposer {
+			return errnotproposer(voteid, tr.By, "open").response()
+		}
+		votelist.State, votelist.Transitions = draft.State, draft.Transitions
+		votelist.transit(stateopen, tr)
+	}
+
+	value, _ := json.Marshal(votelist) //struct as []byte
+	err = stub.PutState(voteid, value) // simple save in ledger - check for uniq
+
+	if err != nil {
+		return errledger(voteid, err).response()
+	}
+	if err := setendorsement(stub, voteid, votelist.endorsers()...); err != nil {
+		return errorresponse(err)
+	}
+	if err := setevent(stub, eventballotstarted, ccevent{VoteID: voteid, Ballot: votelist}); err != nil {
+		return errorresponse(err)
+	}
+
+	return shim.Success(nil)
+
+} // votestart
+
+func (t *SimpleChaincode) vote(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteID := args[0]
+
+	votebyte, err := stub.GetState(voteID)
+	if err != nil {
+		return errledger(voteID, err).response()
+	}
+	if votebyte == nil {
+		return errballotnotfound(voteID).response()
+	}
+
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err).response()
+	}
+	voter, err := invokername(stub, certident)
+	if err != nil {
+		return errorresponse(err)
+	}
+	votestruct := bytesToVoteList(votebyte)
+	if err := votestruct.checkopen(voteID, "vote in"); err != nil {
+		return err.response()
+	}
+	if votestruct.scheduled(txtime(stub)) {
+		return errinvalidstate(voteID, statusscheduled, "vote in").response()
+	}
+	if votestruct.expired(txtime(stub)) {
+		return errdeadlinepassed(voteID, votestruct.EndDate, "vote in").response()
+	}
+	listed, ok := votestruct.listedvoter(voter)
+	if !ok {
+		return errnoteligible(voteID, voter).response()
+	}
+	voter = listed
+	votekey := voteID + "|" + voter // key of our chaininput tx
+	if r, err := findvoter(stub, voter); err != nil {
+		return errorresponse(err)
+	} else if r != nil && !r.Active {
+		return errvoterinactive(voter).response()
+	}
+
+	vote := args[1] // vote is to be one of {yes, no, neutral}, checked by router
+	if !votestruct.allows(strings.ToLower(vote)) {
+		return errbadargs("vote", fmt.Errorf("vote %s is not an option of round %d: %s", vote, votestruct.round(), strings.Join(votestruct.options(), ", "))).response()
+	}
+
+	comment := ""
+	if len(args) > 2 {
+		comment = args[2]
+	}
+
+	var ack []string // digests reviewed by voter
+	if len(args) > 3 && args[3] != "" {
+		ack = ackfromarg(args[3])
+	}
+	if err := votestruct.checkack(ack); err != nil {
+		return errnotacknowledged(voteID, voter, err).response()
+	}
+
+	votestr := voterslist{
+		Voter:     voter, // duplicate of voter ()
+		Org:       certident.GetMSPID(),
+		Vote:      strings.ToLower(vote),
+		Comment:   comment,
+		Ack:       ack,
+		TxID:      stub.GetTxID(),
+		Timestamp: txtime(stub),
+	}
+
+	value, _ := json.Marshal(votestr)
+
+	if err := stub.PutState(votekey, value); err != nil {
+		return errledger(votekey, err).response()
+	}
+	if err := setendorsement(stub, votekey, votestr.Org); err != nil { // only org of voter changes its vote
+		return errorresponse(err)
+	}
+	if err := setevent(stub, eventvotecast, ccevent{VoteID: voteID, Vote: &votestr}); err != nil {
+		return errorresponse(err)
+	}
+
+	receipt, _ := json.Marshal(receiptofvote(voteID, votestr))
+	return shim.Success(receipt)
+} // vote
+
+// close voting - only proposer can, votes are not accepted after it, result can be counted
+func (t *SimpleChaincode) votend(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, err := getballot(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := votestruct.checkopen(voteid, "close"); err != nil {
+		return err.response()
+	}
+
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err).response()
+	}
+	reason := ""
+	if len(args) > 1 {
+		reason = args[1]
+	}
+	tr, err := newtransition(stub, certident, reason)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if tr.By != votestruct.Proposer {
+		return errnotproposer(voteid, tr.By, "close").response()
+	}
+	votestruct.transit(stateclosed, tr) // open ballot is checked
+	votestruct.ClosedAt = tr.At
+
+	runoff, err := openrunoff(stub, voteid, votestruct)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	value, _ := json.Marshal(votestruct)
+	if err := stub.PutState(voteid, value); err != nil {
+		return errledger(voteid, err).response()
+	}
+	if err := setevent(stub, eventballotclosed, ccevent{VoteID: voteid, Ballot: votestruct, Runoff: runoff}); err != nil {
+		return errorresponse(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// withdraw vote of invoker while voting is open
+func (t *SimpleChaincode) votewithdraw(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, err := getballot(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := votestruct.checkopen(voteid, "withdraw vote of"); err != nil {
+		return err.response()
+	}
+	if votestruct.expired(txtime(stub)) {
+		return errdeadlinepassed(voteid, votestruct.EndDate, "withdraw vote of").response()
+	}
+
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err).response()
+	}
+	voter, err := invokername(stub, certident)
+	if err != nil {
+		return errorresponse(err)
+	}
+	voter, _ = votestruct.listedvoter(voter)
+	votekey := voteid + "|" + voter
+
+	val, err := stub.GetState(votekey)
+	if err != nil {
+		return errledger(votekey, err).response()
+	}
+	if val == nil {
+		return errvotenotfound(voteid, voter).response()
+	}
+
+	if err := stub.DelState(votekey); err != nil {
+		return errledger(votekey, err).response()
+	}
+	if err := setevent(stub, eventvotewithdrawn, ccevent{VoteID: voteid, Voter: voter}); err != nil {
+		return errorresponse(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// ballot with metadata and voters
+func (t *SimpleChaincode) voteinfo(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, err := getballot(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, _ := json.Marshal(ballotview{VoteID: voteid, votelist: *votestruct})
+	return shim.Success(banswer)
+}
+
+// ballots in order of keys - keys of ballots are the ones without "|".
+// Options limit=N and after=voteid give pages: next page is after last voteid of page
+func (t *SimpleChaincode) votelist(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	limit, after := 0, ""
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		switch strings.ToLower(kv[0]) {
+		case "limit":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 1 {
+				return errbadargs("votelist", fmt.Errorf("limit %q is not positive number", kv[1])).response()
+			}
+			limit = n
+		case "after":
+			after = kv[1]
+		default:
+			return errbadargs("votelist", fmt.Errorf("unknown option %q", kv[0])).response()
+		}
+	}
+
+	startkey, endkey := "", ""
+	if after != "" {
+		startkey = after + "\x00"     // first key after it
+		endkey = string(utf8.MaxRune) // open end, as fabric does for composite keys
+	}
+
+	iter, err := stub.GetStateByRange(startkey, endkey)
+	if err != nil {
+		return errledger(startkey, err).response()
+	}
+	defer iter.Close()
+
+	ballots := []ballotview{}
+	for iter.HasNext() && (limit == 0 || len(ballots) < limit) {
+		kv, err := iter.Next()
+		if err != nil {
+			return errledger(startkey, err).response()
+		}
+		if strings.Contains(kv.Key, "|") { // vote or result
+			continue
+		}
+		ballots = append(ballots, ballotview{VoteID: kv.Key, votelist: *bytesToVoteList(kv.Value)})
+	}
+
+	banswer, _ := json.Marshal(ballots)
+	return shim.Success(banswer)
+}
+
+func (t *SimpleChaincode) voteresult(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0] // Key of vote, also part of a key of report
+	voteidrwkey := voteid + "|result"
+
+	opts, err := reportoptsfromargs(args[1:], "json")
+	if err != nil {
+		return errbadargs("voteresult", err).response()
+	}
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	voterep, err := buildreport(voteid, votestruct, votearr)
+	if err != nil {
+		return errcountfailed(voteid, err).response()
+	}
+	if voterep.Rounds, err = collectrounds(stub, voteid, votestruct); err != nil {
+		return errorresponse(err)
+	}
+
+	if votestruct.Archive == nil { // result of archived ballot is in its record
+		banswer, _ := json.Marshal(voterep)
+		if err := stub.PutState(voteidrwkey, banswer); err != nil { // log resultcall in chain for future
+			return errledger(voteidrwkey, err).response()
+		}
+		result := &ccresult{Counts: voterep.Counts, Decision: voterep.Decision, Decided: voterep.Decided, MerkleRoot: voterep.Certificate.MerkleRoot, Leaves: voterep.Certificate.Leaves}
+		if err := setevent(stub, eventresultpublished, ccevent{VoteID: voteid, Result: result}); err != nil {
+			return errorresponse(err)
+		}
+	}
+
+	banswer, err := voterep.apply(opts).render(opts)
+	if err != nil {
+		return errorresponse(err)
+	}
+	return shim.Success(banswer)
+
+} //voteresult
+
+// the same report as voteresult, csv by default and without writing in ledger
+func (t *SimpleChaincode) voteresultcsv(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0] // Key of vote, also part of a key of report
+
+	opts, err := reportoptsfromargs(args[1:], "csv")
+	if err != nil {
+		return errbadargs("voteresultcsv", err).response()
+	}
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	voterep, err := buildreport(voteid, votestruct, votearr)
+	if err != nil {
+		return errcountfailed(voteid, err).response()
+	}
+	if voterep.Rounds, err = collectrounds(stub, voteid, votestruct); err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, err := voterep.apply(opts).render(opts)
+	if err != nil {
+		return errorresponse(err)
+	}
+	return shim.Success(banswer)
+} // voteresultcsv
+
+// inclusion proof of vote of voter (invoker by default) in Merkle root of result
+func (t *SimpleChaincode) voteproof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	var voter string
+	if len(args) > 1 {
+		voter = args[1]
+	} else {
+		certident, err := id.FromStub(stub) // identity of ivoker
+		if err != nil {
+			return errbadidentity(err).response()
+		}
+		if voter, err = invokername(stub, certident); err != nil {
+			return errorresponse(err)
+		}
+	}
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if votestruct.Archive != nil { // leaves of votes are not in archived record
+		return errinvalidstate(voteid, votestruct.state(), "prove vote in").response()
+	}
+
+	voter, _ = votestruct.listedvoter(voter)
+	proof := provevote(voteid, votearr, voter)
+	if proof == nil {
+		return errvotenotfound(voteid, voter).response()
+	}
+
+	banswer, _ := json.Marshal(proof)
+	return shim.Success(banswer)
+}
+
+// check receipt returned by vote against current state and history of vote
+func (t *SimpleChaincode) verifyreceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	var receipt votereceipt
+	if err := json.Unmarshal([]byte(args[0]), &receipt); err != nil {
+		return errbadargs("verifyreceipt", fmt.Errorf("can't parse receipt: %s", err)).response()
+	}
+
+	check, err := checkreceipt(stub, receipt)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, _ := json.Marshal(check)
+	return shim.Success(banswer)
+}
+
+//  not implmemented yet
+func (t *SimpleChaincode) votehistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	key := args[0]
+	resp, err := stub.GetState(key)
+
+	if err != nil {
+		return errledger(key, err).response()
+	}
+	if resp == nil {
+		return errkeynotfound(key).response()
+	}
+
+	return shim.Success(resp)
+
+}
+
+//----------------- additional funcs
+
+// ballot of key voteid from ledger
+func getballot(stub shim.ChaincodeStubInterface, voteid string) (*votelist, error) {
+	votebyte, err := stub.GetState(voteid)
+	if err != nil {
+		return nil, errledger(voteid, err)
+	}
+	if votebyte == nil {
+		return nil, errballotnotfound(voteid)
+	}
+	return bytesToVoteList(votebyte), nil
+}
+
+// write orgs in votestr.Voters field (slice), key=value args are metadata of ballot
+func votelistfromargs(args []string) (*votelist, error) {
+
+	votestr := new(votelist)
+
+	votestr.RepoURL = args[1]
+	votestr.EndDate = args[2]
+
+	for i := 3; i < len(args); i++ {
+		if strings.Contains(args[i], "=") {
+			if err := votestr.setmeta(args[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		votestr.Voters = append(votestr.Voters, args[i])
+
+	}
+
+	if len(votestr.Voters) == 0 && len(votestr.Groups) == 0 {
+		return nil, fmt.Errorf("no voters in list")
+	}
+
+	return votestr, votestr.checkmeta()
+}
+
+// voter is in list of voters
+func (v *votelist) hasvoter(voter string) bool {
+	_, ok := v.listedvoter(voter)
+	return ok
+}
+
+// voter as list of voters spells it - keys of votes are by this spelling; voters are compared as normalizevoter does
+func (v *votelist) listedvoter(voter string) (string, bool) {
+	n := normalizevoter(voter)
+	for _, w := range v.Voters {
+		if normalizevoter(w) == n {
+			return w, true
+		}
+	}
+	return voter, false
+}
+
+// check the vote is inside {Yes, No, Neutral }
+func checkvotes(vote string) bool {
+	res := false
+
+	res = (strings.ToLower(vote) == "yes") || (strings.ToLower(vote) == "no") || (strings.ToLower(vote) == "neutral")
+
+	return res
+
+}
+
+// []byte to votelist unmarshal - json, or {repourl enddate [voters]} of votings started before
+func bytesToVoteList(votestr []byte) *votelist { //tested
+
+	//var buf bytes.Buffer
+	var lvotelist votelist
+
+	if err := json.Unmarshal(votestr, &lvotelist); err == nil {
+		return &lvotelist
+	}
+
+	lvotelist.RepoURL, lvotelist.EndDate, lvotelist.Voters = tally.ParseLegacyBallot(votestr)
+
+	return &lvotelist
+
+} // bytesToVoteList
+
+// []byte to voterslist unmarshal - json, or {voter vote comment} of votes written before
+func bytesToVotersList(votestr []byte) voterslist {
+	var lvoterslist voterslist
+
+	if err := json.Unmarshal(votestr, &lvoterslist); err == nil {
+		return lvoterslist
+	}
+
+	lvoterslist.Voter, lvoterslist.Vote, lvoterslist.Comment = tally.ParseLegacyVote(votestr)
+
+	return lvoterslist
+}
+
+// time of transaction as RFC3339 string, empty if stub has no time
+func txtime(stub shim.ChaincodeStubInterface) string {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil || ts == nil {
+		return ""
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format(time.RFC3339Nano)
+}
+
+// ToBytes converts inteface{} (string, []byte , struct to ToByter interface to []byte for storing in state
+// from s7techlab cckit + refactor
+func toBytes(value interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch value.(type) {
+
+	// first priority if value implements ToByter interface
+
+	case proto.Message:
+		return proto.Marshal(proto.Clone(value.(proto.Message)))
+	case bool:
+		return []byte(strconv.FormatBool(value.(bool))), nil
+	case string:
+		return []byte(value.(string)), nil
+	case uint:
+		return []byte(fmt.Sprint(value.(uint))), nil
+	case int:
+		return []byte(fmt.Sprint(value.(int))), nil
+	case int32:
+		return []byte(fmt.Sprint(value.(int32))), nil
+	case []byte:
+		return value.([]byte), nil
+
+	default:
+		valueType := reflect.TypeOf(value).Kind()
+
+		switch valueType {
+		case reflect.Ptr:
+			fallthrough
+		case reflect.Struct:
+
+			return []byte(fmt.Sprintf("%v", value)), nil
+
+			//return json.Marshal(value)
+		case reflect.Array:
+			return []byte(fmt.Sprintf("%v", value)), nil
+		case reflect.Map:
+			return []byte(fmt.Sprintf("%v", value)), nil
+		case reflect.Slice:
+			return []byte(fmt.Sprintf("%v", value)), nil
+		//	return json.Marshal(value)
+		// used when type based on string
+		case reflect.String:
+			return []byte(reflect.ValueOf(value).String()), nil
+
+		default:
+			return nil, fmt.Errorf(
+				`toBytes converting supports ToByter interface,struct,array,slice,bool and string, current type is %s`,
+				valueType)
+		}
+
+	}
+}
+
+//count resolution ov voting
+func resultfrommap(res map[string]int) (string, error) {
+	return tally.Decide(res)
+}
+
+// check list of voters on duplicates absence, voters are compared normalized
+func checkuniqvoters(voters []string) error { //tested
+
+	seen := map[string]bool{}
+	for _, v := range voters {
+		n := normalizevoter(v)
+		if seen[n] {
+			return fmt.Errorf("There are duplicate voters in Your list: %s", v) // negative
+		}
+		seen[n] = true
+	}
+
+	return nil // positive
+}
@@ -1,7 +1,9 @@
-package main
+package chaincode
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -147,7 +149,7 @@ func TestExample02_InvokeStart(t *testing.T) {
 	IDVote := "VoteHash"
 	bIDVote := []byte(IDVote)
 	brepourl := []byte("https://git.repo")
-	bEndDate := []byte("10.04.2019.10.00")
+	bEndDate := []byte("10.04.2099.10.00")
 
 	Orgs := []string{
 		"Org1",
@@ -194,7 +196,7 @@ func TestExample4_VoteStart_Vote(t *testing.T) {
 	IDVote := "VoteHash"
 	bIDVote := []byte("VoteHash")
 	brepourl := []byte("https://git.repo")
-	bEndDate := []byte("10.04.2019.10.00")
+	bEndDate := []byte("10.04.2099.10.00")
 
 	Orgs := []string{ // here we need to put real idenitites
 		creattor,
@@ -263,7 +265,7 @@ func TestExample5_VoteStart_Vote_Result(t *testing.T) {
 	IDVote := "VoteHash"
 	bIDVote := []byte("VoteHash")
 	brepourl := []byte("https://git.repo")
-	bEndDate := []byte("10.04.2019.10.00")
+	bEndDate := []byte("10.04.2099.10.00")
 
 	Orgs := []string{ // here we need to put real idenitites
 		creattor,
@@ -351,7 +353,7 @@ func TestExample6_VoteStart_Vote_Result_3orgs(t *testing.T) {
 	IDVote := "VoteHash"
 	bIDVote := []byte("VoteHash")
 	brepourl := []byte("https://git.repo")
-	bEndDate := []byte("10.04.2019.10.00")
+	bEndDate := []byte("10.04.2099.10.00")
 
 	Orgs := []string{ // here we need to put real idenitites
 		creattor1,
@@ -444,3 +446,133 @@ func TestExample6_VoteStart_Vote_Result_3orgs(t *testing.T) {
 	checkInvoke(t, stub, buff)
 
 }
+
+func TestVoteEnd_Withdraw(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1)) // proposer
+	startvoting(t, stub, "VoteHash", voter1, voter2)
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+
+	// withdrawn vote is not counted, second withdraw finds no vote
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != shim.OK {
+		t.Fatalf("votewithdraw failed: %s", res.Message)
+	}
+	if v, _ := stub.GetState("VoteHash|" + voter2); v != nil {
+		t.Errorf("vote is not deleted: %s", v)
+	}
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != statusnotfound {
+		t.Errorf("withdraw without vote: status %d, expected 404", res.Status)
+	}
+
+	// only proposer closes
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != statusforbidden || !strings.HasPrefix(res.Message, codenotproposer) {
+		t.Errorf("voteend of not proposer: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Fatalf("voteend failed: %s", res.Message)
+	}
+
+	// nothing changes votes of closed voting
+	for _, args := range [][]string{{"vote", "VoteHash", "no"}, {"votewithdraw", "VoteHash"}, {"voteend", "VoteHash"}} {
+		stub.MockCreator("Org1MSP", []byte(stubsert1))
+		if res := stub.MockInvoke("1", argsbytes(args...)); res.Status != statusforbidden || !strings.HasPrefix(res.Message, codeballotclosed) {
+			t.Errorf("%v of closed voting: %d %s", args, res.Status, res.Message)
+		}
+	}
+
+	res := stub.MockInvoke("1", argsbytes("voteresult", "VoteHash"))
+	var rep votereport
+	if err := json.Unmarshal(res.Payload, &rep); err != nil || rep.Decision != "yes" || rep.Turnout.Voted != 1 {
+		t.Errorf("result of closed voting: %s %v", res.Payload, err)
+	}
+}
+
+func TestVoteList_Info(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "Vote2", "org1", "title=Second")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "Vote1", certvoter(t, stubsert1), certvoter(t, stubsert2))
+	castvote(t, stub, "Org1MSP", stubsert1, "Vote1", "yes", "")
+	stub.MockInvoke("1", argsbytes("voteresult", "Vote1"))
+
+	res := stub.MockInvoke("1", argsbytes("votelist"))
+	var ballots []ballotview
+	if err := json.Unmarshal(res.Payload, &ballots); err != nil {
+		t.Fatalf("votelist: %s %v", res.Payload, err)
+	}
+	if len(ballots) != 2 || ballots[0].VoteID != "Vote1" || ballots[1].VoteID != "Vote2" || ballots[1].Title != "Second" {
+		t.Errorf("expected ballots Vote1 and Vote2, got %+v", ballots)
+	}
+
+	// pages of one ballot
+	for i, after := range []string{"", "Vote1", "Vote2"} {
+		res = stub.MockInvoke("1", argsbytes("votelist", "limit=1", "after="+after))
+		ballots = nil
+		json.Unmarshal(res.Payload, &ballots)
+		if i < 2 && (len(ballots) != 1 || ballots[0].VoteID != fmt.Sprintf("Vote%d", i+1)) || i == 2 && len(ballots) != 0 {
+			t.Errorf("page after %q: %s", after, res.Payload)
+		}
+	}
+	if res := stub.MockInvoke("1", argsbytes("votelist", "limit=0")); res.Status != statusbadrequest {
+		t.Errorf("limit=0: status %d", res.Status)
+	}
+
+	res = stub.MockInvoke("1", argsbytes("voteinfo", "Vote1"))
+	var ballot ballotview
+	if err := json.Unmarshal(res.Payload, &ballot); err != nil {
+		t.Fatalf("voteinfo: %s %v", res.Payload, err)
+	}
+	if ballot.VoteID != "Vote1" || ballot.Proposer != certvoter(t, stubsert1) || len(ballot.Voters) != 2 {
+		t.Errorf("wrong ballot %+v", ballot)
+	}
+
+	if res := stub.MockInvoke("1", argsbytes("voteinfo", "NoHash")); res.Status != statusnotfound {
+		t.Errorf("voteinfo of missing ballot: status %d", res.Status)
+	}
+}
+
+// identity of cert as it is used in list of voters
+func certvoter(t *testing.T, cert string) string {
+	certident, err := id.New("MSP", []byte(cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certident.Cert.Issuer.CommonName
+}
+
+// votestart with list of voters, fails test on error
+func startvoting(t *testing.T, stub *cckit.MockStub, voteid string, voters ...string) {
+	args := []string{"votestart", voteid, "https://git.repo", "10.04.2099.10.00"}
+	args = append(args, voters...)
+
+	res := stub.MockInvoke("1", argsbytes(args...))
+	if res.Status != shim.OK {
+		t.Fatalf("votestart %s failed: %s", voteid, res.Message)
+	}
+}
+
+// vote from identity, fails test on error
+func castvote(t *testing.T, stub *cckit.MockStub, mspid string, cert string, voteid string, vote string, comment string) {
+	stub.MockCreator(mspid, []byte(cert))
+
+	res := stub.MockInvoke("1", argsbytes("vote", voteid, vote, comment))
+	if res.Status != shim.OK {
+		t.Fatalf("vote %s failed: %s", voteid, res.Message)
+	}
+}
+
+func argsbytes(args ...string) [][]byte {
+	bargs := [][]byte{}
+	for _, v := range args {
+		bargs = append(bargs, []byte(v))
+	}
+	return bargs
+}
@@ -0,0 +1,155 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const constcsvseparator = rune(';') // separator for csv
+const constcsvbom = "\xEF\xBB\xBF"  // UTF-8 byte order mark, for Excel
+const constabstained = "abstained"  // vote column of voters without vote
+
+// options of voteresultcsv, given as key=value args
+type csvopts struct {
+	header bool // first row with column names
+	comma  rune // separator of fields
+	crlf   bool // \r\n at the end of rows, \n otherwise
+	bom    bool // UTF-8 BOM before first row
+}
+
+// struct for csv fle columns
+type csvrow struct { // struct for string of csv file
+	voteid      string
+	voterepo    string
+	voteenddate string
+	voter       string
+	vote        string
+	result      string
+	comment     string
+}
+
+// names of csvrow columns for header row
+var csvheader = []string{"voteid", "repourl", "enddate", "voter", "vote", "result", "comment"}
+
+// options of csv, as before header and BOM were there
+func defaultcsvopts() csvopts {
+	return csvopts{comma: constcsvseparator, crlf: true}
+}
+
+// set csv option by key=value arg, false for keys which are not csv options
+func (opts *csvopts) set(key, value string) (bool, error) {
+	switch key {
+	case "header", "bom":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("option %s: %s", key, err)
+		}
+		if key == "header" {
+			opts.header = flag
+		} else {
+			opts.bom = flag
+		}
+	case "delimiter":
+		comma, err := csvdelimiter(value)
+		if err != nil {
+			return true, err
+		}
+		opts.comma = comma
+	case "lineend":
+		switch strings.ToLower(value) {
+		case "crlf":
+			opts.crlf = true
+		case "lf":
+			opts.crlf = false
+		default:
+			return true, fmt.Errorf("unknown line end %q", value)
+		}
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// delimiter by name or as single symbol
+func csvdelimiter(value string) (rune, error) {
+	switch strings.ToLower(value) {
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "tab":
+		return '\t', nil
+	}
+
+	comma, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) || comma == '"' || comma == '\r' || comma == '\n' || comma == utf8.RuneError {
+		return 0, fmt.Errorf("can't use %q as delimiter", value)
+	}
+	return comma, nil
+}
+
+// rows of report entries, then rows of voters without vote marked as abstained
+func csvrowsfromreport(rep votereport) []csvrow {
+	rows := []csvrow{}
+	for _, v := range rep.Votes {
+		rows = append(rows, csvrow{rep.VoteID, rep.RepoURL, rep.EndDate, v.Voter, v.Vote, rep.resolution(), v.Comment})
+	}
+	for _, voter := range rep.Abstained {
+		rows = append(rows, csvrow{rep.VoteID, rep.RepoURL, rep.EndDate, voter, constabstained, rep.resolution(), ""})
+	}
+
+	return rows
+}
+
+// write rows as RFC 4180 csv - fields with separator, quotes or line ends are quoted
+func writecsv(rows []csvrow, opts csvopts) ([]byte, error) {
+	records := [][]string{}
+	for _, v := range rows {
+		records = append(records, []string{v.voteid, v.voterepo, v.voteenddate, v.voter, v.vote, v.result, v.comment})
+	}
+	return writerecords(csvheader, records, opts)
+}
+
+// write records with header of their columns as csv of options
+func writerecords(header []string, records [][]string, opts csvopts) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if opts.bom {
+		buf.WriteString(constcsvbom)
+	}
+
+	// csv writer options
+	w := csv.NewWriter(buf)
+	w.UseCRLF = opts.crlf
+	w.Comma = opts.comma
+
+	if opts.header {
+		if err := w.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush() // write to buf
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// csvformatter renders report as csv file
+type csvformatter struct{}
+
+func (csvformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	return writecsv(csvrowsfromreport(rep), opts.csv)
+}
@@ -0,0 +1,74 @@
+package chaincode
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func querycsv(t *testing.T, stub *cckit.MockStub, args ...string) string {
+	res := stub.MockInvoke("1", argsbytes(append([]string{"voteresultcsv", "VoteHash"}, args...)...))
+	if res.Status != shim.OK {
+		t.Fatalf("voteresultcsv failed: %s", res.Message)
+	}
+	return string(res.Payload)
+}
+
+func TestCSV_Default(t *testing.T) {
+	stub := reportstub(t)
+	castvote(t, stub, "Org3MSP", stubsert3, "VoteHash", "Neutral", `Say "maybe"; or not`)
+
+	out := querycsv(t, stub)
+	if strings.HasPrefix(out, constcsvbom) || !strings.HasSuffix(out, "\r\n") {
+		t.Errorf("default csv must be without BOM and with CRLF: %q", out)
+	}
+
+	r := csv.NewReader(strings.NewReader(out))
+	r.Comma = ';'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("csv is not readable: %s\n%s", err, out)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, expected 3", len(rows))
+	}
+	if rows[1][6] != "Looks {good} [to me]" {
+		t.Errorf("brackets are stripped from comment: %q", rows[1][6])
+	}
+	if rows[2][6] != `Say "maybe"; or not` {
+		t.Errorf("comment with separator and quotes: %q", rows[2][6])
+	}
+}
+
+func TestCSV_Options(t *testing.T) {
+	stub := reportstub(t)
+
+	out := querycsv(t, stub, "header=true", "delimiter=comma", "lineend=lf", "bom=true")
+	if !strings.HasPrefix(out, constcsvbom) {
+		t.Errorf("no BOM: %q", out)
+	}
+	if strings.Contains(out, "\r\n") {
+		t.Errorf("CRLF with lineend=lf: %q", out)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out, constcsvbom))).ReadAll()
+	if err != nil {
+		t.Fatalf("csv is not readable: %s\n%s", err, out)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(csvheader, ",") {
+		t.Fatalf("expected header and 3 rows: %v", rows)
+	}
+	if rows[3][3] != certvoter(t, stubsert3) || rows[3][4] != constabstained || rows[3][5] != "equal" {
+		t.Errorf("voter without vote is not marked: %v", rows[3])
+	}
+
+	for _, args := range [][]string{{"delimiter=\""}, {"delimiter=ab"}, {"lineend=cr"}, {"header=maybe"}, {"separator=;"}} {
+		res := stub.MockInvoke("1", argsbytes(append([]string{"voteresultcsv", "VoteHash"}, args...)...))
+		if res.Status == shim.OK {
+			t.Errorf("options %v are accepted", args)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// endorsement policy of key which members of all orgs are to sign, as validation parameter of state
+func endorsementpolicy(orgs []string) ([]byte, error) {
+	identities := make([]*msp.MSPPrincipal, len(orgs))
+	rules := make([]*cb.SignaturePolicy, len(orgs))
+	for i, org := range orgs {
+		role, err := proto.Marshal(&msp.MSPRole{Role: msp.MSPRole_MEMBER, MspIdentifier: org})
+		if err != nil {
+			return nil, err
+		}
+		identities[i] = &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ROLE, Principal: role}
+		rules[i] = cauthdsl.SignedBy(int32(i))
+	}
+	return proto.Marshal(&cb.SignaturePolicyEnvelope{Rule: cauthdsl.NOutOf(int32(len(orgs)), rules), Identities: identities})
+}
+
+// orgs of endorsement policy of key without empty and repeated ones
+func endorsingorgs(orgs ...string) []string {
+	uniq, seen := []string{}, map[string]bool{}
+	for _, org := range orgs {
+		if org != "" && !seen[org] {
+			seen[org] = true
+			uniq = append(uniq, org)
+		}
+	}
+	return uniq
+}
+
+// set state-based endorsement of key: peers of all orgs endorse its next updates, instead of policy of chaincode.
+// Key without orgs keeps policy of chaincode
+func setendorsement(stub shim.ChaincodeStubInterface, key string, orgs ...string) error {
+	orgs = endorsingorgs(orgs...)
+	if len(orgs) == 0 {
+		return nil
+	}
+	ep, err := endorsementpolicy(orgs)
+	if err != nil {
+		return errinternal(fmt.Errorf("can't build endorsement policy of %s: %s", key, err))
+	}
+	if err := stub.SetStateValidationParameter(key, ep); err != nil {
+		return errledger(key, err)
+	}
+	return nil
+}
+
+// hand key over to orgs: its key-level endorsement becomes policy of orgs, or policy of chaincode without orgs
+func resetendorsement(stub shim.ChaincodeStubInterface, key string, orgs ...string) error {
+	if len(endorsingorgs(orgs...)) > 0 {
+		return setendorsement(stub, key, orgs...)
+	}
+	if err := stub.SetStateValidationParameter(key, nil); err != nil {
+		return errledger(key, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// orgs of validation parameter of key, comma separated, and count of them to sign; empty without parameter
+func endorsementof(t *testing.T, stub *cckit.MockStub, key string) (string, int32) {
+	ep, err := stub.GetStateValidationParameter(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep == nil {
+		return "", 0
+	}
+	var policy cb.SignaturePolicyEnvelope
+	if err := proto.Unmarshal(ep, &policy); err != nil {
+		t.Fatalf("validation parameter of %s: %s", key, err)
+	}
+	orgs := []string{}
+	for _, principal := range policy.Identities {
+		var role msp.MSPRole
+		if err := proto.Unmarshal(principal.Principal, &role); err != nil || role.Role != msp.MSPRole_MEMBER {
+			t.Fatalf("principal of %s is not member role: %v", key, principal)
+		}
+		orgs = append(orgs, role.MspIdentifier)
+	}
+	return strings.Join(orgs, ","), policy.Rule.GetNOutOf().GetN()
+}
+
+func TestEndorsement(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, voter3, "endorser=Org2MSP", "endorser=Org1MSP", "runoff=48h", "majority=60")
+	if orgs, n := endorsementof(t, stub, "VoteHash"); orgs != "Org1MSP,Org2MSP" || n != 2 {
+		t.Errorf("endorsement of ballot %s, %d of them", orgs, n)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Other", "https://git.repo", "10.04.2099.10.00", voter1, "endorser=")); res.Status != statusbadrequest {
+		t.Errorf("empty endorser: %d %s", res.Status, res.Message)
+	}
+
+	// vote key is endorsed by org of its voter only
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+	castvote(t, stub, "Org3MSP", stubsert3, "VoteHash", "neutral", "")
+	for voter, org := range map[string]string{voter1: "Org1MSP", voter2: "Org2MSP", voter3: "Org3MSP"} {
+		if orgs, n := endorsementof(t, stub, "VoteHash|"+voter); orgs != org || n != 1 {
+			t.Errorf("endorsement of vote of %s: %s, %d of them", voter, orgs, n)
+		}
+	}
+
+	// runoff keeps orgs of its first round
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if orgs, _ := endorsementof(t, stub, "VoteHash-runoff"); orgs != "Org1MSP,Org2MSP" {
+		t.Errorf("endorsement of runoff %s", orgs)
+	}
+
+	// archive hands vote keys over to endorsers of ballot and deletes them
+	if res := stub.MockInvoke("1", argsbytes("votearchive", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	for _, voter := range []string{voter1, voter2, voter3} {
+		if v, _ := stub.GetState("VoteHash|" + voter); v != nil {
+			t.Errorf("vote of %s is not pruned", voter)
+		}
+		if orgs, n := endorsementof(t, stub, "VoteHash|"+voter); orgs != "Org1MSP,Org2MSP" || n != 2 {
+			t.Errorf("endorsement of pruned vote of %s: %s, %d of them", voter, orgs, n)
+		}
+	}
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if a := b.Archive; a == nil || a.Pruned != 3 || len(a.Votes) != 3 {
+		t.Errorf("archive of endorsed votes %+v", a)
+	}
+
+	// ballot of unknown invoker without endorsers keeps policy of chaincode
+	stub.MockCreator("", nil)
+	startvoting(t, stub, "Anonymous", voter1)
+	if orgs, _ := endorsementof(t, stub, "Anonymous"); orgs != "" {
+		t.Errorf("endorsement of ballot without orgs %s", orgs)
+	}
+}
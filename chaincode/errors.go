@@ -0,0 +1,193 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// statuses of chaincode errors, as of HTTP. Fabric treats every status from 400 as error
+const (
+	statusbadrequest = 400
+	statusforbidden  = 403
+	statusnotfound   = 404
+	statusconflict   = 409
+	statusinternal   = 500
+)
+
+// stable codes of chaincode errors - clients match on them, not on messages
+const (
+	codebadargs         = "BAD_ARGS"
+	codeunknownfunction = "UNKNOWN_FUNCTION"
+	codeduplicatevoters = "DUPLICATE_VOTERS"
+	codebadidentity     = "BAD_IDENTITY"
+	codenoteligible     = "NOT_ELIGIBLE"
+	codenotacknowledged = "NOT_ACKNOWLEDGED"
+	codenotproposer     = "NOT_PROPOSER"
+	codenotapprover     = "NOT_APPROVER"
+	codenotowner        = "NOT_OWNER"
+	codenotregistrar    = "NOT_REGISTRAR"
+	codevoterinactive   = "VOTER_INACTIVE"
+	codecertmismatch    = "CERT_MISMATCH"
+	codeballotclosed    = "BALLOT_CLOSED"
+	codeinvalidstate    = "INVALID_STATE"
+	codedeadlinepassed  = "DEADLINE_PASSED"
+	codeballotnotfound  = "BALLOT_NOT_FOUND"
+	codegroupnotfound   = "GROUP_NOT_FOUND"
+	codegroupexists     = "GROUP_EXISTS"
+	codevoternotfound   = "VOTER_NOT_FOUND"
+	codevoterexists     = "VOTER_EXISTS"
+	codevotenotfound    = "VOTE_NOT_FOUND"
+	codekeynotfound     = "KEY_NOT_FOUND"
+	codecountfailed     = "COUNT_FAILED"
+	codeledger          = "LEDGER_ERROR"
+	codeinternal        = "INTERNAL"
+)
+
+// struct for typed error of chaincode, payload of error response
+type ccerror struct {
+	Code    string            `json:"code"`
+	Status  int32             `json:"status"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"` // ids of ballot, voter, function...
+}
+
+func (e *ccerror) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// response with status of error, message "CODE: message" and error as json payload
+func (e *ccerror) response() pb.Response {
+	payload, _ := json.Marshal(e)
+	return pb.Response{Status: e.Status, Message: e.Error(), Payload: payload}
+}
+
+// response of any error, errors out of catalogue are internal
+func errorresponse(err error) pb.Response {
+	if e, ok := err.(*ccerror); ok {
+		return e.response()
+	}
+	return errinternal(err).response()
+}
+
+func newccerror(code string, status int32, details map[string]string, format string, a ...interface{}) *ccerror {
+	return &ccerror{Code: code, Status: status, Message: fmt.Sprintf(format, a...), Details: details}
+}
+
+//----------------- catalogue
+
+func errbadargs(function string, err error) *ccerror {
+	return newccerror(codebadargs, statusbadrequest, map[string]string{"function": function}, "%s", err)
+}
+
+func errunknownfunction(function string) *ccerror {
+	return newccerror(codeunknownfunction, statusbadrequest, map[string]string{"function": function},
+		"unknown function %q, call describe for list of functions", function)
+}
+
+func errduplicatevoters(voteid string, err error) *ccerror {
+	return newccerror(codeduplicatevoters, statusbadrequest, map[string]string{"voteid": voteid}, "%s", err)
+}
+
+func errbadidentity(err error) *ccerror {
+	return newccerror(codebadidentity, statusforbidden, nil, "can't get identity of invoker: %s", err)
+}
+
+func errnoteligible(voteid string, voter string) *ccerror {
+	return newccerror(codenoteligible, statusforbidden, map[string]string{"voteid": voteid, "voter": voter},
+		"%s is not in list of voters of %s", voter, voteid)
+}
+
+func errnotacknowledged(voteid string, voter string, err error) *ccerror {
+	return newccerror(codenotacknowledged, statusforbidden, map[string]string{"voteid": voteid, "voter": voter}, "%s", err)
+}
+
+func errnotproposer(voteid string, invoker string, action string) *ccerror {
+	return newccerror(codenotproposer, statusforbidden, map[string]string{"voteid": voteid, "invoker": invoker},
+		"only proposer can %s %s, %s is not", action, voteid, invoker)
+}
+
+func errnotapprover(voteid string, invoker string, org string) *ccerror {
+	return newccerror(codenotapprover, statusforbidden, map[string]string{"voteid": voteid, "invoker": invoker, "org": org},
+		"only proposer and sponsor orgs approve extension of %s, %s of %s is not", voteid, invoker, org)
+}
+
+func errnotowner(group string, invoker string, action string) *ccerror {
+	return newccerror(codenotowner, statusforbidden, map[string]string{"group": group, "invoker": invoker},
+		"only owner and admin orgs can %s group %s, %s is not", action, group, invoker)
+}
+
+func errnotregistrar(voter string, invoker string, org string, action string) *ccerror {
+	return newccerror(codenotregistrar, statusforbidden, map[string]string{"voter": voter, "invoker": invoker, "org": org},
+		"only admin orgs and voter itself in its MSP can %s voter %s, %s of %s is not", action, voter, invoker, org)
+}
+
+func errnotadminoption(voter string, invoker string, org string, option string) *ccerror {
+	return newccerror(codenotregistrar, statusforbidden, map[string]string{"voter": voter, "invoker": invoker, "org": org, "option": option},
+		"only admin orgs can set %s of voter %s, %s of %s is not", option, voter, invoker, org)
+}
+
+func errvoterinactive(voter string) *ccerror {
+	return newccerror(codevoterinactive, statusforbidden, map[string]string{"voter": voter}, "voter %s is deactivated", voter)
+}
+
+func errcertmismatch(voter string, org string) *ccerror {
+	return newccerror(codecertmismatch, statusforbidden, map[string]string{"voter": voter, "org": org},
+		"certificate of invoker of %s is not the one voter %s is bound to", org, voter)
+}
+
+func errballotclosed(voteid string) *ccerror {
+	return newccerror(codeballotclosed, statusforbidden, map[string]string{"voteid": voteid}, "voting %s is closed", voteid)
+}
+
+func errinvalidstate(voteid string, state string, action string) *ccerror {
+	return newccerror(codeinvalidstate, statusconflict, map[string]string{"voteid": voteid, "state": state},
+		"can't %s %s in state %s", action, voteid, state)
+}
+
+func errdeadlinepassed(voteid string, enddate string, action string) *ccerror {
+	return newccerror(codedeadlinepassed, statusconflict, map[string]string{"voteid": voteid, "enddate": enddate},
+		"can't %s %s, end date %s has passed", action, voteid, enddate)
+}
+
+func errballotnotfound(voteid string) *ccerror {
+	return newccerror(codeballotnotfound, statusnotfound, map[string]string{"voteid": voteid}, "no such voting %s", voteid)
+}
+
+func errgroupnotfound(group string) *ccerror {
+	return newccerror(codegroupnotfound, statusnotfound, map[string]string{"group": group}, "no such group %s", group)
+}
+
+func errgroupexists(group string) *ccerror {
+	return newccerror(codegroupexists, statusconflict, map[string]string{"group": group}, "group %s exists", group)
+}
+
+func errvoternotfound(voter string) *ccerror {
+	return newccerror(codevoternotfound, statusnotfound, map[string]string{"voter": voter}, "voter %s is not registered", voter)
+}
+
+func errvoterexists(voter string) *ccerror {
+	return newccerror(codevoterexists, statusconflict, map[string]string{"voter": voter}, "voter %s is registered", voter)
+}
+
+func errvotenotfound(voteid string, voter string) *ccerror {
+	return newccerror(codevotenotfound, statusnotfound, map[string]string{"voteid": voteid, "voter": voter},
+		"no counted vote of %s in %s", voter, voteid)
+}
+
+func errkeynotfound(key string) *ccerror {
+	return newccerror(codekeynotfound, statusnotfound, map[string]string{"key": key}, "no value of key %s", key)
+}
+
+func errcountfailed(voteid string, err error) *ccerror {
+	return newccerror(codecountfailed, statusinternal, map[string]string{"voteid": voteid}, "error counting result: %s", err)
+}
+
+func errledger(key string, err error) *ccerror {
+	return newccerror(codeledger, statusinternal, map[string]string{"key": key}, "%s", err)
+}
+
+func errinternal(err error) *ccerror {
+	return newccerror(codeinternal, statusinternal, nil, "%s", err)
+}
@@ -0,0 +1,59 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestErrors_Catalogue(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", certvoter(t, stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Expired", "https://git.repo", "10.04.2019.10.00", certvoter(t, stubsert1))); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+
+	cases := []struct {
+		args    []string
+		code    string
+		status  int32
+		details map[string]string
+	}{
+		{[]string{"votes"}, codeunknownfunction, 400, map[string]string{"function": "votes"}},
+		{[]string{"vote", "VoteHash"}, codebadargs, 400, map[string]string{"function": "vote"}},
+		{[]string{"votestart", "Dup", "https://git.repo", "10.04.2099.10.00", "org1", "org1"}, codeduplicatevoters, 400, map[string]string{"voteid": "Dup"}},
+		{[]string{"vote", "NoHash", "yes"}, codeballotnotfound, 404, map[string]string{"voteid": "NoHash"}},
+		{[]string{"vote", "VoteHash", "yes"}, codenoteligible, 403, map[string]string{"voteid": "VoteHash", "voter": certvoter(t, stubsert1)}},
+		{[]string{"vote", "Expired", "yes"}, codedeadlinepassed, 409, map[string]string{"voteid": "Expired", "enddate": "10.04.2019.10.00"}},
+		{[]string{"voteproof", "VoteHash"}, codevotenotfound, 404, map[string]string{"voteid": "VoteHash", "voter": certvoter(t, stubsert1)}},
+		{[]string{"voteresult", "NoHash"}, codeballotnotfound, 404, map[string]string{"voteid": "NoHash"}},
+		{[]string{"voteresult", "VoteHash", "sort=voter"}, codebadargs, 400, map[string]string{"function": "voteresult"}},
+		{[]string{"votehistory", "NoKey"}, codekeynotfound, 404, map[string]string{"key": "NoKey"}},
+	}
+
+	for _, c := range cases {
+		res := stub.MockInvoke("1", argsbytes(c.args...))
+		if res.Status != c.status {
+			t.Errorf("%v: status %d, expected %d: %s", c.args, res.Status, c.status, res.Message)
+			continue
+		}
+
+		var e ccerror
+		if err := json.Unmarshal(res.Payload, &e); err != nil {
+			t.Errorf("%v: payload is not error: %s", c.args, res.Payload)
+			continue
+		}
+		if e.Code != c.code || e.Status != c.status || res.Message != e.Error() {
+			t.Errorf("%v: error %+v, expected %s", c.args, e, c.code)
+		}
+		for k, v := range c.details {
+			if e.Details[k] != v {
+				t.Errorf("%v: details %v, expected %s=%s", c.args, e.Details, k, v)
+			}
+		}
+	}
+}
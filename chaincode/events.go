@@ -0,0 +1,49 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// names of chaincode events, one event per tx which changes state
+const (
+	eventballotstarted      = "ballotstarted"
+	eventvotecast           = "votecast"
+	eventvotewithdrawn      = "votewithdrawn"
+	eventballotclosed       = "ballotclosed"
+	eventballotcancelled    = "ballotcancelled"
+	eventballotarchived     = "ballotarchived"
+	eventballotextended     = "ballotextended"     // extension of end date is applied
+	eventextensionrequested = "extensionrequested" // extension of end date waits for approvals
+	eventresultpublished    = "resultpublished"
+)
+
+// payload of chaincode event - ballot of started \ closed, vote of cast \ withdrawn, result of published.
+// Runoff opened by close is in ballotclosed event, as tx has one event
+type ccevent struct {
+	VoteID string      `json:"voteid"`
+	Ballot *votelist   `json:"ballot,omitempty"`
+	Runoff *ballotview `json:"runoff,omitempty"` // runoff opened by close
+	Vote   *voterslist `json:"vote,omitempty"`   // vote cast
+	Voter  string      `json:"voter,omitempty"`  // voter of withdrawn vote
+	Result *ccresult   `json:"result,omitempty"` // published result
+}
+
+// published result in event - without entries of voters, they are in votecast events
+type ccresult struct {
+	Counts     map[string]int `json:"counts"`
+	Decision   string         `json:"decision"`
+	Decided    bool           `json:"decided"`
+	MerkleRoot string         `json:"merkleroot"`
+	Leaves     int            `json:"leaves"`
+}
+
+// set event of tx, fabric keeps only last event of tx
+func setevent(stub shim.ChaincodeStubInterface, name string, ev ccevent) error {
+	payload, _ := json.Marshal(ev)
+	if err := stub.SetEvent(name, payload); err != nil {
+		return errinternal(err)
+	}
+	return nil
+}
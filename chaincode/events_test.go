@@ -0,0 +1,61 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// event of last invoke, fails test without it
+func lastevent(t *testing.T, stub *cckit.MockStub, name string) ccevent {
+	if stub.ChaincodeEvent == nil || stub.ChaincodeEvent.EventName != name {
+		t.Fatalf("expected event %s, got %v", name, stub.ChaincodeEvent)
+	}
+	var ev ccevent
+	if err := json.Unmarshal(stub.ChaincodeEvent.Payload, &ev); err != nil {
+		t.Fatalf("event %s is not json: %s", name, err)
+	}
+	return ev
+}
+
+func TestEvents(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	startvoting(t, stub, "VoteHash", voter1, voter2, "title=Release")
+	if ev := lastevent(t, stub, eventballotstarted); ev.VoteID != "VoteHash" || ev.Ballot == nil || ev.Ballot.Title != "Release" || len(ev.Ballot.Voters) != 2 {
+		t.Errorf("wrong ballotstarted %+v", ev)
+	}
+
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "ok")
+	if ev := lastevent(t, stub, eventvotecast); ev.Vote == nil || ev.Vote.Voter != voter1 || ev.Vote.Org != "Org1MSP" || ev.Vote.Vote != "yes" {
+		t.Errorf("wrong votecast %+v", ev)
+	}
+
+	stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash"))
+	if ev := lastevent(t, stub, eventvotewithdrawn); ev.Voter != voter1 {
+		t.Errorf("wrong votewithdrawn %+v", ev)
+	}
+
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	stub.MockInvoke("1", argsbytes("voteend", "VoteHash"))
+	if ev := lastevent(t, stub, eventballotclosed); ev.Ballot == nil || !ev.Ballot.Closed {
+		t.Errorf("wrong ballotclosed %+v", ev)
+	}
+
+	stub.MockInvoke("1", argsbytes("voteresult", "VoteHash"))
+	if ev := lastevent(t, stub, eventresultpublished); ev.Result == nil || ev.Result.Decision != "no" || ev.Result.Leaves != 1 || ev.Result.MerkleRoot == "" {
+		t.Errorf("wrong resultpublished %+v", ev)
+	}
+
+	// queries and failed invokes have no events
+	stub.MockInvoke("1", argsbytes("voteinfo", "VoteHash"))
+	stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes"))
+	if stub.ChaincodeEvent != nil {
+		t.Errorf("event %s of query or failed invoke", stub.ChaincodeEvent.EventName)
+	}
+}
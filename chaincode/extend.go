@@ -0,0 +1,169 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/tally"
+	id "github.com/s7techlab/cckit/identity"
+)
+
+// struct for approval of extension by member of org
+type approval struct {
+	By  string `json:"by"`
+	Org string `json:"org"` // MSP ID, one approval of org is counted
+	At  string `json:"at"`
+}
+
+// struct for request of extension of end date in log of ballot, in order of requests
+type extension struct {
+	From      string     `json:"from"`    // end date before request
+	EndDate   string     `json:"enddate"` // requested end date
+	By        string     `json:"by"`
+	Org       string     `json:"org"`
+	At        string     `json:"at"`
+	Reason    string     `json:"reason,omitempty"`
+	Approvals []approval `json:"approvals"`
+	AppliedAt string     `json:"appliedat,omitempty"` // tx time of approval which applied extension, empty while pending
+}
+
+// approvals of extension required by ballot, one by default
+func (v *votelist) requiredapprovals() int {
+	if v.Approvals == 0 {
+		return 1
+	}
+	return v.Approvals
+}
+
+// proposer and members of sponsor orgs request and approve extensions
+func (v *votelist) approver(invoker string, org string) bool {
+	if invoker == v.Proposer {
+		return true
+	}
+	for _, sponsor := range v.Sponsors {
+		if sponsor == org {
+			return true
+		}
+	}
+	return false
+}
+
+// extension is approved by org other than org of request - proposer can't extend own ballot alone
+func (ext *extension) approvedbyother() bool {
+	for _, a := range ext.Approvals {
+		if a.Org != ext.Org {
+			return true
+		}
+	}
+	return false
+}
+
+// pending request of extension, the last one if it is not applied. Requests before it are superseded
+func (v *votelist) pending() *extension {
+	if n := len(v.Extensions); n > 0 && v.Extensions[n-1].AppliedAt == "" {
+		return &v.Extensions[n-1]
+	}
+	return nil
+}
+
+// check that end date extends ballot within maximum total extension from end date of votestart
+func (v *votelist) checkextension(enddate string) error {
+	if v.MaxExtend == "" {
+		return fmt.Errorf("voting has no extension, maxextend is not set")
+	}
+	maxextend, _ := time.ParseDuration(v.MaxExtend)
+
+	requested, ok := tally.Deadline(enddate)
+	if !ok {
+		return fmt.Errorf("end date %q is not date", enddate)
+	}
+	current, _ := tally.Deadline(v.EndDate)
+	if !requested.After(current) {
+		return fmt.Errorf("end date %s is not after %s", enddate, v.EndDate)
+	}
+	original := v.EndDate
+	if len(v.Extensions) > 0 {
+		original = v.Extensions[0].From
+	}
+	start, _ := tally.Deadline(original)
+	if requested.Sub(start) > maxextend {
+		return fmt.Errorf("end date %s extends end date %s more than %s", enddate, original, v.MaxExtend)
+	}
+	if runoff, ok := v.runoffdeadline(); ok && !requested.Before(runoff) {
+		return fmt.Errorf("end date %s is not before end date of runoff %s", enddate, v.RunoffEnd)
+	}
+	return nil
+}
+
+// request or approve extension of end date of open ballot to args[1], reason in args[2], admin orgs of config can as well.
+// Request of other end date supersedes pending one, extension is applied by approvals of required number of orgs,
+// one of them is sponsor or admin org other than org of request
+func (t *SimpleChaincode) voteextend(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid, enddate := args[0], args[1]
+	reason := ""
+	if len(args) > 2 {
+		reason = args[2]
+	}
+
+	votestruct, err := getballot(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := votestruct.checkopen(voteid, "extend"); err != nil {
+		return err.response()
+	}
+
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err).response()
+	}
+	tr, err := newtransition(stub, certident, reason)
+	if err != nil {
+		return errorresponse(err)
+	}
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if !votestruct.approver(tr.By, tr.Org) && !cfg.admin(tr.Org) {
+		return errnotapprover(voteid, tr.By, tr.Org).response()
+	}
+	if err := votestruct.checkextension(enddate); err != nil {
+		return errbadargs("voteextend", err).response()
+	}
+
+	ext := votestruct.pending()
+	if ext == nil || ext.EndDate != enddate {
+		votestruct.Extensions = append(votestruct.Extensions,
+			extension{From: votestruct.EndDate, EndDate: enddate, By: tr.By, Org: tr.Org, At: tr.At, Reason: reason})
+		ext = votestruct.pending()
+	}
+	for _, a := range ext.Approvals {
+		if a.Org == tr.Org {
+			return errbadargs("voteextend", fmt.Errorf("%s has approved extension of %s to %s", tr.Org, voteid, enddate)).response()
+		}
+	}
+	ext.Approvals = append(ext.Approvals, approval{By: tr.By, Org: tr.Org, At: tr.At})
+
+	event := eventextensionrequested
+	if len(ext.Approvals) >= votestruct.requiredapprovals() && ext.approvedbyother() {
+		ext.AppliedAt = tr.At
+		votestruct.EndDate = enddate
+		event = eventballotextended
+	}
+
+	value, _ := json.Marshal(votestruct)
+	if err := stub.PutState(voteid, value); err != nil {
+		return errledger(voteid, err).response()
+	}
+	if err := setevent(stub, event, ccevent{VoteID: voteid, Ballot: votestruct}); err != nil {
+		return errorresponse(err)
+	}
+
+	answer, _ := json.Marshal(ext)
+	return shim.Success(answer)
+}
@@ -0,0 +1,134 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// voteextend from identity
+func extend(stub *cckit.MockStub, mspid string, cert string, args ...string) (extension, int32, string) {
+	stub.MockCreator(mspid, []byte(cert))
+	res := stub.MockInvoke("1", argsbytes(append([]string{"voteextend", "VoteHash"}, args...)...))
+	var ext extension
+	if res.Status == shim.OK {
+		json.Unmarshal(res.Payload, &ext)
+	}
+	return ext, res.Status, res.Message
+}
+
+func TestVoteExtend(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, "sponsor=Org2MSP", "approvals=3")); res.Status != statusbadrequest {
+		t.Errorf("approvals of more orgs than sponsors: %d %s", res.Status, res.Message)
+	}
+	startvoting(t, stub, "VoteHash", voter1, voter2, voter3, "maxextend=72h", "sponsor=Org2MSP", "approvals=2")
+
+	if _, status, msg := extend(stub, "Org3MSP", stubsert3, "12.04.2099.10.00"); !strings.HasPrefix(msg, codenotapprover) {
+		t.Errorf("extension by not sponsor: %d %s", status, msg)
+	}
+
+	// proposer requests, sponsor org approves
+	ext, status, msg := extend(stub, "Org1MSP", stubsert1, "12.04.2099.10.00", "holidays")
+	if status != shim.OK || ext.AppliedAt != "" || len(ext.Approvals) != 1 || ext.From != "10.04.2099.10.00" {
+		t.Fatalf("request of extension %+v: %s", ext, msg)
+	}
+	if ev := lastevent(t, stub, eventextensionrequested); ev.Ballot.EndDate != "10.04.2099.10.00" {
+		t.Errorf("end date before approvals %s", ev.Ballot.EndDate)
+	}
+	if _, status, _ := extend(stub, "Org1MSP", stubsert1, "12.04.2099.10.00"); status != statusbadrequest {
+		t.Errorf("second approval of org: %d", status)
+	}
+	ext, status, msg = extend(stub, "Org2MSP", stubsert2, "12.04.2099.10.00")
+	if status != shim.OK || ext.AppliedAt == "" || len(ext.Approvals) != 2 || ext.Approvals[1].By != voter2 {
+		t.Fatalf("approval of extension %+v: %s", ext, msg)
+	}
+	if ev := lastevent(t, stub, eventballotextended); ev.Ballot.EndDate != "12.04.2099.10.00" {
+		t.Errorf("end date after approvals %s", ev.Ballot.EndDate)
+	}
+
+	// total extension is counted from end date of votestart
+	for _, enddate := range []string{"14.04.2099.10.00", "11.04.2099.10.00", "soon"} {
+		if _, status, msg := extend(stub, "Org1MSP", stubsert1, enddate); status != statusbadrequest {
+			t.Errorf("extension to %s: %d %s", enddate, status, msg)
+		}
+	}
+
+	// request of other end date supersedes pending one
+	extend(stub, "Org2MSP", stubsert2, "13.04.2099.10.00", "missing voters")
+	extend(stub, "Org1MSP", stubsert1, "13.04.2099.09.00")
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if b.EndDate != "12.04.2099.10.00" || len(b.Extensions) != 3 || b.Extensions[1].Reason != "missing voters" || b.Extensions[2].AppliedAt != "" {
+		t.Errorf("log of extensions %+v", b.Extensions)
+	}
+
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if _, status, msg := extend(stub, "Org1MSP", stubsert1, "13.04.2099.09.00"); !strings.HasPrefix(msg, codeballotclosed) {
+		t.Errorf("extension of closed: %d %s", status, msg)
+	}
+
+	startvoting(t, stub, "Vote2", voter1)
+	if res := stub.MockInvoke("1", argsbytes("voteextend", "Vote2", "12.04.2099.10.00")); res.Status != statusbadrequest {
+		t.Errorf("extension without maxextend: %d %s", res.Status, res.Message)
+	}
+}
+
+func TestVote_Deadline(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+
+	// before end date votes are cast and withdrawn
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, "maxextend=48h", "sponsor=Org2MSP")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+
+	// after end date they are not, ballot is still open until voteend
+	var b votelist
+	value, _ := stub.GetState("VoteHash")
+	json.Unmarshal(value, &b)
+	b.EndDate = past
+	value, _ = json.Marshal(b)
+	stub.MockTransactionStart("expire")
+	stub.PutState("VoteHash", value)
+	stub.MockTransactionEnd("expire")
+
+	for _, args := range [][]string{{"vote", "VoteHash", "yes"}, {"votewithdraw", "VoteHash"}} {
+		res := stub.MockInvoke("1", argsbytes(args...))
+		var e ccerror
+		json.Unmarshal(res.Payload, &e)
+		if res.Status != statusconflict || e.Code != codedeadlinepassed || e.Details["voteid"] != "VoteHash" || e.Details["enddate"] != past {
+			t.Errorf("%s after end date: %d %s", args[0], res.Status, res.Message)
+		}
+	}
+
+	// extension moves deadline when org other than org of proposer approves it
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	if ext, status, msg := extend(stub, "Org1MSP", stubsert1, future); status != shim.OK || ext.AppliedAt != "" {
+		t.Fatalf("extension of expired ballot by proposer alone %+v: %s", ext, msg)
+	}
+	if ext, status, msg := extend(stub, "Org2MSP", stubsert2, future); status != shim.OK || ext.AppliedAt == "" {
+		t.Fatalf("extension of expired ballot %+v: %s", ext, msg)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != shim.OK {
+		t.Errorf("withdraw after extension: %s", res.Message)
+	}
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "yes", "")
+}
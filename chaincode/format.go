@@ -0,0 +1,492 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// formatter renders report of vote in one of export formats
+type formatter interface {
+	format(rep votereport, opts reportopts) ([]byte, error)
+}
+
+// formatters by name of format= option of voteresult \ voteresultcsv
+var formatters = map[string]formatter{
+	"json":     jsonformatter{},
+	"jsonl":    jsonlformatter{},
+	"xml":      xmlformatter{},
+	"markdown": markdownformatter{},
+	"md":       markdownformatter{},
+	"html":     htmlformatter{},
+	"text":     textformatter{},
+	"csv":      csvformatter{},
+}
+
+// render report by formatter of options
+func (r votereport) render(opts reportopts) ([]byte, error) {
+	f, ok := formatters[opts.format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", opts.format)
+	}
+	return f.format(r, opts)
+}
+
+// jsonformatter renders report as one json document
+type jsonformatter struct{}
+
+func (jsonformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	return json.Marshal(rep)
+}
+
+// line of jsonl - entry of voter with vote data
+type reportline struct {
+	VoteID   string `json:"voteid"`
+	Decision string `json:"decision"`
+	voterslist
+}
+
+// jsonlformatter renders report as json object per line, for each voter
+type jsonlformatter struct{}
+
+func (jsonlformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	for _, v := range rep.Votes {
+		if err := enc.Encode(reportline{rep.VoteID, rep.Decision, v}); err != nil {
+			return nil, err
+		}
+	}
+	for _, voter := range rep.Abstained {
+		if err := enc.Encode(reportline{rep.VoteID, rep.Decision, voterslist{Voter: voter, Vote: constabstained}}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// structs of xml document
+type xmlreport struct {
+	XMLName     xml.Name      `xml:"report"`
+	VoteID      string        `xml:"voteid,attr"`
+	RepoURL     string        `xml:"repourl"`
+	EndDate     string        `xml:"enddate"`
+	Title       string        `xml:"title,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Proposer    string        `xml:"proposer,omitempty"`
+	Tags        []string      `xml:"tags>tag,omitempty"`
+	Artifacts   []xmlartifact `xml:"artifacts>artifact,omitempty"`
+	Counts      []xmlcount    `xml:"counts>count"`
+	Turnout     xmlturnout    `xml:"turnout"`
+	Decided     bool          `xml:"decided,attr"`
+	Decision    string        `xml:"decision"`
+	Votes       []xmlvote     `xml:"votes>vote"`
+	Abstained   []string      `xml:"abstained>voter"`
+	Merkle      xmlmerkle     `xml:"certificate"`
+	Rounds      *xmlrounds    `xml:"rounds,omitempty"` // only for ballots with runoff
+	State       string        `xml:"state,omitempty"`
+	Transitions *xmlstates    `xml:"transitions,omitempty"`
+}
+
+type xmlstates struct {
+	Transitions []xmlstate `xml:"transition"`
+}
+
+type xmlstate struct {
+	From   string `xml:"from,attr,omitempty"`
+	To     string `xml:"to,attr"`
+	By     string `xml:"by,attr"`
+	Org    string `xml:"org,attr,omitempty"`
+	At     string `xml:"at,attr"`
+	Reason string `xml:",chardata"`
+}
+
+type xmlrounds struct {
+	Rounds []xmlround `xml:"round"`
+}
+
+type xmlround struct {
+	VoteID   string     `xml:"voteid,attr"`
+	Round    int        `xml:"number,attr"`
+	Closed   bool       `xml:"closed,attr"`
+	Decided  bool       `xml:"decided,attr"`
+	Options  []string   `xml:"options>option"`
+	Counts   []xmlcount `xml:"counts>count"`
+	Decision string     `xml:"decision"`
+}
+
+type xmlmerkle struct {
+	Algorithm string   `xml:"algorithm,attr"`
+	Leaves    int      `xml:"leaves,attr"`
+	Root      string   `xml:"merkleroot"`
+	TxIDs     []string `xml:"txids>txid"`
+}
+
+type xmlartifact struct {
+	Name   string `xml:"name,attr"`
+	SHA256 string `xml:"sha256,attr,omitempty"`
+	Commit string `xml:"commit,attr,omitempty"`
+}
+
+type xmlcount struct {
+	Option string `xml:"option,attr"`
+	Votes  int    `xml:",chardata"`
+}
+
+type xmlturnout struct {
+	Registered int    `xml:"registered,attr"`
+	Voted      int    `xml:"voted,attr"`
+	Percent    string `xml:"percent,attr"`
+}
+
+type xmlvote struct {
+	Voter     string `xml:"voter,attr"`
+	Org       string `xml:"org,attr,omitempty"`
+	TxID      string `xml:"txid,attr,omitempty"`
+	Timestamp string `xml:"timestamp,attr,omitempty"`
+	Vote      string `xml:"choice"`
+	Comment   string `xml:"comment"`
+}
+
+// xmlformatter renders report as xml document
+type xmlformatter struct{}
+
+func (xmlformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	doc := xmlreport{
+		VoteID:      rep.VoteID,
+		RepoURL:     rep.RepoURL,
+		EndDate:     rep.EndDate,
+		Title:       rep.Title,
+		Description: rep.Description,
+		Proposer:    rep.Proposer,
+		Tags:        rep.Tags,
+		Turnout:     xmlturnout{rep.Turnout.Registered, rep.Turnout.Voted, fmt.Sprintf("%.1f", rep.Turnout.Percent)},
+		Decided:     rep.Decided,
+		Decision:    rep.Decision,
+		Abstained:   rep.Abstained,
+		Merkle:      xmlmerkle{rep.Certificate.Algorithm, rep.Certificate.Leaves, rep.Certificate.MerkleRoot, rep.Certificate.TxIDs},
+		State:       rep.State,
+	}
+
+	for _, a := range rep.Artifacts {
+		doc.Artifacts = append(doc.Artifacts, xmlartifact{a.Name, a.SHA256, a.Commit})
+	}
+	for _, option := range voteoptions {
+		doc.Counts = append(doc.Counts, xmlcount{option, rep.Counts[option]})
+	}
+	for _, v := range rep.Votes {
+		doc.Votes = append(doc.Votes, xmlvote{v.Voter, v.Org, v.TxID, v.Timestamp, v.Vote, v.Comment})
+	}
+	if len(rep.Rounds) > 0 {
+		doc.Rounds = &xmlrounds{}
+	}
+	for _, r := range rep.Rounds {
+		round := xmlround{VoteID: r.VoteID, Round: r.Round, Closed: r.Closed, Decided: r.Decided, Options: r.Options, Decision: r.Decision}
+		for _, option := range r.Options {
+			round.Counts = append(round.Counts, xmlcount{option, r.Counts[option]})
+		}
+		doc.Rounds.Rounds = append(doc.Rounds.Rounds, round)
+	}
+	if len(rep.Transitions) > 0 {
+		doc.Transitions = &xmlstates{}
+	}
+	for _, tr := range rep.Transitions {
+		doc.Transitions.Transitions = append(doc.Transitions.Transitions, xmlstate{tr.From, tr.To, tr.By, tr.Org, tr.At, tr.Reason})
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+// markdownformatter renders report as markdown for meeting minutes
+type markdownformatter struct{}
+
+func (markdownformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	var b strings.Builder
+
+	if rep.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", mdcell(rep.Title))
+		fmt.Fprintf(&b, "Vote %s\n\n", mdcell(rep.VoteID))
+	} else {
+		fmt.Fprintf(&b, "# Vote %s\n\n", mdcell(rep.VoteID))
+	}
+	if rep.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", rep.Description)
+	}
+	fmt.Fprintf(&b, "- Repo Url: %s\n", mdcell(rep.RepoURL))
+	fmt.Fprintf(&b, "- End Date: %s\n", mdcell(rep.EndDate))
+	if rep.State != "" {
+		fmt.Fprintf(&b, "- State: %s\n", rep.State)
+	}
+	if rep.Proposer != "" {
+		fmt.Fprintf(&b, "- Proposer: %s\n", mdcell(rep.Proposer))
+	}
+	if len(rep.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", mdcell(strings.Join(rep.Tags, ", ")))
+	}
+	for _, a := range rep.Artifacts {
+		fmt.Fprintf(&b, "- Artifact: %s %s\n", mdcell(a.Name), artifactlabel(a))
+	}
+	fmt.Fprintf(&b, "- Turnout: %d of %d (%.1f%%)\n", rep.Turnout.Voted, rep.Turnout.Registered, rep.Turnout.Percent)
+	fmt.Fprintf(&b, "- Resolution of Voting: **%s**\n", rep.resolution())
+	fmt.Fprintf(&b, "- Merkle Root: `%s` (%d votes)\n\n", rep.Certificate.MerkleRoot, rep.Certificate.Leaves)
+
+	b.WriteString("| Option | Votes |\n|---|---|\n")
+	for _, option := range voteoptions {
+		fmt.Fprintf(&b, "| %s | %d |\n", option, rep.Counts[option])
+	}
+
+	if len(rep.Votes) > 0 {
+		b.WriteString("\n| Voter | Org | Vote | Comment | Time |\n|---|---|---|---|---|\n")
+		for _, v := range rep.Votes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", mdcell(v.Voter), mdcell(v.Org), v.Vote, mdcell(v.Comment), v.Timestamp)
+		}
+	}
+
+	if len(rep.Abstained) > 0 {
+		b.WriteString("\nAbstained:\n\n")
+		for _, voter := range rep.Abstained {
+			fmt.Fprintf(&b, "- %s\n", mdcell(voter))
+		}
+	}
+
+	if len(rep.Rounds) > 0 {
+		b.WriteString("\n| Round | Vote | Options | Decision |\n|---|---|---|---|\n")
+		for _, r := range rep.Rounds {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", r.Round, mdcell(r.VoteID), roundcounts(r), roundstatus(r))
+		}
+	}
+
+	if len(rep.Transitions) > 0 {
+		b.WriteString("\n| State | By | Time | Reason |\n|---|---|---|---|\n")
+		for _, tr := range rep.Transitions {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", transitionstates(tr), mdcell(transitionby(tr)), tr.At, mdcell(tr.Reason))
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+// states of transition: open, open -> closed
+func transitionstates(tr transition) string {
+	if tr.From == "" {
+		return tr.To
+	}
+	return tr.From + " -> " + tr.To
+}
+
+// invoker of transition with org: ca.org1.example.com (Org1MSP)
+func transitionby(tr transition) string {
+	if tr.Org == "" {
+		return tr.By
+	}
+	return tr.By + " (" + tr.Org + ")"
+}
+
+// counts of options of round: yes 1, no 1
+func roundcounts(r roundsummary) string {
+	counts := []string{}
+	for _, option := range r.Options {
+		counts = append(counts, fmt.Sprintf("%s %d", option, r.Counts[option]))
+	}
+	return strings.Join(counts, ", ")
+}
+
+// decision of round with its state: open, runoff for rounds without decision
+func roundstatus(r roundsummary) string {
+	switch {
+	case !r.Closed:
+		return r.Decision + " (open)"
+	case !r.Decided:
+		return r.Decision + " (no decision)"
+	}
+	return r.Decision
+}
+
+// digest of artifact with its kind
+func artifactlabel(a artifact) string {
+	if a.SHA256 != "" {
+		return "sha256:" + a.SHA256
+	}
+	return "git:" + a.Commit
+}
+
+// escape text for table cell of markdown
+func mdcell(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "|", `\|`, -1)
+	s = strings.Replace(s, "\r\n", "<br>", -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+var htmlreport = template.Must(template.New("report").Funcs(template.FuncMap{"roundcounts": roundcounts, "roundstatus": roundstatus,
+	"transitionstates": transitionstates, "transitionby": transitionby}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Vote {{.VoteID}}{{end}}</title>
+</head>
+<body>
+<h1>{{if .Title}}{{.Title}}{{else}}Vote {{.VoteID}}{{end}}</h1>
+{{- if .Title}}
+<p>Vote {{.VoteID}}</p>
+{{- end}}
+{{- if .Description}}
+<p>{{.Description}}</p>
+{{- end}}
+<ul>
+<li>Repo Url: <a href="{{.RepoURL}}">{{.RepoURL}}</a></li>
+<li>End Date: {{.EndDate}}</li>
+{{- if .State}}
+<li>State: {{.State}}</li>
+{{- end}}
+{{- if .Proposer}}
+<li>Proposer: {{.Proposer}}</li>
+{{- end}}
+{{- range .Tags}}
+<li>Tag: {{.}}</li>
+{{- end}}
+{{- range .Artifacts}}
+<li>Artifact: {{.Name}} <code>{{if .SHA256}}sha256:{{.SHA256}}{{else}}git:{{.Commit}}{{end}}</code></li>
+{{- end}}
+<li>Turnout: {{.Turnout.Voted}} of {{.Turnout.Registered}} ({{printf "%.1f" .Turnout.Percent}}%)</li>
+<li>Resolution of Voting: <strong>{{.Resolution}}</strong></li>
+<li>Merkle Root: <code>{{.Certificate.MerkleRoot}}</code> ({{.Certificate.Leaves}} votes)</li>
+</ul>
+<table>
+<tr><th>Option</th><th>Votes</th></tr>
+{{- range .Options}}
+<tr><td>{{.Option}}</td><td>{{.Votes}}</td></tr>
+{{- end}}
+</table>
+{{- if .Votes}}
+<table>
+<tr><th>Voter</th><th>Org</th><th>Vote</th><th>Comment</th><th>Time</th></tr>
+{{- range .Votes}}
+<tr><td>{{.Voter}}</td><td>{{.Org}}</td><td>{{.Vote}}</td><td>{{.Comment}}</td><td>{{.Timestamp}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Abstained}}
+<p>Abstained:</p>
+<ul>
+{{- range .Abstained}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Rounds}}
+<table>
+<tr><th>Round</th><th>Vote</th><th>Options</th><th>Decision</th></tr>
+{{- range .Rounds}}
+<tr><td>{{.Round}}</td><td>{{.VoteID}}</td><td>{{roundcounts .}}</td><td>{{roundstatus .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Transitions}}
+<table>
+<tr><th>State</th><th>By</th><th>Time</th><th>Reason</th></tr>
+{{- range .Transitions}}
+<tr><td>{{transitionstates .}}</td><td>{{transitionby .}}</td><td>{{.At}}</td><td>{{.Reason}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlformatter renders report as html page for meeting minutes
+type htmlformatter struct{}
+
+func (htmlformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	data := struct {
+		votereport
+		Options    []xmlcount
+		Resolution string
+	}{votereport: rep, Resolution: rep.resolution()}
+	for _, option := range voteoptions {
+		data.Options = append(data.Options, xmlcount{option, rep.Counts[option]})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := htmlreport.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// textformatter renders report as human readable text
+type textformatter struct{}
+
+func (textformatter) format(rep votereport, opts reportopts) ([]byte, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Vote ID: %s\n", rep.VoteID)
+	if rep.Title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", rep.Title)
+	}
+	if rep.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", rep.Description)
+	}
+	if rep.Proposer != "" {
+		fmt.Fprintf(&b, "Proposer: %s\n", rep.Proposer)
+	}
+	if len(rep.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags: %s\n", strings.Join(rep.Tags, ", "))
+	}
+	for _, a := range rep.Artifacts {
+		fmt.Fprintf(&b, "Artifact: %s %s\n", a.Name, artifactlabel(a))
+	}
+	fmt.Fprintf(&b, "Repo Url: %s\n", rep.RepoURL)
+	fmt.Fprintf(&b, "End Date: %s\n", rep.EndDate)
+	if rep.State != "" {
+		fmt.Fprintf(&b, "State: %s\n", rep.State)
+	}
+	fmt.Fprintf(&b, "Turnout: %d of %d (%.1f%%)\n", rep.Turnout.Voted, rep.Turnout.Registered, rep.Turnout.Percent)
+	fmt.Fprintf(&b, "Yes: %d No: %d Neutral: %d\n", rep.Counts["yes"], rep.Counts["no"], rep.Counts["neutral"])
+	fmt.Fprintf(&b, "Resolution of Voting: %s\n", rep.resolution())
+	fmt.Fprintf(&b, "Merkle Root: %s (%d votes)\n", rep.Certificate.MerkleRoot, rep.Certificate.Leaves)
+
+	for _, v := range rep.Votes {
+		fmt.Fprintf(&b, "\nVoter: %s\n", v.Voter)
+		if v.Org != "" {
+			fmt.Fprintf(&b, "Org: %s\n", v.Org)
+		}
+		fmt.Fprintf(&b, "Voice: %s\n", v.Vote)
+		fmt.Fprintf(&b, "Comment: %s\n", v.Comment)
+		if v.Timestamp != "" {
+			fmt.Fprintf(&b, "Time: %s\n", v.Timestamp)
+		}
+	}
+
+	if len(rep.Abstained) > 0 {
+		fmt.Fprintf(&b, "\nAbstained: %s\n", strings.Join(rep.Abstained, ", "))
+	}
+
+	for _, r := range rep.Rounds {
+		fmt.Fprintf(&b, "\nRound %d: %s\n", r.Round, r.VoteID)
+		fmt.Fprintf(&b, "Votes: %s\n", roundcounts(r))
+		fmt.Fprintf(&b, "Resolution: %s\n", roundstatus(r))
+	}
+
+	if len(rep.Transitions) > 0 {
+		b.WriteString("\n")
+	}
+	for _, tr := range rep.Transitions {
+		fmt.Fprintf(&b, "Transition: %s by %s at %s", transitionstates(tr), transitionby(tr), tr.At)
+		if tr.Reason != "" {
+			fmt.Fprintf(&b, ": %s", tr.Reason)
+		}
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,105 @@
+package chaincode
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// the same tally for every format: 3 voted, 1 abstained, comments with markup symbols
+func goldenreport(t *testing.T) votereport {
+	votes := []voterslist{
+		{Voter: "ca.org1.example.com", Org: "Org1MSP", Vote: "yes", Comment: "Fine | go <ahead> & merge", TxID: "tx1", Timestamp: "2019-04-10T09:00:00Z"},
+		{Voter: "org2.smple.for.test", Org: "Org2MSP", Vote: "no", Comment: "Needs \"review\"; see [PR]", TxID: "tx2", Timestamp: "2019-04-10T09:05:00Z"},
+		{Voter: "server.fqdn.name", Org: "Org3MSP", Vote: "yes", Comment: "Согласен\nс решением", TxID: "tx3", Timestamp: "2019-04-10T09:10:00Z"},
+	}
+	votestruct := &votelist{
+		RepoURL:     "https://git.repo",
+		EndDate:     "10.04.2019.10.00",
+		Voters:      []string{"ca.org1.example.com", "org2.smple.for.test", "server.fqdn.name", "ca.org4.example.com"},
+		Title:       "Release 2.0",
+		Description: "Merge <release> branch & tag it",
+		Proposer:    "ca.org1.example.com",
+		Tags:        []string{"release", "q2"},
+		Artifacts: []artifact{
+			{Name: "spec.pdf", SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+			{Name: "repo", Commit: "3f786850e387550fdab836ed7e6dc881de23001b"},
+		},
+		State:  stateclosed,
+		Closed: true,
+		Transitions: []transition{
+			{To: stateopen, By: "ca.org1.example.com", Org: "Org1MSP", At: "2019-04-09T10:00:00Z"},
+			{From: stateopen, To: stateclosed, By: "ca.org1.example.com", Org: "Org1MSP", At: "2019-04-10T10:00:00Z", Reason: "end date & <quorum>"},
+		},
+	}
+
+	rep, err := buildreport("VoteHash", votestruct, votes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *rep
+}
+
+func TestFormatters_Golden(t *testing.T) {
+	golden := map[string]string{
+		"json":     "report.json.golden",
+		"jsonl":    "report.jsonl.golden",
+		"xml":      "report.xml.golden",
+		"markdown": "report.md.golden",
+		"html":     "report.html.golden",
+		"text":     "report.txt.golden",
+		"csv":      "report.csv.golden",
+	}
+
+	for format, file := range golden {
+		opts, err := reportoptsfromargs([]string{"header=true"}, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := goldenreport(t).apply(opts).render(opts)
+		if err != nil {
+			t.Errorf("format %s: %s", format, err)
+			continue
+		}
+
+		path := filepath.Join("testdata", file)
+		if *update {
+			if err := ioutil.WriteFile(path, out, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expected, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("format %s differs from %s:\n%s", format, path, out)
+		}
+	}
+}
+
+func TestFormatters_Invoke(t *testing.T) {
+	stub := reportstub(t)
+
+	for _, fn := range []string{"voteresult", "voteresultcsv"} {
+		for format := range formatters {
+			res := stub.MockInvoke("1", argsbytes(fn, "VoteHash", "format="+format))
+			if res.Status != shim.OK || len(res.Payload) == 0 {
+				t.Errorf("%s format=%s failed: %s", fn, format, res.Message)
+			}
+		}
+	}
+
+	res := stub.MockInvoke("1", argsbytes("voteresult", "VoteHash", "format=pdf"))
+	if res.Status == shim.OK {
+		t.Errorf("unknown format is accepted")
+	}
+}
@@ -0,0 +1,313 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	id "github.com/s7techlab/cckit/identity"
+)
+
+// prefix of keys of voter groups, reserved like config key
+const groupprefix = "|group|"
+
+// prefix of keys of last versions of deleted groups, group created again continues from it
+const groupversionprefix = "|groupversion|"
+
+// struct for named group of voters, key is groupprefix + name
+type votergroup struct {
+	Name        string   `json:"name"`
+	Version     int      `json:"version"` // 1 on create, every update and create after delete increments it
+	Members     []string `json:"members"`
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner"` // creator, with admin orgs of config it updates and deletes group
+	OwnerOrg    string   `json:"ownerorg"`
+	CreatedAt   string   `json:"createdat"`
+	UpdatedBy   string   `json:"updatedby,omitempty"`
+	UpdatedAt   string   `json:"updatedat,omitempty"`
+}
+
+// struct for group of ballot - version of group which members were added to voters by votestart
+type groupref struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// check name of group, it is a part of key
+func checkgroupname(name string) error {
+	if name == "" || strings.Contains(name, "|") {
+		return fmt.Errorf("group name %q is empty or has |", name)
+	}
+	return nil
+}
+
+// group of ledger by name
+func getgroup(stub shim.ChaincodeStubInterface, name string) (*votergroup, error) {
+	key := groupprefix + name
+	value, err := stub.GetState(key)
+	if err != nil {
+		return nil, errledger(key, err)
+	}
+	if value == nil {
+		return nil, errgroupnotfound(name)
+	}
+	g := &votergroup{}
+	if err := json.Unmarshal(value, g); err != nil {
+		return nil, errinternal(fmt.Errorf("can't parse group %s: %s", name, err))
+	}
+	return g, nil
+}
+
+// write group in ledger
+func putgroup(stub shim.ChaincodeStubInterface, g *votergroup) pb.Response {
+	key := groupprefix + g.Name
+	value, _ := json.Marshal(g)
+	if err := stub.PutState(key, value); err != nil {
+		return errledger(key, err).response()
+	}
+	return shim.Success(value)
+}
+
+// set members and description of group by args of groupcreate \ groupupdate: voters replace members,
+// add=voter and remove=voter change them, description=text
+func (g *votergroup) setargs(args []string) error {
+	voters := []string{}
+	for _, arg := range args {
+		if !strings.Contains(arg, "=") {
+			voters = append(voters, arg)
+		}
+	}
+	if len(voters) > 0 {
+		g.Members = voters
+	}
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch key, value := strings.ToLower(kv[0]), kv[1]; key {
+		case "description":
+			g.Description = value
+		case "add":
+			if !g.hasmember(value) {
+				g.Members = append(g.Members, value)
+			}
+		case "remove":
+			if !g.hasmember(value) {
+				return fmt.Errorf("%s is not member of group %s", value, g.Name)
+			}
+			members := []string{}
+			for _, m := range g.Members {
+				if normalizevoter(m) != normalizevoter(value) {
+					members = append(members, m)
+				}
+			}
+			g.Members = members
+		default:
+			return fmt.Errorf("unknown option %q", kv[0])
+		}
+	}
+
+	if len(g.Members) == 0 {
+		return fmt.Errorf("group %s has no members", g.Name)
+	}
+	return checkuniqvoters(g.Members)
+}
+
+// voter is member of group, names are compared as normalized
+func (g *votergroup) hasmember(voter string) bool {
+	for _, m := range g.Members {
+		if normalizevoter(m) == normalizevoter(voter) {
+			return true
+		}
+	}
+	return false
+}
+
+// check that invoker owns group or is member of admin org, returns name of invoker
+func (g *votergroup) checkowner(stub shim.ChaincodeStubInterface, action string) (string, error) {
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return "", errbadidentity(err)
+	}
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return "", err
+	}
+	invoker, err := invokername(stub, certident)
+	if err != nil {
+		return "", err
+	}
+	if invoker != g.Owner && !cfg.admin(certident.GetMSPID()) {
+		return "", errnotowner(g.Name, invoker, action)
+	}
+	return invoker, nil
+}
+
+// members of groups of ballot, at their current versions, come before voters of args; voters of args
+// which are members already are added once. Versions of groups are kept in ballot
+func resolvegroups(stub shim.ChaincodeStubInterface, v *votelist) error {
+	if len(v.Groups) == 0 {
+		return nil
+	}
+
+	voters, members := []string{}, map[string]bool{}
+	for i, ref := range v.Groups {
+		g, err := getgroup(stub, ref.Name)
+		if err != nil {
+			return err
+		}
+		v.Groups[i].Version = g.Version
+		for _, m := range g.Members {
+			if !members[m] {
+				members[m] = true
+				voters = append(voters, m)
+			}
+		}
+	}
+	for _, voter := range v.Voters {
+		if !members[voter] {
+			voters = append(voters, voter)
+		}
+	}
+	v.Voters = voters
+
+	if err := v.checkorgs(); err != nil {
+		return errbadargs("votestart", err)
+	}
+	return nil
+}
+
+// create group of voters of args, invoker owns it
+func (t *SimpleChaincode) groupcreate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	name := args[0]
+	if err := checkgroupname(name); err != nil {
+		return errbadargs("groupcreate", err).response()
+	}
+
+	existing, err := stub.GetState(groupprefix + name)
+	if err != nil {
+		return errledger(groupprefix+name, err).response()
+	}
+	if existing != nil {
+		return errgroupexists(name).response()
+	}
+
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err).response()
+	}
+	owner, err := invokername(stub, certident)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	// group of deleted name continues its versions, ballots keep refs to them
+	last := groupref{}
+	value, err := stub.GetState(groupversionprefix + name)
+	if err != nil {
+		return errledger(groupversionprefix+name, err).response()
+	}
+	if value != nil {
+		if err := json.Unmarshal(value, &last); err != nil {
+			return errinternal(fmt.Errorf("can't parse last version of group %s: %s", name, err)).response()
+		}
+		if err := stub.DelState(groupversionprefix + name); err != nil {
+			return errledger(groupversionprefix+name, err).response()
+		}
+	}
+
+	g := &votergroup{Name: name, Version: last.Version + 1, Owner: owner, OwnerOrg: certident.GetMSPID(), CreatedAt: txtime(stub)}
+	if err := g.setargs(args[1:]); err != nil {
+		return errbadargs("groupcreate", err).response()
+	}
+	return putgroup(stub, g)
+}
+
+// change members or description of group, next version of group. Ballots keep voters of versions they started with
+func (t *SimpleChaincode) groupupdate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	if len(args) < 2 {
+		return errbadargs("groupupdate", fmt.Errorf("no changes of group %s", args[0])).response()
+	}
+
+	g, err := getgroup(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+	invoker, err := g.checkowner(stub, "update")
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	if err := g.setargs(args[1:]); err != nil {
+		return errbadargs("groupupdate", err).response()
+	}
+	g.Version++
+	g.UpdatedBy, g.UpdatedAt = invoker, txtime(stub)
+	return putgroup(stub, g)
+}
+
+// delete group from state, history of ledger keeps its versions. Last version is kept for group created again
+func (t *SimpleChaincode) groupdelete(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	g, err := getgroup(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+	if _, err := g.checkowner(stub, "delete"); err != nil {
+		return errorresponse(err)
+	}
+
+	last, _ := json.Marshal(groupref{Name: g.Name, Version: g.Version})
+	if err := stub.PutState(groupversionprefix+g.Name, last); err != nil {
+		return errledger(groupversionprefix+g.Name, err).response()
+	}
+	if err := stub.DelState(groupprefix + g.Name); err != nil {
+		return errledger(groupprefix+g.Name, err).response()
+	}
+	return shim.Success(nil)
+}
+
+// group with members and version
+func (t *SimpleChaincode) groupinfo(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	g, err := getgroup(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, _ := json.Marshal(g)
+	return shim.Success(banswer)
+}
+
+// groups in order of names
+func (t *SimpleChaincode) grouplist(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	iter, err := stub.GetStateByRange(groupprefix, groupprefix+string(utf8.MaxRune))
+	if err != nil {
+		return errledger(groupprefix, err).response()
+	}
+	defer iter.Close()
+
+	groups := []votergroup{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return errledger(groupprefix, err).response()
+		}
+		var g votergroup
+		if err := json.Unmarshal(kv.Value, &g); err != nil {
+			return errinternal(fmt.Errorf("can't parse group %s: %s", kv.Key, err)).response()
+		}
+		groups = append(groups, g)
+	}
+
+	banswer, _ := json.Marshal(groups)
+	return shim.Success(banswer)
+}
@@ -0,0 +1,97 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestVoterGroups(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("groupcreate", "board", voter1, voter2, "description=board of directors")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("groupcreate", "board", voter3)); res.Status != statusconflict || !strings.HasPrefix(res.Message, codegroupexists) {
+		t.Errorf("create of existing group: %d %s", res.Status, res.Message)
+	}
+	for _, args := range [][]string{{"groupcreate", "team|1", voter1}, {"groupcreate", "empty", "description=none"}, {"groupupdate", "board"}} {
+		if res := stub.MockInvoke("1", argsbytes(args...)); res.Status != statusbadrequest {
+			t.Errorf("%v: %d %s", args, res.Status, res.Message)
+		}
+	}
+
+	// ballot snapshots members of group, voters of args are added once
+	startvoting(t, stub, "VoteHash", "group=board", voter2, voter3, "org=Org2MSP:"+voter2)
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if strings.Join(b.Voters, ",") != strings.Join([]string{voter1, voter2, voter3}, ",") || len(b.Groups) != 1 || b.Groups[0].Version != 1 {
+		t.Fatalf("voters of group %v, groups %+v", b.Voters, b.Groups)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote2", "https://git.repo", "10.04.2099.10.00", "group=council")); !strings.HasPrefix(res.Message, codegroupnotfound) {
+		t.Errorf("ballot of unknown group: %d %s", res.Status, res.Message)
+	}
+
+	// only owner updates group, open ballot keeps its voters
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("groupupdate", "board", "add="+voter3)); !strings.HasPrefix(res.Message, codenotowner) {
+		t.Errorf("update by not owner: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("groupupdate", "board", "add="+strings.ToUpper(voter1), "add="+voter3, "remove="+strings.ToUpper(voter1))); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var g votergroup
+	query(t, stub, &g, "groupinfo", "board")
+	if g.Version != 2 || strings.Join(g.Members, ",") != voter2+","+voter3 || g.UpdatedBy != voter1 || g.Description != "board of directors" {
+		t.Errorf("updated group %+v", g)
+	}
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if len(b.Voters) != 3 || b.Groups[0].Version != 1 {
+		t.Errorf("voters of open ballot after update %v", b.Voters)
+	}
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+
+	startvoting(t, stub, "Vote2", "group=board")
+	query(t, stub, &b, "voteinfo", "Vote2")
+	if len(b.Voters) != 2 || b.Groups[0].Version != 2 {
+		t.Errorf("ballot of updated group %v %+v", b.Voters, b.Groups)
+	}
+
+	var groups []votergroup
+	query(t, stub, &groups, "grouplist")
+	if len(groups) != 1 {
+		t.Errorf("groups %+v", groups)
+	}
+	if res := stub.MockInvoke("1", argsbytes("groupdelete", "board")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("groupinfo", "board")); res.Status != statusnotfound {
+		t.Errorf("deleted group: %d %s", res.Status, res.Message)
+	}
+
+	// group created again continues versions, ref of ballot to version 2 is not reused
+	if res := stub.MockInvoke("1", argsbytes("groupcreate", "board", voter1)); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	query(t, stub, &g, "groupinfo", "board")
+	if g.Version != 3 || strings.Join(g.Members, ",") != voter1 {
+		t.Errorf("group created after delete %+v", g)
+	}
+	query(t, stub, &groups, "grouplist")
+	if len(groups) != 1 {
+		t.Errorf("groups after create %+v", groups)
+	}
+
+	// groups are not ballots
+	var ballots []ballotview
+	query(t, stub, &ballots, "votelist")
+	if len(ballots) != 2 {
+		t.Errorf("ballots %d", len(ballots))
+	}
+}
@@ -0,0 +1,213 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/tally"
+	id "github.com/s7techlab/cckit/identity"
+)
+
+// states of ballot
+const (
+	statedraft     = "draft"     // defined, votestart of proposer opens it
+	stateopen      = "open"      // voters vote
+	stateclosed    = "closed"    // voteend, result is final
+	statecancelled = "cancelled" // votecancel of draft or open ballot, no result
+	statearchived  = "archived"  // votearchive of closed or cancelled ballot
+)
+
+// status of open ballot before its start date, it is not state of ballot
+const statusscheduled = "scheduled"
+
+// allowed transitions by state, votestart of new ballot starts it as draft or open
+var transitions = map[string][]string{
+	statedraft:     {stateopen, statecancelled},
+	stateopen:      {stateclosed, statecancelled},
+	stateclosed:    {statearchived},
+	statecancelled: {statearchived},
+}
+
+// struct for transition of ballot in log of ballot
+type transition struct {
+	From   string `json:"from,omitempty"`
+	To     string `json:"to"`
+	By     string `json:"by"`            // invoker
+	Org    string `json:"org,omitempty"` // MSP ID of invoker
+	At     string `json:"at"`            // tx time, RFC3339
+	Reason string `json:"reason,omitempty"`
+}
+
+// state of ballot, ballots before states are open or closed
+func (v *votelist) state() string {
+	switch {
+	case v.State != "":
+		return v.State
+	case v.Closed:
+		return stateclosed
+	}
+	return stateopen
+}
+
+// status of ballot at tx time: state, scheduled for open ballot before its start date
+func (v *votelist) status(stub shim.ChaincodeStubInterface) string {
+	state := v.state()
+	if state == stateopen && v.scheduled(txtime(stub)) {
+		return statusscheduled
+	}
+	return state
+}
+
+// ballot has start date after at (RFC3339), false if at is unknown
+func (v *votelist) scheduled(at string) bool {
+	opening, ok := tally.Opening(v.StartDate)
+	now, err := time.Parse(time.RFC3339Nano, at)
+	return ok && err == nil && now.Before(opening)
+}
+
+// end date of ballot is not after at (RFC3339), false if at or end date is unknown. Extensions move end date
+func (v *votelist) expired(at string) bool {
+	deadline, ok := tally.Deadline(v.EndDate)
+	now, err := time.Parse(time.RFC3339Nano, at)
+	return ok && err == nil && !now.Before(deadline)
+}
+
+// ballot was cancelled, even if it is archived after it
+func (v *votelist) cancelled() bool {
+	for _, tr := range v.Transitions {
+		if tr.To == statecancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// start new ballot in state, draft or open
+func (v *votelist) begin(state string, tr transition) {
+	tr.To = state
+	v.State = state
+	v.Transitions = []transition{tr}
+}
+
+// move ballot to state and log transition, false if transition is not allowed
+func (v *votelist) transit(to string, tr transition) bool {
+	from := v.state()
+	allowed := false
+	for _, state := range transitions[from] {
+		allowed = allowed || state == to
+	}
+	if !allowed {
+		return false
+	}
+
+	tr.From, tr.To = from, to
+	v.State = to
+	v.Transitions = append(v.Transitions, tr)
+	if to == stateclosed || to == statecancelled {
+		v.Closed = true // no more votes
+	}
+	return true
+}
+
+// check that ballot takes votes, BALLOT_CLOSED for closed ballots as before states
+func (v *votelist) checkopen(voteid string, action string) *ccerror {
+	switch state := v.state(); state {
+	case stateopen:
+		return nil
+	case stateclosed:
+		return errballotclosed(voteid)
+	default:
+		return errinvalidstate(voteid, state, action)
+	}
+}
+
+// transition by invoker at tx time, invoker is nil if identity is unknown
+func newtransition(stub shim.ChaincodeStubInterface, invoker *id.CertIdentity, reason string) (transition, error) {
+	tr := transition{At: txtime(stub), Reason: reason}
+	if invoker != nil {
+		name, err := invokername(stub, invoker)
+		if err != nil {
+			return tr, err
+		}
+		tr.By, tr.Org = name, invoker.GetMSPID()
+	}
+	return tr, nil
+}
+
+// move ballot to state by proposer, ballot is not written
+func proposertransit(stub shim.ChaincodeStubInterface, voteid string, votestruct *votelist, to string, action string, reason string) error {
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err)
+	}
+	tr, err := newtransition(stub, certident, reason)
+	if err != nil {
+		return err
+	}
+	if tr.By != votestruct.Proposer {
+		return errnotproposer(voteid, tr.By, action)
+	}
+	if !votestruct.transit(to, tr) {
+		return errinvalidstate(voteid, votestruct.state(), action)
+	}
+	return nil
+}
+
+// write ballot moved to state and set event of transition
+func putballot(stub shim.ChaincodeStubInterface, voteid string, votestruct *votelist, event string) pb.Response {
+	value, _ := json.Marshal(votestruct)
+	if err := stub.PutState(voteid, value); err != nil {
+		return errledger(voteid, err).response()
+	}
+	if err := setevent(stub, event, ccevent{VoteID: voteid, Ballot: votestruct}); err != nil {
+		return errorresponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// cancel draft or open ballot with reason, only proposer can
+func (t *SimpleChaincode) votecancel(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, err := getballot(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := proposertransit(stub, voteid, votestruct, statecancelled, "cancel", args[1]); err != nil {
+		return errorresponse(err)
+	}
+	return putballot(stub, voteid, votestruct, eventballotcancelled)
+}
+
+// archive closed or cancelled ballot, only proposer can. Ballot keeps compact record of its votes,
+// vote keys and result are deleted from state
+func (t *SimpleChaincode) votearchive(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+	reason := ""
+	if len(args) > 1 {
+		reason = args[1]
+	}
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if err := proposertransit(stub, voteid, votestruct, statearchived, "archive", reason); err != nil {
+		return errorresponse(err)
+	}
+
+	archive, err := newarchive(voteid, votearr)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if archive.Pruned, err = prunevotes(stub, voteid, votestruct); err != nil {
+		return errorresponse(err)
+	}
+	votestruct.Archive = archive
+
+	return putballot(stub, voteid, votestruct, eventballotarchived)
+}
@@ -0,0 +1,206 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestLifecycle_Draft(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, "draft=true", "reason=first reading")
+	if ev := lastevent(t, stub, eventballotstarted); ev.Ballot.State != statedraft {
+		t.Errorf("ballotstarted of draft %+v", ev.Ballot)
+	}
+
+	// draft takes no votes and is not closed
+	res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes", ""))
+	if res.Status != statusconflict || !strings.HasPrefix(res.Message, codeinvalidstate) {
+		t.Errorf("vote in draft: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != statusconflict {
+		t.Errorf("voteend of draft: %d %s", res.Status, res.Message)
+	}
+
+	// only proposer opens draft, draft can't be restarted as draft
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, voter2)); !strings.HasPrefix(res.Message, codenotproposer) {
+		t.Errorf("open by not proposer: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, "draft=true")); res.Status != statusconflict {
+		t.Errorf("restart of draft as draft: %d %s", res.Status, res.Message)
+	}
+	startvoting(t, stub, "VoteHash", voter1, voter2, "title=Release", "reason=second reading")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+
+	// open ballot is not started again
+	if res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1)); res.Status != statusconflict {
+		t.Errorf("restart of open ballot: %d %s", res.Status, res.Message)
+	}
+
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash", "end of term")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votecancel", "VoteHash", "too late")); res.Status != statusconflict {
+		t.Errorf("cancel of closed: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votearchive", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if ev := lastevent(t, stub, eventballotarchived); ev.Ballot.State != statearchived {
+		t.Errorf("ballotarchived %+v", ev.Ballot)
+	}
+
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	states := []string{}
+	for _, tr := range b.Transitions {
+		if tr.By != voter1 || tr.Org != "Org1MSP" || tr.At == "" {
+			t.Errorf("transition by %+v", tr)
+		}
+		states = append(states, tr.From+">"+tr.To+":"+tr.Reason)
+	}
+	expected := []string{">draft:first reading", "draft>open:second reading", "open>closed:end of term", "closed>archived:"}
+	if len(states) != len(expected) {
+		t.Fatalf("transitions %v", states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Errorf("transition %d: %s, expected %s", i, states[i], expected[i])
+		}
+	}
+	if b.State != statearchived || !b.Closed || b.Title != "Release" {
+		t.Errorf("archived ballot %+v", b.votelist)
+	}
+
+	rep := queryreport(t, stub)
+	if rep.State != statearchived || len(rep.Transitions) != 4 || rep.Decision != "yes" {
+		t.Errorf("report of archived %+v", rep)
+	}
+}
+
+func TestLifecycle_Cancel(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2)
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+
+	if res := stub.MockInvoke("1", argsbytes("votecancel", "VoteHash", "wrong repo")); !strings.HasPrefix(res.Message, codenotproposer) {
+		t.Errorf("cancel by not proposer: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votecancel", "VoteHash")); res.Status != statusbadrequest {
+		t.Errorf("cancel without reason: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votearchive", "VoteHash")); res.Status != statusconflict {
+		t.Errorf("archive of open: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votecancel", "VoteHash", "wrong repo")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if ev := lastevent(t, stub, eventballotcancelled); ev.Ballot.State != statecancelled || ev.Ballot.Transitions[1].Reason != "wrong repo" {
+		t.Errorf("ballotcancelled %+v", ev.Ballot)
+	}
+
+	// cancelled ballot takes no votes and has no decision in analytics
+	if res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes", "")); res.Status != statusconflict {
+		t.Errorf("vote in cancelled: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != statusconflict {
+		t.Errorf("withdraw in cancelled: %d %s", res.Status, res.Message)
+	}
+	var decision struct{ Rows []decisionrow }
+	query(t, stub, &decision, "voteanalytics", "decision")
+	if len(decision.Rows) != 0 {
+		t.Errorf("decision of cancelled ballot %+v", decision.Rows)
+	}
+
+	var st votestatus
+	query(t, stub, &st, "votestatus", "VoteHash")
+	if st.Status != statecancelled {
+		t.Errorf("status of cancelled %+v", st)
+	}
+}
+
+func TestLifecycle_Scheduled(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1 := certvoter(t, stubsert1)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote1", "https://git.repo", "01.01.2100", voter1, "start=02.01.2100")); res.Status != statusbadrequest {
+		t.Errorf("start after end date: %d %s", res.Status, res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote1", "https://git.repo", "01.01.2100", voter1, "start=tomorrow")); res.Status != statusbadrequest {
+		t.Errorf("start is not date: %d %s", res.Status, res.Message)
+	}
+
+	// vote opens on start date, tx time of MockStub is now
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote1", "https://git.repo", "02.01.2100", voter1, "start=01.01.2100")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	res := stub.MockInvoke("1", argsbytes("vote", "Vote1", "yes", ""))
+	if res.Status != statusconflict || !strings.Contains(res.Message, statusscheduled) {
+		t.Errorf("vote before start: %d %s", res.Status, res.Message)
+	}
+	var st votestatus
+	query(t, stub, &st, "votestatus", "Vote1")
+	if st.Status != statusscheduled || st.StartDate != "01.01.2100" {
+		t.Errorf("status before start %+v", st)
+	}
+
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote2", "https://git.repo", "01.01.2100", voter1, "start=01.01.2020.10.00")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	castvote(t, stub, "Org1MSP", stubsert1, "Vote2", "yes", "")
+	query(t, stub, &st, "votestatus", "Vote2")
+	if st.Status != stateopen {
+		t.Errorf("status after start %+v", st)
+	}
+}
+
+func TestLifecycle_Proposer(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	// proposer option names invoker only - ballot of proposer nobody controls can't be closed
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	for _, proposer := range []string{voter2, "nobody"} {
+		res := stub.MockInvoke("1", argsbytes("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter1, "proposer="+proposer))
+		if res.Status != statusbadrequest || !strings.HasPrefix(res.Message, codebadargs) {
+			t.Errorf("proposer %s of ballot of %s: %d %s", proposer, voter1, res.Status, res.Message)
+		}
+	}
+	startvoting(t, stub, "VoteHash", voter1, voter2, "proposer="+strings.ToUpper(voter1))
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if b.Proposer != voter1 {
+		t.Errorf("proposer %s, expected %s", b.Proposer, voter1)
+	}
+
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); !strings.HasPrefix(res.Message, codenotproposer) {
+		t.Errorf("close by not proposer: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Errorf("close by proposer: %s", res.Message)
+	}
+
+	stub.MockCreator("", nil)
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Anonymous", "https://git.repo", "10.04.2099.10.00", voter1, "proposer="+voter1)); res.Status != statusbadrequest {
+		t.Errorf("proposer of unknown invoker: %d %s", res.Status, res.Message)
+	}
+}
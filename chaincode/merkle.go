@@ -0,0 +1,111 @@
+package chaincode
+
+import (
+	"encoding/hex"
+
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// Merkle tree over counted votes, primitives are in tally package
+
+// struct for certificate of result - every counted vote is under the root
+type votecertificate struct {
+	Algorithm  string   `json:"algorithm"`
+	MerkleRoot string   `json:"merkleroot"` // hex of root hash
+	Leaves     int      `json:"leaves"`     // counted votes
+	TxIDs      []string `json:"txids"`      // tx of vote writes, in order of leaves
+}
+
+// struct for inclusion proof of one vote
+type voteproof struct {
+	VoteID     string   `json:"voteid"`
+	Voter      string   `json:"voter"`
+	TxID       string   `json:"txid"`
+	Canonical  string   `json:"canonical"`  // canonical encoding of vote - leaf data
+	LeafHash   string   `json:"leafhash"`   // hex
+	Index      int      `json:"index"`      // position of leaf
+	Leaves     int      `json:"leaves"`     // size of tree
+	Path       []string `json:"path"`       // hex hashes from leaf to root
+	MerkleRoot string   `json:"merkleroot"` // hex
+}
+
+// canonical encoding of counted vote, leaf data of tree
+func canonicalvote(voteid string, v voterslist) []byte {
+	return tally.Canonical(voteid, v.Voter, v.Org, v.Vote, v.Comment, v.TxID, v.Timestamp)
+}
+
+// leaf hashes of counted votes, in order of report entries
+func voteleaves(voteid string, votearr []voterslist) [][]byte {
+	leaves := [][]byte{}
+	for _, v := range votearr {
+		leaves = append(leaves, tally.Leaf(canonicalvote(voteid, v)))
+	}
+	return leaves
+}
+
+// certificate over all counted votes of vote
+func certifyvotes(voteid string, votearr []voterslist) votecertificate {
+	cert := votecertificate{
+		Algorithm:  tally.Algorithm,
+		MerkleRoot: hex.EncodeToString(tally.Root(voteleaves(voteid, votearr))),
+		Leaves:     len(votearr),
+		TxIDs:      []string{},
+	}
+	for _, v := range votearr {
+		cert.TxIDs = append(cert.TxIDs, v.TxID)
+	}
+	return cert
+}
+
+// inclusion proof of vote of voter, nil if voter has no counted vote
+func provevote(voteid string, votearr []voterslist, voter string) *voteproof {
+	leaves := voteleaves(voteid, votearr)
+
+	for i, v := range votearr {
+		if v.Voter != voter {
+			continue
+		}
+
+		proof := &voteproof{
+			VoteID:     voteid,
+			Voter:      voter,
+			TxID:       v.TxID,
+			Canonical:  string(canonicalvote(voteid, v)),
+			LeafHash:   hex.EncodeToString(leaves[i]),
+			Index:      i,
+			Leaves:     len(leaves),
+			Path:       []string{},
+			MerkleRoot: hex.EncodeToString(tally.Root(leaves)),
+		}
+		for _, p := range tally.Path(leaves, i) {
+			proof.Path = append(proof.Path, hex.EncodeToString(p))
+		}
+		return proof
+	}
+
+	return nil
+}
+
+// check proof offline: leaf is hash of canonical vote and path leads to root
+func (p voteproof) verify() bool {
+	leaf := tally.Leaf([]byte(p.Canonical))
+	if hex.EncodeToString(leaf) != p.LeafHash {
+		return false
+	}
+
+	root, err := hex.DecodeString(p.MerkleRoot)
+	if err != nil {
+		return false
+	}
+
+	path := [][]byte{}
+	for _, h := range p.Path {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return false
+		}
+		path = append(path, b)
+	}
+
+	return tally.Verify(leaf, p.Index, p.Leaves, path, root)
+}
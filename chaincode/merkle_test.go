@@ -0,0 +1,46 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestVoteProof(t *testing.T) {
+	stub := reportstub(t)
+	rep := queryreport(t, stub)
+
+	if rep.Certificate.Leaves != 2 || len(rep.Certificate.TxIDs) != 2 || rep.Certificate.MerkleRoot == "" {
+		t.Fatalf("no certificate in report: %+v", rep.Certificate)
+	}
+
+	// proof of invoker's vote
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	res := stub.MockInvoke("1", argsbytes("voteproof", "VoteHash"))
+	if res.Status != shim.OK {
+		t.Fatalf("voteproof failed: %s", res.Message)
+	}
+
+	var proof voteproof
+	if err := json.Unmarshal(res.Payload, &proof); err != nil {
+		t.Fatal(err)
+	}
+	if proof.Voter != certvoter(t, stubsert2) || proof.MerkleRoot != rep.Certificate.MerkleRoot || proof.TxID != rep.Certificate.TxIDs[proof.Index] {
+		t.Errorf("proof does not match report: %+v", proof)
+	}
+	if !proof.verify() {
+		t.Errorf("proof is not verified: %+v", proof)
+	}
+
+	proof.Canonical = string(canonicalvote("VoteHash", voterslist{Voter: proof.Voter, Vote: "no"}))
+	if proof.verify() {
+		t.Errorf("proof of changed vote is verified")
+	}
+
+	// voter without vote
+	res = stub.MockInvoke("1", argsbytes("voteproof", "VoteHash", certvoter(t, stubsert3)))
+	if res.Status == shim.OK {
+		t.Errorf("proof for voter without vote")
+	}
+}
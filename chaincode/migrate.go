@@ -0,0 +1,126 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// struct for migration of state to schema version, it returns number of rewritten keys
+type migration struct {
+	version int
+	name    string
+	apply   func(stub shim.ChaincodeStubInterface) (int, error)
+}
+
+// registry of migrations, in order of versions
+var migrations = []migration{}
+
+func init() {
+	registermigration(1, "jsonrecords", migratejsonrecords)
+	registermigration(2, "ballotstates", migrateballotstates)
+}
+
+// add migration to registry, versions follow one by one
+func registermigration(version int, name string, apply func(shim.ChaincodeStubInterface) (int, error)) {
+	if version != len(migrations)+1 {
+		panic("migration " + name + " is out of order of versions")
+	}
+	migrations = append(migrations, migration{version: version, name: name, apply: apply})
+}
+
+// apply migrations of versions after version of config, in order, and log them in config
+func migrate(stub shim.ChaincodeStubInterface, cfg *ccconfig) error {
+	for _, m := range migrations {
+		if m.version <= cfg.Version {
+			continue
+		}
+		records, err := m.apply(stub)
+		if err != nil {
+			return err
+		}
+		cfg.Version = m.version
+		cfg.Migrations = append(cfg.Migrations, appliedmigration{Version: m.version, Name: m.name, Records: records, At: txtime(stub)})
+	}
+	return nil
+}
+
+// all keys of state but reserved ones, keys are read before migration writes any
+func statekeys(stub shim.ChaincodeStubInterface) ([]string, error) {
+	iter, err := stub.GetStateByRange("", string(utf8.MaxRune))
+	if err != nil {
+		return nil, errledger("", err)
+	}
+	defer iter.Close()
+
+	keys := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, errledger("", err)
+		}
+		if !strings.HasPrefix(kv.Key, "|") {
+			keys = append(keys, kv.Key)
+		}
+	}
+	return keys, nil
+}
+
+// rewrite keys of state by rewrite, which returns nil for values it keeps
+func rewritestate(stub shim.ChaincodeStubInterface, rewrite func(key string, value []byte) []byte) (int, error) {
+	keys, err := statekeys(stub)
+	if err != nil {
+		return 0, err
+	}
+
+	records := 0
+	for _, key := range keys {
+		value, err := stub.GetState(key)
+		if err != nil {
+			return records, errledger(key, err)
+		}
+		next := rewrite(key, value)
+		if next == nil {
+			continue
+		}
+		if err := stub.PutState(key, next); err != nil {
+			return records, errledger(key, err)
+		}
+		records++
+	}
+	return records, nil
+}
+
+// ballots and votes written before json as {repourl enddate [voters]} and {voter vote comment} are rewritten as json
+func migratejsonrecords(stub shim.ChaincodeStubInterface) (int, error) {
+	return rewritestate(stub, func(key string, value []byte) []byte {
+		if json.Valid(value) || strings.HasSuffix(key, "|result") {
+			return nil
+		}
+		var next []byte
+		if strings.Contains(key, "|") {
+			next, _ = json.Marshal(bytesToVotersList(value))
+		} else {
+			next, _ = json.Marshal(bytesToVoteList(value))
+		}
+		return next
+	})
+}
+
+// ballots written before states get state of their closed flag
+func migrateballotstates(stub shim.ChaincodeStubInterface) (int, error) {
+	return rewritestate(stub, func(key string, value []byte) []byte {
+		if strings.Contains(key, "|") {
+			return nil
+		}
+		votestruct := bytesToVoteList(value)
+		if votestruct.State != "" {
+			return nil
+		}
+		votestruct.State = votestruct.state()
+		next, _ := json.Marshal(votestruct)
+		return next
+	})
+}
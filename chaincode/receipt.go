@@ -0,0 +1,116 @@
+package chaincode
+
+import (
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// statuses of verifyreceipt
+const (
+	constreceiptcurrent     = "current"     // vote in state is the vote of receipt
+	constreceiptoverwritten = "overwritten" // vote of receipt is in history, but was changed later
+	constreceiptmismatch    = "mismatch"    // tx of receipt wrote other vote than in receipt
+	constreceiptnotfound    = "notfound"    // no tx of receipt for vote key
+	constreceiptarchived    = "archived"    // vote of receipt is counted in record of archived ballot
+)
+
+// struct for receipt of vote, returned by vote
+type votereceipt struct {
+	VoteID    string `json:"voteid"`
+	Voter     string `json:"voter"`
+	TxID      string `json:"txid"`
+	Timestamp string `json:"timestamp"`
+	VoteHash  string `json:"votehash"` // hex of Merkle leaf hash of canonical vote
+}
+
+// struct for answer of verifyreceipt
+type receiptcheck struct {
+	Valid   bool         `json:"valid"`   // receipt is the vote of its tx
+	Status  string       `json:"status"`  // current \ overwritten \ mismatch \ notfound
+	Current *votereceipt `json:"current"` // receipt of vote in state, nil if no vote
+}
+
+// receipt of vote as it is written in ledger
+func receiptofvote(voteid string, v voterslist) votereceipt {
+	return votereceipt{
+		VoteID:    voteid,
+		Voter:     v.Voter,
+		TxID:      v.TxID,
+		Timestamp: v.Timestamp,
+		VoteHash:  hex.EncodeToString(tally.Leaf(canonicalvote(voteid, v))),
+	}
+}
+
+// check receipt against vote in state, then against history of vote key
+func checkreceipt(stub shim.ChaincodeStubInterface, receipt votereceipt) (*receiptcheck, error) {
+	votekey := receipt.VoteID + "|" + receipt.Voter
+
+	check := &receiptcheck{Status: constreceiptnotfound}
+
+	val, err := stub.GetState(votekey)
+	if err != nil {
+		return nil, errledger(votekey, err)
+	}
+	if val != nil {
+		v := bytesToVotersList(val)
+		v.Voter = receipt.Voter
+		current := receiptofvote(receipt.VoteID, v)
+		check.Current = &current
+
+		if current.TxID == receipt.TxID {
+			check.Valid = current == receipt
+			check.Status = constreceiptcurrent
+			if !check.Valid {
+				check.Status = constreceiptmismatch
+			}
+			return check, nil
+		}
+	}
+
+	history, err := stub.GetHistoryForKey(votekey)
+	if err != nil {
+		return nil, errledger(votekey, err)
+	}
+	defer history.Close()
+
+	for history.HasNext() {
+		mod, err := history.Next()
+		if err != nil {
+			return nil, errledger(votekey, err)
+		}
+		if mod.TxId != receipt.TxID || mod.IsDelete {
+			continue
+		}
+
+		v := bytesToVotersList(mod.Value)
+		v.Voter = receipt.Voter
+		check.Valid = receiptofvote(receipt.VoteID, v) == receipt
+		check.Status = constreceiptoverwritten
+		if !check.Valid {
+			check.Status = constreceiptmismatch
+		}
+		if check.Valid && val == nil { // vote key is deleted by archive of ballot
+			archived, err := archivedreceipt(stub, receipt)
+			if err != nil {
+				return nil, err
+			}
+			if archived {
+				check.Status = constreceiptarchived
+			}
+		}
+		return check, nil
+	}
+
+	return check, nil
+}
+
+// vote of receipt is counted in record of its archived ballot
+func archivedreceipt(stub shim.ChaincodeStubInterface, receipt votereceipt) (bool, error) {
+	votestruct, err := getballot(stub, receipt.VoteID)
+	if err != nil {
+		return false, err
+	}
+	return votestruct.Archive != nil && votestruct.Archive.vote(receipt.Voter, receipt.TxID) != nil, nil
+}
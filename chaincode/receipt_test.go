@@ -0,0 +1,141 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// MockStub with history of keys, mock of fabric has no GetHistoryForKey
+type historystub struct {
+	*cckit.MockStub
+	history map[string][]*queryresult.KeyModification
+	txnum   int
+}
+
+type historyiter struct {
+	mods []*queryresult.KeyModification
+}
+
+func newhistorystub() *historystub {
+	stub := &historystub{
+		MockStub: cckit.NewMockStub("crocc", new(SimpleChaincode)),
+		history:  map[string][]*queryresult.KeyModification{},
+	}
+	stub.ClearCreatorAfterInvoke = false
+	return stub
+}
+
+func (s *historystub) PutState(key string, value []byte) error {
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.TxID, Value: value})
+	return s.MockStub.PutState(key, value)
+}
+
+func (s *historystub) DelState(key string) error {
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.TxID, IsDelete: true})
+	return s.MockStub.DelState(key)
+}
+
+func (s *historystub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyiter{mods: s.history[key]}, nil
+}
+
+// invoke chaincode with this stub, so chaincode sees history
+func (s *historystub) invoke(args ...string) pb.Response {
+	s.txnum++
+	txid := fmt.Sprintf("tx%d", s.txnum)
+
+	s.SetArgs(argsbytes(args...))
+	s.MockTransactionStart(txid)
+	res := new(SimpleChaincode).Invoke(s)
+	s.MockTransactionEnd(txid)
+	return res
+}
+
+func (it *historyiter) HasNext() bool { return len(it.mods) > 0 }
+
+func (it *historyiter) Close() error { return nil }
+
+func (it *historyiter) Next() (*queryresult.KeyModification, error) {
+	mod := it.mods[0]
+	it.mods = it.mods[1:]
+	return mod, nil
+}
+
+func checkreceiptstatus(t *testing.T, stub *historystub, receipt votereceipt, status string, valid bool) {
+	breceipt, _ := json.Marshal(receipt)
+	res := stub.invoke("verifyreceipt", string(breceipt))
+	if res.Status != shim.OK {
+		t.Fatalf("verifyreceipt failed: %s", res.Message)
+	}
+
+	var check receiptcheck
+	if err := json.Unmarshal(res.Payload, &check); err != nil {
+		t.Fatal(err)
+	}
+	if check.Status != status || check.Valid != valid {
+		t.Errorf("receipt %+v: status %s valid %v, expected %s %v", receipt, check.Status, check.Valid, status, valid)
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	stub := newhistorystub()
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	voter := certvoter(t, stubsert1)
+
+	if res := stub.invoke("votestart", "VoteHash", "https://git.repo", "10.04.2099.10.00", voter); res.Status != shim.OK {
+		t.Fatalf("votestart failed: %s", res.Message)
+	}
+
+	res := stub.invoke("vote", "VoteHash", "Yes", "first thought")
+	if res.Status != shim.OK {
+		t.Fatalf("vote failed: %s", res.Message)
+	}
+
+	var receipt votereceipt
+	if err := json.Unmarshal(res.Payload, &receipt); err != nil {
+		t.Fatalf("vote returned no receipt: %s", res.Payload)
+	}
+	if receipt.VoteID != "VoteHash" || receipt.Voter != voter || receipt.TxID != "tx2" || receipt.Timestamp == "" || receipt.VoteHash == "" {
+		t.Errorf("wrong receipt: %+v", receipt)
+	}
+
+	checkreceiptstatus(t, stub, receipt, constreceiptcurrent, true)
+
+	forged := receipt
+	forged.VoteHash = receipt.VoteHash[:62] + "00"
+	checkreceiptstatus(t, stub, forged, constreceiptmismatch, false)
+
+	// vote of voter is overwritten by later vote
+	res = stub.invoke("vote", "VoteHash", "No", "second thought")
+	if res.Status != shim.OK {
+		t.Fatalf("vote failed: %s", res.Message)
+	}
+	var latest votereceipt
+	json.Unmarshal(res.Payload, &latest)
+
+	checkreceiptstatus(t, stub, receipt, constreceiptoverwritten, true)
+
+	unknown := receipt
+	unknown.TxID = "tx100"
+	checkreceiptstatus(t, stub, unknown, constreceiptnotfound, false)
+
+	// hash of receipt is the leaf of vote in certificate
+	res = stub.invoke("voteproof", "VoteHash")
+	var proof voteproof
+	if err := json.Unmarshal(res.Payload, &proof); err != nil {
+		t.Fatalf("voteproof failed: %s", res.Message)
+	}
+	if proof.LeafHash != latest.VoteHash || proof.TxID != latest.TxID {
+		t.Errorf("receipt %+v is not the leaf of proof %+v", latest, proof)
+	}
+
+	if res := stub.invoke("verifyreceipt", "not a receipt"); res.Status == shim.OK {
+		t.Errorf("broken receipt is accepted")
+	}
+}
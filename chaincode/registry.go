@@ -0,0 +1,327 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	id "github.com/s7techlab/cckit/identity"
+)
+
+// prefix of keys of registered voters, reserved like config key
+const voterprefix = "|voter|"
+
+// struct for voter of registry, key is voterprefix + id
+type registeredvoter struct {
+	ID           string `json:"id"` // normalized, name of voter in ballots
+	MSPID        string `json:"mspid"`
+	Fingerprint  string `json:"fingerprint,omitempty"` // sha256 of DER of certificate, hex
+	Subject      string `json:"subject,omitempty"`     // common name of subject of certificate
+	Name         string `json:"name,omitempty"`        // display name
+	Org          string `json:"org,omitempty"`         // organization of voter
+	Active       bool   `json:"active"`
+	Reason       string `json:"reason,omitempty"` // reason of deactivation
+	RegisteredBy string `json:"registeredby"`
+	RegisteredAt string `json:"registeredat"`
+	UpdatedBy    string `json:"updatedby,omitempty"`
+	UpdatedAt    string `json:"updatedat,omitempty"`
+}
+
+// voter id as it is compared: without surrounding spaces, lower case
+func normalizevoter(voter string) string {
+	return strings.ToLower(strings.TrimSpace(voter))
+}
+
+// fingerprint of certificate as registry keeps it
+func certfingerprint(certident *id.CertIdentity) string {
+	sum := sha256.Sum256(certident.Cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// registered voter of id, nil if voter is not registered
+func findvoter(stub shim.ChaincodeStubInterface, voter string) (*registeredvoter, error) {
+	key := voterprefix + normalizevoter(voter)
+	value, err := stub.GetState(key)
+	if err != nil {
+		return nil, errledger(key, err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	r := &registeredvoter{}
+	if err := json.Unmarshal(value, r); err != nil {
+		return nil, errinternal(fmt.Errorf("can't parse voter %s: %s", voter, err))
+	}
+	return r, nil
+}
+
+// registered voter of id, VOTER_NOT_FOUND if voter is not registered
+func getvoter(stub shim.ChaincodeStubInterface, voter string) (*registeredvoter, error) {
+	r, err := findvoter(stub, voter)
+	if err == nil && r == nil {
+		err = errvoternotfound(voter)
+	}
+	return r, err
+}
+
+// write voter in registry
+func putvoter(stub shim.ChaincodeStubInterface, r *registeredvoter) pb.Response {
+	key := voterprefix + r.ID
+	value, _ := json.Marshal(r)
+	if err := stub.PutState(key, value); err != nil {
+		return errledger(key, err).response()
+	}
+	return shim.Success(value)
+}
+
+// set binding and details of voter by key=value args: mspid, fingerprint, subject, name, org, active
+func (r *registeredvoter) setargs(args []string) error {
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("option %q is not key=value", arg)
+		}
+		switch key, value := strings.ToLower(kv[0]), kv[1]; key {
+		case "mspid":
+			r.MSPID = value
+		case "fingerprint": // hex, sha256: prefix and colons are allowed
+			fp := strings.Replace(strings.TrimPrefix(strings.ToLower(value), "sha256:"), ":", "", -1)
+			if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("fingerprint %q is not sha256 of certificate", value)
+			}
+			r.Fingerprint = fp
+		case "subject":
+			r.Subject = value
+		case "name":
+			r.Name = value
+		case "org":
+			r.Org = value
+		case "active":
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("option active: %s", err)
+			}
+			r.Active = flag
+			if flag {
+				r.Reason = ""
+			}
+		default:
+			return fmt.Errorf("unknown option %q", kv[0])
+		}
+	}
+
+	if r.MSPID == "" {
+		return fmt.Errorf("voter %s has no mspid", r.ID)
+	}
+	if r.Fingerprint == "" && r.Subject == "" {
+		return fmt.Errorf("voter %s is bound to no certificate, set fingerprint or subject", r.ID)
+	}
+	return nil
+}
+
+// check that certificate of invoker is the one voter is bound to
+func (r *registeredvoter) checkcert(certident *id.CertIdentity) error {
+	if certident.GetMSPID() != r.MSPID ||
+		(r.Fingerprint != "" && certfingerprint(certident) != r.Fingerprint) ||
+		(r.Subject != "" && certident.Cert.Subject.CommonName != r.Subject) {
+		return errcertmismatch(r.ID, certident.GetMSPID())
+	}
+	return nil
+}
+
+// check that invoker registers voter - members of admin orgs of config register any voter,
+// other invokers only themselves and in MSP of their own. Returns name of invoker
+func checkregistrar(stub shim.ChaincodeStubInterface, voter string, mspid string, action string) (string, error) {
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return "", errbadidentity(err)
+	}
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return "", err
+	}
+	invoker, err := invokername(stub, certident)
+	if err != nil {
+		return "", err
+	}
+	org := certident.GetMSPID()
+	if cfg.admin(org) {
+		return invoker, nil
+	}
+	if org != mspid || normalizevoter(invoker) != normalizevoter(voter) {
+		return "", errnotregistrar(voter, invoker, org, action)
+	}
+	return invoker, nil
+}
+
+// options of voterupdate which only admin orgs set: activation and binding to MSP and certificate
+var adminoptions = map[string]bool{"active": true, "mspid": true, "fingerprint": true, "subject": true}
+
+// check that invoker out of admin orgs changes only name and org of voter, not its activation or binding
+func checkadminoptions(stub shim.ChaincodeStubInterface, voter string, args []string) error {
+	certident, err := id.FromStub(stub) // identity of ivoker
+	if err != nil {
+		return errbadidentity(err)
+	}
+	cfg, err := getconfig(stub)
+	if err != nil {
+		return err
+	}
+	org := certident.GetMSPID()
+	if cfg.admin(org) {
+		return nil
+	}
+	for _, arg := range args {
+		if option := strings.ToLower(strings.SplitN(arg, "=", 2)[0]); adminoptions[option] {
+			invoker, err := invokername(stub, certident)
+			if err != nil {
+				return err
+			}
+			return errnotadminoption(voter, invoker, org, option)
+		}
+	}
+	return nil
+}
+
+// voters of ballot by registry: registered voters are named by their ids, take org of their MSP
+// and are to be active; voters out of registry are rejected if config requires registry
+func checkregistry(stub shim.ChaincodeStubInterface, cfg *ccconfig, v *votelist) error {
+	for i, voter := range v.Voters {
+		r, err := findvoter(stub, voter)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			if cfg.Registry {
+				return errvoternotfound(voter)
+			}
+			continue
+		}
+		if !r.Active {
+			return errvoterinactive(r.ID)
+		}
+
+		v.Voters[i] = r.ID
+		if v.Orgs == nil {
+			v.Orgs = map[string]string{}
+		}
+		org, ok := v.Orgs[voter]
+		delete(v.Orgs, voter)
+		if !ok {
+			org = r.MSPID
+		}
+		v.Orgs[r.ID] = org
+	}
+	return nil
+}
+
+// register voter of id bound to MSP and certificate: voterregister(id, mspid=, fingerprint= or subject=, name=, org=)
+func (t *SimpleChaincode) voterregister(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voter := normalizevoter(args[0])
+	if voter == "" || strings.ContainsAny(voter, "|=") {
+		return errbadargs("voterregister", fmt.Errorf("voter id %q is empty or has | or =", args[0])).response()
+	}
+	existing, err := findvoter(stub, voter)
+	if err != nil {
+		return errorresponse(err)
+	}
+	if existing != nil {
+		return errvoterexists(voter).response()
+	}
+
+	r := &registeredvoter{ID: voter, Active: true}
+	if err := r.setargs(args[1:]); err != nil {
+		return errbadargs("voterregister", err).response()
+	}
+	if r.RegisteredBy, err = checkregistrar(stub, voter, r.MSPID, "register"); err != nil {
+		return errorresponse(err)
+	}
+	r.RegisteredAt = txtime(stub)
+	return putvoter(stub, r)
+}
+
+// change binding or details of registered voter, active=true activates deactivated one.
+// Voter itself changes only its name and org, activation and binding are set by admin orgs
+func (t *SimpleChaincode) voterupdate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	r, err := getvoter(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+	if _, err := checkregistrar(stub, r.ID, r.MSPID, "update"); err != nil { // registrar of MSP before update
+		return errorresponse(err)
+	}
+	if err := checkadminoptions(stub, r.ID, args[1:]); err != nil {
+		return errorresponse(err)
+	}
+	if err := r.setargs(args[1:]); err != nil {
+		return errbadargs("voterupdate", err).response()
+	}
+	if r.UpdatedBy, err = checkregistrar(stub, r.ID, r.MSPID, "update"); err != nil { // and after it
+		return errorresponse(err)
+	}
+	r.UpdatedAt = txtime(stub)
+	return putvoter(stub, r)
+}
+
+// deactivate registered voter with reason: it can't vote and can't be voter of new ballots
+func (t *SimpleChaincode) voterdeactivate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	r, err := getvoter(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+	if r.UpdatedBy, err = checkregistrar(stub, r.ID, r.MSPID, "deactivate"); err != nil {
+		return errorresponse(err)
+	}
+	r.Active, r.UpdatedAt = false, txtime(stub)
+	if len(args) > 1 {
+		r.Reason = args[1]
+	}
+	return putvoter(stub, r)
+}
+
+// registered voter of id, id is compared normalized
+func (t *SimpleChaincode) voterinfo(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	r, err := getvoter(stub, args[0])
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	banswer, _ := json.Marshal(r)
+	return shim.Success(banswer)
+}
+
+// registered voters in order of ids
+func (t *SimpleChaincode) voterlist(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	iter, err := stub.GetStateByRange(voterprefix, voterprefix+string(utf8.MaxRune))
+	if err != nil {
+		return errledger(voterprefix, err).response()
+	}
+	defer iter.Close()
+
+	voters := []registeredvoter{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return errledger(voterprefix, err).response()
+		}
+		var r registeredvoter
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return errinternal(fmt.Errorf("can't parse voter %s: %s", kv.Key, err)).response()
+		}
+		voters = append(voters, r)
+	}
+
+	banswer, _ := json.Marshal(voters)
+	return shim.Success(banswer)
+}
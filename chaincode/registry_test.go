@@ -0,0 +1,190 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	id "github.com/s7techlab/cckit/identity"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestCheckUniqVoters(t *testing.T) {
+	if err := checkuniqvoters([]string{"org1", "org10", "org1.example.com"}); err != nil {
+		t.Errorf("voters with common prefix: %s", err)
+	}
+	if err := checkuniqvoters([]string{"org1", " ORG1"}); err == nil {
+		t.Errorf("normalized duplicate is not found")
+	}
+}
+
+func TestVote_ListedVoter(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1 := certvoter(t, stubsert1)
+	listed := " " + strings.ToUpper(voter1)
+
+	// voter listed in other case votes and withdraws by key of listed spelling
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", listed)
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	if val, _ := stub.GetState("VoteHash|" + listed); val == nil {
+		t.Errorf("vote is not under key of listed voter %q", listed)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votewithdraw", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if val, _ := stub.GetState("VoteHash|" + listed); val != nil {
+		t.Errorf("withdrawn vote of %q is kept", listed)
+	}
+}
+
+func TestVoterRegistry(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+	cert1, err := id.New("Org1MSP", []byte(stubsert1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert2, err := id.New("Org2MSP", []byte(stubsert2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// members of MSP register voters of it
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voterregister", strings.ToUpper(voter1), "mspid=Org1MSP", "fingerprint="+certfingerprint(cert1), "name=Alice")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	for _, c := range []struct {
+		args []string
+		code string
+	}{
+		{[]string{"voterregister", voter1, "mspid=Org1MSP", "subject=alice"}, codevoterexists},
+		{[]string{"voterregister", voter2, "mspid=Org2MSP", "subject=bob"}, codenotregistrar},
+		// member of MSP can't squat ids of others in its MSP
+		{[]string{"voterregister", voter2, "mspid=Org1MSP", "subject=bob"}, codenotregistrar},
+		{[]string{"voterregister", "squatted", "mspid=Org1MSP", "subject=squatted"}, codenotregistrar},
+		{[]string{"voterregister", "org10", "mspid=Org1MSP"}, codebadargs},
+		{[]string{"voterregister", "org10", "mspid=Org1MSP", "fingerprint=abc"}, codebadargs},
+	} {
+		if res := stub.MockInvoke("1", argsbytes(c.args...)); !strings.HasPrefix(res.Message, c.code) {
+			t.Errorf("%v: %d %s", c.args, res.Status, res.Message)
+		}
+	}
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("voterregister", voter2, "mspid=Org2MSP", "subject="+cert2.Cert.Subject.CommonName)); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	// voter3 is bound to certificate it has not
+	stub.MockCreator("Org3MSP", []byte(stubsert3))
+	if res := stub.MockInvoke("1", argsbytes("voterregister", voter3, "mspid=Org3MSP", "fingerprint="+certfingerprint(cert1))); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+
+	// ballot names registered voters by ids and takes their orgs
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", " "+strings.ToUpper(voter1), voter2, voter3)
+	var b ballotview
+	query(t, stub, &b, "voteinfo", "VoteHash")
+	if b.Voters[0] != voter1 || b.Orgs[voter1] != "Org1MSP" || b.Orgs[voter2] != "Org2MSP" {
+		t.Errorf("registered voters of ballot %v %v", b.Voters, b.Orgs)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Dup", "https://git.repo", "10.04.2099.10.00", voter1, strings.ToUpper(voter1))); !strings.HasPrefix(res.Message, codeduplicatevoters) {
+		t.Errorf("normalized duplicate: %d %s", res.Status, res.Message)
+	}
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+
+	stub.MockCreator("Org3MSP", []byte(stubsert3))
+	if res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "yes", "")); res.Status != statusforbidden || !strings.HasPrefix(res.Message, codecertmismatch) {
+		t.Errorf("vote by other certificate: %d %s", res.Status, res.Message)
+	}
+
+	// deactivated voter can't vote and can't be voter of new ballots
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	if res := stub.MockInvoke("1", argsbytes("voterdeactivate", voter2, "left board")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("vote", "VoteHash", "no", "")); !strings.HasPrefix(res.Message, codevoterinactive) {
+		t.Errorf("vote of deactivated: %d %s", res.Status, res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote2", "https://git.repo", "10.04.2099.10.00", voter2)); !strings.HasPrefix(res.Message, codevoterinactive) {
+		t.Errorf("ballot of deactivated: %d %s", res.Status, res.Message)
+	}
+
+	// voter itself changes its name and org, not its activation or binding
+	stub.MockCreator("Org2MSP", []byte(stubsert2))
+	for _, option := range []string{"active=true", "subject=mallory", "fingerprint=" + certfingerprint(cert1), "mspid=Org1MSP"} {
+		if res := stub.MockInvoke("1", argsbytes("voterupdate", voter2, option)); res.Status != statusforbidden || !strings.HasPrefix(res.Message, codenotregistrar) {
+			t.Errorf("voterupdate %s by voter itself: %d %s", option, res.Status, res.Message)
+		}
+	}
+	if res := stub.MockInvoke("1", argsbytes("voterupdate", voter2, "org=Example Org")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var r registeredvoter
+	query(t, stub, &r, "voterinfo", strings.ToUpper(voter2))
+	if r.Active || r.Org != "Example Org" || r.Subject != cert2.Cert.Subject.CommonName || r.UpdatedBy != voter2 {
+		t.Errorf("voter updated by itself %+v", r)
+	}
+
+	// admin org reactivates it
+	if res := stub.MockInit("1", argsbytes("init", "admin=Org1MSP")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voterupdate", voter2, "active=true")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var reactivated registeredvoter
+	query(t, stub, &reactivated, "voterinfo", voter2)
+	if !reactivated.Active || reactivated.Reason != "" || reactivated.UpdatedBy != voter1 {
+		t.Errorf("reactivated voter %+v", reactivated)
+	}
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+
+	// registry of config requires registered voters
+	if res := stub.MockInit("1", argsbytes("init", "registry=true")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("votestart", "Vote3", "https://git.repo", "10.04.2099.10.00", voter1, "org10")); !strings.HasPrefix(res.Message, codevoternotfound) {
+		t.Errorf("ballot of unregistered voter: %d %s", res.Status, res.Message)
+	}
+
+	var voters []registeredvoter
+	query(t, stub, &voters, "voterlist")
+	if len(voters) != 3 || voters[0].ID != voter1 || voters[0].Name != "Alice" {
+		t.Errorf("registry %+v", voters)
+	}
+}
+
+func TestVoterRegistry_Admin(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	if res := stub.MockInit("1", argsbytes("init", "admin=Org3MSP")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	voter3 := certvoter(t, stubsert3)
+
+	// admin org registers and deactivates voters of other MSPs
+	stub.MockCreator("Org3MSP", []byte(stubsert3))
+	if res := stub.MockInvoke("1", argsbytes("voterregister", "org10", "mspid=Org1MSP", "subject=org10")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if res := stub.MockInvoke("1", argsbytes("voterdeactivate", "org10", "left board")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	var r registeredvoter
+	query(t, stub, &r, "voterinfo", "org10")
+	if r.RegisteredBy != voter3 || r.UpdatedBy != voter3 || r.Active {
+		t.Errorf("voter of admin %+v", r)
+	}
+
+	// member of MSP of voter is not its registrar
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voterupdate", "org10", "active=true")); res.Status != statusforbidden || !strings.HasPrefix(res.Message, codenotregistrar) {
+		t.Errorf("update by member of MSP: %d %s", res.Status, res.Message)
+	}
+}
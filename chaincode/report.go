@@ -0,0 +1,263 @@
+package chaincode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// options of vote in order of reports
+var voteoptions = tally.Options
+
+// options of voteresult \ voteresultcsv: filter and order of report entries and format, given as key=value args
+type reportopts struct {
+	org    string    // MSP ID of voter
+	vote   string    // yes \ no \ neutral
+	from   time.Time // votes cast not before
+	to     time.Time // votes cast not after
+	sortby string    // org \ vote \ time
+	desc   bool      // reverse order of sort
+	format string    // name of formatter
+	csv    csvopts   // options of csv formatter
+}
+
+// struct for turnout of voting
+type voteturnout struct {
+	Registered int     `json:"registered"` // voters in list of vote
+	Voted      int     `json:"voted"`      // voters with vote in ledger
+	Percent    float64 `json:"percent"`
+}
+
+// struct for report of vote
+type votereport struct {
+	VoteID      string          `json:"voteid"`  // Hash of vote
+	RepoURL     string          `json:"repourl"` // link to repo with add data to vote
+	EndDate     string          `json:"enddate"` // deadline of vote
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Proposer    string          `json:"proposer,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	Artifacts   []artifact      `json:"artifacts,omitempty"` // pinned content of proposal
+	Counts      map[string]int  `json:"counts"`              // votes per option
+	Turnout     voteturnout     `json:"turnout"`             // how many voters voted
+	Decision    string          `json:"decision"`            // result according to votes
+	Decided     bool            `json:"decided"`             // decision is not equal and reaches majority of ballot
+	Votes       []voterslist    `json:"votes"`               // entries of voters
+	Abstained   []string        `json:"abstained"`           // voters without vote
+	Certificate votecertificate `json:"certificate"`         // Merkle root over counted votes
+	Rounds      []roundsummary  `json:"rounds,omitempty"`    // rounds of ballot with runoff, from the first one
+	State       string          `json:"state,omitempty"`     // state of ballot
+	Transitions []transition    `json:"transitions,omitempty"`
+}
+
+// parse voteresult \ voteresultcsv args after voteID
+func reportoptsfromargs(args []string, format string) (reportopts, error) {
+	opts := reportopts{format: format, csv: defaultcsvopts()}
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return opts, fmt.Errorf("option %q is not key=value", arg)
+		}
+		key, value := strings.ToLower(kv[0]), kv[1]
+
+		switch key {
+		case "org":
+			opts.org = value
+		case "vote":
+			if !checkvotes(value) {
+				return opts, fmt.Errorf("can't determine vote %q", value)
+			}
+			opts.vote = strings.ToLower(value)
+		case "from", "to":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return opts, fmt.Errorf("option %s: %s", key, err)
+			}
+			if key == "from" {
+				opts.from = t
+			} else {
+				opts.to = t
+			}
+		case "sort":
+			sortby := strings.ToLower(value)
+			if strings.HasPrefix(sortby, "-") {
+				opts.desc = true
+				sortby = sortby[1:]
+			}
+			if sortby != "org" && sortby != "vote" && sortby != "time" {
+				return opts, fmt.Errorf("can't sort by %q", value)
+			}
+			opts.sortby = sortby
+		case "format":
+			format := strings.ToLower(value)
+			if _, ok := formatters[format]; !ok {
+				return opts, fmt.Errorf("unknown format %q", value)
+			}
+			opts.format = format
+		default:
+			known, err := opts.csv.set(key, value)
+			if err != nil {
+				return opts, err
+			}
+			if !known {
+				return opts, fmt.Errorf("unknown option %q", kv[0])
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// read list of voters and all votes of it from ledger
+func collectvotes(stub shim.ChaincodeStubInterface, voteid string) (*votelist, []voterslist, error) {
+	votebyte, err := stub.GetState(voteid) //metadata & voters
+	if err != nil {
+		return nil, nil, errledger(voteid, err)
+	}
+	if votebyte == nil {
+		return nil, nil, errballotnotfound(voteid)
+	}
+	votestruct := bytesToVoteList(votebyte)
+	if votestruct.Archive != nil {
+		return votestruct, votestruct.Archive.votes(), nil
+	}
+
+	votearr := []voterslist{}
+	for _, v := range votestruct.Voters { // for every voter in our list of voters
+		val, err := stub.GetState(voteid + "|" + v) // key for vote is key + "|voter"
+		if err != nil {
+			return nil, nil, errledger(voteid+"|"+v, err)
+		}
+		if val == nil { // voter has not voted yet
+			continue
+		}
+
+		votestr := bytesToVotersList(val)
+		votestr.Voter = v
+		votearr = append(votearr, votestr)
+	}
+
+	return votestruct, votearr, nil
+}
+
+// count votes per option
+func countvotes(votearr []voterslist) map[string]int {
+	votes := []string{}
+	for _, v := range votearr {
+		votes = append(votes, v.Vote)
+	}
+	return tally.Count(votes)
+}
+
+// count votes and combine report of voting
+func buildreport(voteid string, votestruct *votelist, votearr []voterslist) (*votereport, error) {
+	results := countvotes(votearr)
+
+	result, err := resultfrommap(results)
+	if err != nil {
+		return nil, err
+	}
+	_, decided := votestruct.decide(results)
+
+	voted := map[string]bool{}
+	for _, v := range votearr {
+		voted[v.Voter] = true
+	}
+	abstained := []string{}
+	for _, v := range votestruct.Voters {
+		if !voted[v] {
+			abstained = append(abstained, v)
+		}
+	}
+
+	turnout := newturnout(len(votestruct.Voters), len(votearr))
+	cert := certifyvotes(voteid, votearr)
+	if votestruct.Archive != nil { // votes of archive are without comments, root is the one of votes
+		cert = votestruct.Archive.certificate()
+	}
+
+	return &votereport{
+		VoteID:      voteid,
+		RepoURL:     votestruct.RepoURL,
+		EndDate:     votestruct.EndDate,
+		Title:       votestruct.Title,
+		Description: votestruct.Description,
+		Proposer:    votestruct.Proposer,
+		Tags:        votestruct.Tags,
+		Artifacts:   votestruct.Artifacts,
+		Counts:      results,
+		Turnout:     turnout,
+		Decision:    result,
+		Decided:     decided,
+		Votes:       votearr,
+		Abstained:   abstained,
+		Certificate: cert,
+		State:       votestruct.state(),
+		Transitions: votestruct.Transitions,
+	}, nil
+}
+
+// decision of report, marked when option of decision has no majority of ballot - equal is no decision by itself
+func (r votereport) resolution() string {
+	if !r.Decided && r.Decision != "equal" {
+		return r.Decision + " (no decision)"
+	}
+	return r.Decision
+}
+
+// copy of report with filtered and sorted entries - counts stay for all votes,
+// abstained voters are left only when entries are not filtered
+func (r votereport) apply(opts reportopts) votereport {
+	if opts.org != "" || opts.vote != "" || !opts.from.IsZero() || !opts.to.IsZero() {
+		r.Abstained = []string{}
+	}
+
+	votes := []voterslist{}
+	for _, v := range r.Votes {
+		if opts.org != "" && v.Org != opts.org {
+			continue
+		}
+		if opts.vote != "" && v.Vote != opts.vote {
+			continue
+		}
+		if !opts.from.IsZero() || !opts.to.IsZero() {
+			t := v.time()
+			if t.IsZero() || (!opts.from.IsZero() && t.Before(opts.from)) || (!opts.to.IsZero() && t.After(opts.to)) {
+				continue
+			}
+		}
+		votes = append(votes, v)
+	}
+
+	var less func(a, b voterslist) bool
+	switch opts.sortby {
+	case "org":
+		less = func(a, b voterslist) bool { return a.Org < b.Org }
+	case "vote":
+		less = func(a, b voterslist) bool { return a.Vote < b.Vote }
+	case "time":
+		less = func(a, b voterslist) bool { return a.time().Before(b.time()) }
+	}
+	if less != nil {
+		sort.SliceStable(votes, func(i, j int) bool {
+			if opts.desc {
+				return less(votes[j], votes[i])
+			}
+			return less(votes[i], votes[j])
+		})
+	}
+
+	r.Votes = votes
+	return r
+}
+
+// time of vote, zero for votes written without it
+func (v voterslist) time() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, v.Timestamp)
+	return t
+}
@@ -0,0 +1,131 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// ballot of 3 voters, where 2 voted: Org2 - yes, Org1 - no
+func reportstub(t *testing.T) *cckit.MockStub {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+
+	startvoting(t, stub, "VoteHash", certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3))
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "Yes", "Looks {good} [to me]")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "No", "Not now")
+
+	return stub
+}
+
+func queryreport(t *testing.T, stub *cckit.MockStub, args ...string) votereport {
+	res := stub.MockInvoke("1", argsbytes(append([]string{"voteresult", "VoteHash"}, args...)...))
+	if res.Status != shim.OK {
+		t.Fatalf("voteresult failed: %s", res.Message)
+	}
+
+	var rep votereport
+	if err := json.Unmarshal(res.Payload, &rep); err != nil {
+		t.Fatalf("voteresult is not json: %s", err)
+	}
+	return rep
+}
+
+func TestReport_JSON(t *testing.T) {
+	stub := reportstub(t)
+	rep := queryreport(t, stub)
+
+	if rep.VoteID != "VoteHash" || rep.RepoURL != "https://git.repo" || rep.EndDate != "10.04.2099.10.00" {
+		t.Errorf("wrong ballot metadata: %+v", rep)
+	}
+	if rep.Counts["yes"] != 1 || rep.Counts["no"] != 1 || rep.Counts["neutral"] != 0 {
+		t.Errorf("wrong counts: %v", rep.Counts)
+	}
+	if rep.Turnout.Registered != 3 || rep.Turnout.Voted != 2 {
+		t.Errorf("wrong turnout: %+v", rep.Turnout)
+	}
+	if rep.Decision != "equal" {
+		t.Errorf("decision %q, expected equal", rep.Decision)
+	}
+	if len(rep.Votes) != 2 {
+		t.Fatalf("%d votes in report, expected 2", len(rep.Votes))
+	}
+	for _, v := range rep.Votes {
+		if v.TxID == "" || v.Timestamp == "" || v.Org == "" {
+			t.Errorf("vote without tx data: %+v", v)
+		}
+	}
+	if rep.Votes[1].Org != "Org2MSP" || rep.Votes[1].Comment != "Looks {good} [to me]" {
+		t.Errorf("comment is changed: %+v", rep.Votes[1])
+	}
+
+	stored, _ := stub.GetState("VoteHash|result")
+	var storedrep votereport
+	if err := json.Unmarshal(stored, &storedrep); err != nil || storedrep.Decision != "equal" {
+		t.Errorf("result record is not stored as json: %s", stored)
+	}
+}
+
+func TestReport_FilterSort(t *testing.T) {
+	stub := reportstub(t)
+
+	rep := queryreport(t, stub, "org=Org1MSP")
+	if len(rep.Votes) != 1 || rep.Votes[0].Vote != "no" {
+		t.Errorf("org filter: %+v", rep.Votes)
+	}
+	if rep.Counts["yes"] != 1 {
+		t.Errorf("filter must not change counts: %v", rep.Counts)
+	}
+
+	rep = queryreport(t, stub, "vote=yes")
+	if len(rep.Votes) != 1 || rep.Votes[0].Org != "Org2MSP" {
+		t.Errorf("vote filter: %+v", rep.Votes)
+	}
+
+	rep = queryreport(t, stub, "sort=-org")
+	if len(rep.Votes) != 2 || rep.Votes[0].Org != "Org2MSP" {
+		t.Errorf("sort by org desc: %+v", rep.Votes)
+	}
+
+	rep = queryreport(t, stub, "sort=time")
+	if len(rep.Votes) != 2 || rep.Votes[0].Org != "Org2MSP" {
+		t.Errorf("sort by time: %+v", rep.Votes)
+	}
+
+	rep = queryreport(t, stub, "from=2000-01-01T00:00:00Z", "to=2001-01-01T00:00:00Z")
+	if len(rep.Votes) != 0 {
+		t.Errorf("time filter: %+v", rep.Votes)
+	}
+
+	res := stub.MockInvoke("1", argsbytes("voteresult", "VoteHash", "sort=voter"))
+	if res.Status == shim.OK {
+		t.Errorf("unknown sort is accepted")
+	}
+}
+
+func TestReport_Text(t *testing.T) {
+	stub := reportstub(t)
+
+	res := stub.MockInvoke("1", argsbytes("voteresult", "VoteHash", "format=text"))
+	if res.Status != shim.OK {
+		t.Fatalf("voteresult failed: %s", res.Message)
+	}
+
+	text := string(res.Payload)
+	for _, s := range []string{"Vote ID: VoteHash", "Resolution of Voting: equal", "Voice: no", "Comment: Not now"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("text report has no %q:\n%s", s, text)
+		}
+	}
+}
+
+func TestBytesToVotersList_Legacy(t *testing.T) {
+	v := bytesToVotersList([]byte("{ca.org1.example.com No Because I can}"))
+	if v.Voter != "ca.org1.example.com" || v.Vote != "no" || v.Comment != "Because I can" {
+		t.Errorf("legacy vote parsed wrong: %+v", v)
+	}
+}
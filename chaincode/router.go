@@ -0,0 +1,232 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// types of args of chaincode functions
+const (
+	argstring = "string"    // any non empty string
+	argvote   = "vote"      // yes \ no \ neutral
+	argjson   = "json"      // json document
+	argoption = "key=value" // option of report
+)
+
+// kinds of chaincode functions
+const (
+	fninvoke = "invoke" // writes to ledger
+	fnquery  = "query"  // only reads ledger
+)
+
+// struct for arg of chaincode function
+type argspec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"` // takes all rest args, only last arg
+}
+
+// struct for chaincode function in registry
+type ccfunction struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"` // invoke \ query
+	Args      []argspec `json:"args"`
+	Signature string    `json:"signature"`
+	Doc       string    `json:"doc"`
+	handler   func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) pb.Response
+}
+
+// registry of chaincode functions, in order of registration
+var ccfunctions = []*ccfunction{}
+
+func init() {
+	register("votestart", fninvoke, (*SimpleChaincode).votestart,
+		"start voting with list of voters; key=value args are metadata: title, description, proposer (only invoker), tag, tags, "+
+			"artifact=name@sha256:digest or name@git:commit, requireack=true, quorum=percent, org=mspid:voter,voter, majority=percent, "+
+			"runoff=duration after close or end date - close without decision opens runoff of yes and no, "+
+			"draft=true starts draft, votestart of proposer opens it, reason of transition, start=date of opening of vote, "+
+			"maxextend=duration of total extension, sponsor=mspid of org to approve extensions, approvals=orgs to approve extension, "+
+			"group=name adds members of voter group before voters of args, "+
+			"endorser=mspid of org which endorses amendments of ballot with org of creator",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "repourl", Type: argstring},
+		argspec{Name: "enddate", Type: argstring},
+		argspec{Name: "voters", Type: argstring, Variadic: true})
+	register("vote", fninvoke, (*SimpleChaincode).vote, "vote of invoker, returns receipt; ack is comma separated digests of reviewed artifacts",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "vote", Type: argvote},
+		argspec{Name: "comment", Type: argstring, Optional: true},
+		argspec{Name: "ack", Type: argstring, Optional: true})
+	register("voteend", fninvoke, (*SimpleChaincode).votend, "close open voting, only proposer can",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "reason", Type: argstring, Optional: true})
+	register("voteextend", fninvoke, (*SimpleChaincode).voteextend,
+		"request or approve extension of end date of open voting, proposer and sponsor orgs can; applied by approvals of orgs, one of them other than org of request",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "enddate", Type: argstring},
+		argspec{Name: "reason", Type: argstring, Optional: true})
+	register("votecancel", fninvoke, (*SimpleChaincode).votecancel, "cancel draft or open voting, only proposer can",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "reason", Type: argstring})
+	register("votearchive", fninvoke, (*SimpleChaincode).votearchive, "archive closed or cancelled voting, only proposer can",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "reason", Type: argstring, Optional: true})
+	register("votewithdraw", fninvoke, (*SimpleChaincode).votewithdraw, "withdraw vote of invoker while voting is open",
+		argspec{Name: "voteid", Type: argstring})
+	register("voteinfo", fnquery, (*SimpleChaincode).voteinfo, "ballot with metadata and voters",
+		argspec{Name: "voteid", Type: argstring})
+	register("votepending", fnquery, (*SimpleChaincode).votepending, "voters without vote and turnout of orgs",
+		argspec{Name: "voteid", Type: argstring})
+	register("votestatus", fnquery, (*SimpleChaincode).votestatus, "turnout of ballot against its quorum",
+		argspec{Name: "voteid", Type: argstring})
+	register("votelist", fnquery, (*SimpleChaincode).votelist, "ballots in order of ids, options limit=N and after=voteid give pages",
+		argspec{Name: "options", Type: argoption, Optional: true, Variadic: true})
+	register("voteresult", fninvoke, (*SimpleChaincode).voteresult, "count result, write it in ledger and return report",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "options", Type: argoption, Optional: true, Variadic: true})
+	register("voteresultcsv", fnquery, (*SimpleChaincode).voteresultcsv, "report of voting, csv by default",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "options", Type: argoption, Optional: true, Variadic: true})
+	register("voteanalytics", fnquery, (*SimpleChaincode).voteanalytics,
+		"analytics over all ballots, view is participation, orgturnout, decision or orgagreement; "+
+			"options limit=N and after=key give pages, format=csv with csv options of voteresultcsv",
+		argspec{Name: "view", Type: argstring},
+		argspec{Name: "options", Type: argoption, Optional: true, Variadic: true})
+	register("voteproof", fnquery, (*SimpleChaincode).voteproof, "inclusion proof of vote in Merkle root of result",
+		argspec{Name: "voteid", Type: argstring},
+		argspec{Name: "voter", Type: argstring, Optional: true})
+	register("verifyreceipt", fnquery, (*SimpleChaincode).verifyreceipt, "check receipt of vote against ledger",
+		argspec{Name: "receipt", Type: argjson})
+	register("votehistory", fnquery, (*SimpleChaincode).votehistory, "value of key in ledger",
+		argspec{Name: "key", Type: argstring})
+	register("groupcreate", fninvoke, (*SimpleChaincode).groupcreate, "create group of voters, invoker owns it; description=text",
+		argspec{Name: "name", Type: argstring},
+		argspec{Name: "members", Type: argstring, Variadic: true})
+	register("groupupdate", fninvoke, (*SimpleChaincode).groupupdate,
+		"next version of group, owner and admin orgs can; voters replace members, add=voter and remove=voter change them, description=text",
+		argspec{Name: "name", Type: argstring},
+		argspec{Name: "members", Type: argstring, Variadic: true})
+	register("groupdelete", fninvoke, (*SimpleChaincode).groupdelete, "delete group, owner and admin orgs can",
+		argspec{Name: "name", Type: argstring})
+	register("groupinfo", fnquery, (*SimpleChaincode).groupinfo, "group with members and version",
+		argspec{Name: "name", Type: argstring})
+	register("grouplist", fnquery, (*SimpleChaincode).grouplist, "groups in order of names")
+	register("voterregister", fninvoke, (*SimpleChaincode).voterregister,
+		"register voter bound to MSP and certificate, admin orgs and voter itself can; options mspid=, fingerprint=sha256 of certificate, "+
+			"subject=common name, name=display name, org=organization",
+		argspec{Name: "id", Type: argstring},
+		argspec{Name: "options", Type: argoption, Variadic: true})
+	register("voterupdate", fninvoke, (*SimpleChaincode).voterupdate,
+		"change binding or details of registered voter, options of voterregister and active=true; "+
+			"voter itself changes only name and org, admin orgs change binding and activate",
+		argspec{Name: "id", Type: argstring},
+		argspec{Name: "options", Type: argoption, Variadic: true})
+	register("voterdeactivate", fninvoke, (*SimpleChaincode).voterdeactivate, "deactivate registered voter, it can't vote and can't be voter of new ballots",
+		argspec{Name: "id", Type: argstring},
+		argspec{Name: "reason", Type: argstring, Optional: true})
+	register("voterinfo", fnquery, (*SimpleChaincode).voterinfo, "registered voter with binding and active flag",
+		argspec{Name: "id", Type: argstring})
+	register("voterlist", fnquery, (*SimpleChaincode).voterlist, "registered voters in order of ids")
+	register("config", fnquery, (*SimpleChaincode).config,
+		"configuration of chaincode of Init: admin orgs, options, tally rule, identity mode, maxvoters, schema version and migrations")
+	register("describe", fnquery, (*SimpleChaincode).describe, "list of chaincode functions and their args")
+}
+
+// add function to registry
+func register(name string, kind string, handler func(*SimpleChaincode, shim.ChaincodeStubInterface, []string) pb.Response, doc string, args ...argspec) {
+	sig := []string{}
+	for _, a := range args {
+		s := a.Name + " " + a.Type
+		if a.Variadic {
+			s = a.Name + " ..." + a.Type
+		}
+		if a.Optional {
+			s = "[" + s + "]"
+		}
+		sig = append(sig, s)
+	}
+
+	ccfunctions = append(ccfunctions, &ccfunction{
+		Name:      name,
+		Kind:      kind,
+		Args:      args,
+		Signature: name + "(" + strings.Join(sig, ", ") + ")",
+		Doc:       doc,
+		handler:   handler,
+	})
+}
+
+// function of registry by name, case insensitive
+func lookupfunction(name string) *ccfunction {
+	for _, f := range ccfunctions {
+		if f.Name == strings.ToLower(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// check count and types of args against function args
+func (f *ccfunction) checkargs(args []string) error {
+	required, variadic := 0, false
+	for _, a := range f.Args {
+		if !a.Optional {
+			required++
+		}
+		variadic = variadic || a.Variadic
+	}
+
+	if len(args) < required {
+		return fmt.Errorf("%s expects at least %d args, got %d: %s", f.Name, required, len(args), f.Signature)
+	}
+	if !variadic && len(args) > len(f.Args) {
+		return fmt.Errorf("%s expects at most %d args, got %d: %s", f.Name, len(f.Args), len(args), f.Signature)
+	}
+
+	for i, arg := range args {
+		spec := f.Args[len(f.Args)-1]
+		if i < len(f.Args) {
+			spec = f.Args[i]
+		}
+
+		if err := checkarg(spec, arg); err != nil {
+			return fmt.Errorf("%s: arg %d (%s): %s", f.Name, i+1, spec.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// check one arg by its type
+func checkarg(spec argspec, arg string) error {
+	switch spec.Type {
+	case argstring:
+		if arg == "" && !spec.Optional {
+			return fmt.Errorf("must not be empty")
+		}
+	case argvote:
+		if !checkvotes(arg) {
+			return fmt.Errorf("can't determine vote %q, expected yes, no or neutral", arg)
+		}
+	case argjson:
+		if !json.Valid([]byte(arg)) {
+			return fmt.Errorf("is not json")
+		}
+	case argoption:
+		if !strings.Contains(arg, "=") {
+			return fmt.Errorf("option %q is not key=value", arg)
+		}
+	}
+	return nil
+}
+
+// list of chaincode functions
+func (t *SimpleChaincode) describe(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	banswer, _ := json.Marshal(ccfunctions)
+	return shim.Success(banswer)
+}
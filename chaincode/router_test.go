@@ -0,0 +1,78 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestRouter_Errors(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", certvoter(t, stubsert1))
+
+	cases := []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{"votes", "VoteHash", "yes"}, `unknown function "votes"`},
+		{[]string{"vote", "VoteHash"}, "vote expects at least 2 args, got 1"},
+		{[]string{"vote"}, "vote expects at least 2 args, got 0"},
+		{[]string{"vote", "VoteHash", "maybe"}, "arg 2 (vote): can't determine vote"},
+		{[]string{"vote", "VoteHash", "yes", "comment", "ack", "more"}, "vote expects at most 4 args"},
+		{[]string{"votestart", "VoteHash2", "https://git.repo", "10.04.2099.10.00"}, "votestart expects at least 4 args"},
+		{[]string{"voteresult", ""}, "arg 1 (voteid): must not be empty"},
+		{[]string{"voteresult", "VoteHash", "sort"}, `arg 2 (options): option "sort" is not key=value`},
+		{[]string{"verifyreceipt", "{"}, "arg 1 (receipt): is not json"},
+	}
+
+	for _, c := range cases {
+		res := stub.MockInvoke("1", argsbytes(c.args...))
+		if res.Status == shim.OK {
+			t.Errorf("%v is accepted", c.args)
+			continue
+		}
+		if !strings.Contains(res.Message, c.msg) {
+			t.Errorf("%v: error %q has no %q", c.args, res.Message, c.msg)
+		}
+	}
+
+	// names are case insensitive and comment is optional
+	if res := stub.MockInvoke("1", argsbytes("Vote", "VoteHash", "YES")); res.Status != shim.OK {
+		t.Errorf("vote without comment failed: %s", res.Message)
+	}
+}
+
+func TestRouter_Describe(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+
+	res := stub.MockInvoke("1", argsbytes("describe"))
+	if res.Status != shim.OK {
+		t.Fatalf("describe failed: %s", res.Message)
+	}
+
+	var functions []ccfunction
+	if err := json.Unmarshal(res.Payload, &functions); err != nil {
+		t.Fatal(err)
+	}
+
+	byname := map[string]ccfunction{}
+	for _, f := range functions {
+		byname[f.Name] = f
+	}
+	for _, name := range []string{"votestart", "vote", "voteresult", "voteresultcsv", "voteproof", "verifyreceipt", "describe"} {
+		if _, ok := byname[name]; !ok {
+			t.Errorf("describe has no %s", name)
+		}
+	}
+	if byname["vote"].Kind != fninvoke || byname["voteproof"].Kind != fnquery {
+		t.Errorf("wrong kinds of functions: %+v", functions)
+	}
+	if byname["vote"].Signature != "vote(voteid string, vote vote, [comment string], [ack string])" {
+		t.Errorf("signature of vote: %s", byname["vote"].Signature)
+	}
+}
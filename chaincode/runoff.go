@@ -0,0 +1,197 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// suffix of id of runoff ballot, opened by voteend of ballot without decision
+const runoffsuffix = "-runoff"
+
+// layout of end date of runoff ballot, as of votestart args
+const enddatelayout = "02.01.2006.15.04"
+
+// struct for round of ballot in report, in order of rounds
+type roundsummary struct {
+	VoteID   string         `json:"voteid"`
+	Round    int            `json:"round"`
+	Options  []string       `json:"options"`
+	Counts   map[string]int `json:"counts"`
+	Decision string         `json:"decision"`
+	Decided  bool           `json:"decided"` // decision is not equal and reaches majority
+	Closed   bool           `json:"closed"`
+}
+
+// check runoff option of votestart: duration after close or end date
+func checkrunoffend(value string) error {
+	if _, err := time.ParseDuration(value); err == nil {
+		return nil
+	}
+	if _, ok := tally.Deadline(value); ok {
+		return nil
+	}
+	return fmt.Errorf("option runoff: %q is not duration or end date", value)
+}
+
+// end date of runoff option given as date, false for duration after close
+func (v *votelist) runoffdeadline() (time.Time, bool) {
+	if _, err := time.ParseDuration(v.RunoffEnd); err == nil || v.RunoffEnd == "" {
+		return time.Time{}, false
+	}
+	return tally.Deadline(v.RunoffEnd)
+}
+
+// end date of runoff ballot closed at closedat (RFC3339)
+func (v *votelist) runoffenddate(closedat string) string {
+	d, err := time.ParseDuration(v.RunoffEnd)
+	if err != nil {
+		return v.RunoffEnd // end date as is
+	}
+	closed, err := time.Parse(time.RFC3339Nano, closedat)
+	if err != nil {
+		return ""
+	}
+	return closed.Add(d).UTC().Format(enddatelayout)
+}
+
+// options of vote of ballot, all of them if ballot does not restrict them
+func (v *votelist) options() []string {
+	if len(v.Options) > 0 {
+		return v.Options
+	}
+	return voteoptions
+}
+
+// vote is one of options of ballot
+func (v *votelist) allows(vote string) bool {
+	for _, option := range v.options() {
+		if option == vote {
+			return true
+		}
+	}
+	return false
+}
+
+// round of ballot, the first one is 1
+func (v *votelist) round() int {
+	if v.Round == 0 {
+		return 1
+	}
+	return v.Round
+}
+
+// decision of counts and if it decides ballot: decision is not equal
+// and option of decision has majority of counted votes, if ballot requires it
+func (v *votelist) decide(counts map[string]int) (string, bool) {
+	decision, err := tally.Decide(counts)
+	if err != nil {
+		return decision, false
+	}
+	return decision, tally.Decides(counts, decision, v.Majority)
+}
+
+// options of runoff - yes and no of options of ballot. Neutral is left out: decision counts yes minus no,
+// so neutral votes can't decide runoff
+func runoffoptions(options []string) []string {
+	decisive := []string{}
+	for _, option := range options {
+		if option == "yes" || option == "no" {
+			decisive = append(decisive, option)
+		}
+	}
+	return decisive
+}
+
+// runoff ballot of ballot closed without decision: options yes and no, the same voters
+// and metadata, end date of runoff option. Runoff ballot has no runoff of its own
+func (v *votelist) runoff(voteid string) (string, *votelist) {
+	r := *v
+	r.EndDate = v.runoffenddate(v.ClosedAt)
+	r.Options = runoffoptions(v.options())
+	r.Round = v.round() + 1
+	r.PrevRound = voteid
+	r.RunoffEnd = ""
+	r.Extensions = nil
+	r.NextRound = ""
+	r.Closed, r.ClosedAt = false, ""
+	// runoff is opened by transition of close
+	tr := v.Transitions[len(v.Transitions)-1]
+	tr.Reason = "runoff of " + voteid
+	r.begin(stateopen, tr)
+	return voteid + runoffsuffix, &r
+}
+
+// open runoff of ballot closed without decision, nil if ballot has no runoff option, is decided,
+// has not both yes and no to vote in runoff or is closed at or after end date of runoff
+func openrunoff(stub shim.ChaincodeStubInterface, voteid string, votestruct *votelist) (*ballotview, error) {
+	if votestruct.RunoffEnd == "" || votestruct.NextRound != "" || len(runoffoptions(votestruct.options())) < 2 {
+		return nil, nil
+	}
+	if (&votelist{EndDate: votestruct.runoffenddate(votestruct.ClosedAt)}).expired(votestruct.ClosedAt) {
+		return nil, nil
+	}
+	_, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return nil, err
+	}
+	counts := countvotes(votearr)
+	if _, decided := votestruct.decide(counts); decided {
+		return nil, nil
+	}
+
+	runoffid, runoff := votestruct.runoff(voteid)
+	if runoff.EndDate == "" {
+		return nil, errinternal(fmt.Errorf("no end date of runoff %s", runoffid))
+	}
+	existing, err := stub.GetState(runoffid)
+	if err != nil {
+		return nil, errledger(runoffid, err)
+	}
+	if existing != nil {
+		return nil, errinternal(fmt.Errorf("key %s of runoff is taken", runoffid))
+	}
+	value, _ := json.Marshal(runoff)
+	if err := stub.PutState(runoffid, value); err != nil {
+		return nil, errledger(runoffid, err)
+	}
+	if err := setendorsement(stub, runoffid, votestruct.endorsers()...); err != nil { // orgs of first round
+		return nil, err
+	}
+
+	votestruct.NextRound = runoffid
+	return &ballotview{VoteID: runoffid, votelist: *runoff}, nil
+}
+
+// rounds of ballot linked by runoffs, from the first one; nil for ballot without runoff
+func collectrounds(stub shim.ChaincodeStubInterface, voteid string, votestruct *votelist) ([]roundsummary, error) {
+	if votestruct.PrevRound == "" && votestruct.NextRound == "" {
+		return nil, nil
+	}
+
+	first := voteid
+	for v := votestruct; v.PrevRound != ""; {
+		first = v.PrevRound
+		prev, err := getballot(stub, first)
+		if err != nil {
+			return nil, err
+		}
+		v = prev
+	}
+
+	rounds := []roundsummary{}
+	for next := first; next != ""; {
+		v, votearr, err := collectvotes(stub, next)
+		if err != nil {
+			return nil, err
+		}
+		counts := countvotes(votearr)
+		decision, decided := v.decide(counts)
+		rounds = append(rounds, roundsummary{VoteID: next, Round: v.round(), Options: v.options(), Counts: counts, Decision: decision, Decided: decided, Closed: v.state() != stateopen})
+		next = v.NextRound
+	}
+	return rounds, nil
+}
@@ -0,0 +1,144 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestVoteEnd_Runoff(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, voter3, "runoff=48h", "majority=60")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+	castvote(t, stub, "Org3MSP", stubsert3, "VoteHash", "neutral", "")
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	ev := lastevent(t, stub, eventballotclosed)
+	if ev.Runoff == nil || ev.Runoff.VoteID != "VoteHash-runoff" || ev.Ballot.NextRound != "VoteHash-runoff" {
+		t.Fatalf("no runoff in event %+v", ev)
+	}
+
+	var runoff ballotview
+	query(t, stub, &runoff, "voteinfo", "VoteHash-runoff")
+	if runoff.Round != 2 || runoff.PrevRound != "VoteHash" || runoff.RunoffEnd != "" || runoff.Closed || len(runoff.Voters) != 3 ||
+		strings.Join(runoff.Options, ",") != "yes,no" || runoff.EndDate == "" {
+		t.Errorf("wrong runoff %+v", runoff)
+	}
+
+	// neutral is not an option of runoff
+	stub.MockCreator("Org3MSP", []byte(stubsert3))
+	if res := stub.MockInvoke("1", argsbytes("vote", "VoteHash-runoff", "neutral")); res.Status != statusbadrequest {
+		t.Errorf("vote of option out of runoff: %d %s", res.Status, res.Message)
+	}
+	castvote(t, stub, "Org3MSP", stubsert3, "VoteHash-runoff", "yes", "")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash-runoff", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash-runoff", "no", "")
+
+	// runoff has no runoff of its own
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash-runoff")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if ev := lastevent(t, stub, eventballotclosed); ev.Runoff != nil {
+		t.Errorf("runoff of runoff %+v", ev.Runoff)
+	}
+
+	for _, voteid := range []string{"VoteHash", "VoteHash-runoff"} {
+		var rep votereport
+		query(t, stub, &rep, "voteresultcsv", voteid, "format=json")
+		if len(rep.Rounds) != 2 || rep.Rounds[0].VoteID != "VoteHash" || rep.Rounds[0].Decided || rep.Rounds[1].Decision != "yes" || !rep.Rounds[1].Decided {
+			t.Errorf("rounds in report of %s %+v", voteid, rep.Rounds)
+		}
+	}
+	res := stub.MockInvoke("1", argsbytes("voteresultcsv", "VoteHash-runoff", "format=text"))
+	if !strings.Contains(string(res.Payload), "Round 1: VoteHash\nVotes: yes 1, no 1, neutral 1\nResolution: equal (no decision)") {
+		t.Errorf("text report of rounds:\n%s", res.Payload)
+	}
+}
+
+func TestVoteResult_Majority(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	// yes of 2 votes of 3 leads, but has no majority of 70%
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, voter3, "runoff=48h", "majority=70")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "yes", "")
+	castvote(t, stub, "Org3MSP", stubsert3, "VoteHash", "no", "")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	if res := stub.MockInvoke("1", argsbytes("voteend", "VoteHash")); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if ev := lastevent(t, stub, eventballotclosed); ev.Runoff == nil {
+		t.Fatalf("no runoff of ballot without majority")
+	}
+
+	var rep votereport
+	query(t, stub, &rep, "voteresult", "VoteHash")
+	if rep.Decision != "yes" || rep.Decided {
+		t.Errorf("report of ballot without majority: %s, decided %v", rep.Decision, rep.Decided)
+	}
+	if ev := lastevent(t, stub, eventresultpublished); ev.Result == nil || ev.Result.Decided {
+		t.Errorf("published result of ballot without majority %+v", ev.Result)
+	}
+	res := stub.MockInvoke("1", argsbytes("voteresultcsv", "VoteHash"))
+	if !strings.Contains(string(res.Payload), "yes (no decision)") {
+		t.Errorf("csv of ballot without majority:\n%s", res.Payload)
+	}
+}
+
+func TestVoteEnd_Decided(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	voter1, voter2 := certvoter(t, stubsert1), certvoter(t, stubsert2)
+
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	startvoting(t, stub, "VoteHash", voter1, voter2, "runoff=01.06.2099")
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	stub.MockInvoke("1", argsbytes("voteend", "VoteHash"))
+	if ev := lastevent(t, stub, eventballotclosed); ev.Runoff != nil || ev.Ballot.NextRound != "" {
+		t.Errorf("runoff of decided ballot %+v", ev)
+	}
+
+	v := &votelist{Majority: 60}
+	if _, decided := v.decide(map[string]int{"yes": 3, "no": 2, "neutral": 1}); decided {
+		t.Errorf("60%% of 6 votes is decided by 3")
+	}
+	// neutral decides nothing, it is not an option of runoff even with most votes
+	if options := runoffoptions(voteoptions); strings.Join(options, ",") != "yes,no" {
+		t.Errorf("options of runoff %v", options)
+	}
+	v = &votelist{Options: []string{"yes", "neutral"}, RunoffEnd: "48h"}
+	if runoff, err := openrunoff(stub, "Neutral", v); runoff != nil || err != nil {
+		t.Errorf("runoff of ballot without no %+v %v", runoff, err)
+	}
+	if _, err := votelistfromargs([]string{"VoteHash", "https://git.repo", "10.04.2099", voter1, "runoff=soon"}); err == nil {
+		t.Errorf("runoff option of no date is accepted")
+	}
+	for _, runoff := range []string{"runoff=10.04.2099", "runoff=01.04.2099"} {
+		if _, err := votelistfromargs([]string{"VoteHash", "https://git.repo", "10.04.2099", voter1, runoff}); err == nil {
+			t.Errorf("%s before end date is accepted", runoff)
+		}
+	}
+	// ballot closed after end date of runoff has no runoff
+	v = &votelist{RunoffEnd: "01.06.2099", ClosedAt: "2099-06-02T10:00:00Z"}
+	if runoff, err := openrunoff(stub, "Late", v); runoff != nil || err != nil {
+		t.Errorf("runoff of ballot closed after its end date %+v %v", runoff, err)
+	}
+	if d := (&votelist{RunoffEnd: "48h"}).runoffenddate("2099-04-10T10:00:00Z"); d != "12.04.2099.10.00" {
+		t.Errorf("end date of runoff %s", d)
+	}
+}
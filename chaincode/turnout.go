@@ -0,0 +1,143 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// org of voters without MSP ID - not voted and not in org option of votestart
+const unknownorg = "unknown"
+
+// struct for voter without vote
+type pendingvoter struct {
+	Voter string `json:"voter"`
+	Org   string `json:"org"`
+}
+
+// struct for turnout of one org
+type orgturnout struct {
+	Org string `json:"org"`
+	voteturnout
+}
+
+// struct for answer of votepending
+type votepending struct {
+	VoteID  string         `json:"voteid"`
+	Pending []pendingvoter `json:"pending"` // in order of list of voters
+	Orgs    []orgturnout   `json:"orgs"`    // in order of orgs
+	Turnout voteturnout    `json:"turnout"`
+}
+
+// struct for answer of votestatus
+type votestatus struct {
+	VoteID    string      `json:"voteid"`
+	Status    string      `json:"status"` // state of ballot, scheduled before start date
+	StartDate string      `json:"startdate,omitempty"`
+	EndDate   string      `json:"enddate"`
+	Turnout   voteturnout `json:"turnout"`
+	Quorum    float64     `json:"quorum"`    // percent, 0 - no quorum
+	Required  int         `json:"required"`  // votes for quorum
+	Missing   int         `json:"missing"`   // votes to reach quorum
+	QuorumMet bool        `json:"quorummet"` // result is valid by turnout
+}
+
+// turnout of voted of registered
+func newturnout(registered int, voted int) voteturnout {
+	t := voteturnout{Registered: registered, Voted: voted}
+	if registered > 0 {
+		t.Percent = float64(voted) * 100 / float64(registered)
+	}
+	return t
+}
+
+// registered voters who have not voted, with turnout of orgs
+func (t *SimpleChaincode) votepending(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	voted := map[string]bool{}
+	for _, v := range votearr {
+		voted[v.Voter] = true
+	}
+	orgs := voterorgs(votestruct, votearr)
+
+	answer := votepending{VoteID: voteid, Pending: []pendingvoter{}, Orgs: []orgturnout{}, Turnout: newturnout(len(votestruct.Voters), len(votearr))}
+	registered, votes := map[string]int{}, map[string]int{}
+	for _, voter := range votestruct.Voters {
+		org, ok := orgs[voter], voted[voter]
+		registered[org]++
+		if ok {
+			votes[org]++
+		} else {
+			answer.Pending = append(answer.Pending, pendingvoter{Voter: voter, Org: org})
+		}
+	}
+
+	for org, n := range registered {
+		answer.Orgs = append(answer.Orgs, orgturnout{Org: org, voteturnout: newturnout(n, votes[org])})
+	}
+	sort.Slice(answer.Orgs, func(i, j int) bool { return answer.Orgs[i].Org < answer.Orgs[j].Org })
+
+	panswer, _ := json.Marshal(answer)
+	return shim.Success(panswer)
+}
+
+// org by voter of ballot: MSP ID of vote, org option of votestart for voters without vote, unknown otherwise
+func voterorgs(votestruct *votelist, votearr []voterslist) map[string]string {
+	orgs := map[string]string{}
+	for _, voter := range votestruct.Voters {
+		orgs[voter] = votestruct.Orgs[voter]
+	}
+	for _, v := range votearr {
+		orgs[v.Voter] = v.Org
+	}
+	for voter, org := range orgs {
+		if org == "" {
+			orgs[voter] = unknownorg
+		}
+	}
+	return orgs
+}
+
+// turnout of ballot against its quorum
+func (t *SimpleChaincode) votestatus(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	voteid := args[0]
+
+	votestruct, votearr, err := collectvotes(stub, voteid)
+	if err != nil {
+		return errorresponse(err)
+	}
+
+	answer := votestatus{
+		VoteID:    voteid,
+		Status:    votestruct.status(stub),
+		EndDate:   votestruct.EndDate,
+		StartDate: votestruct.StartDate,
+		Turnout:   newturnout(len(votestruct.Voters), len(votearr)),
+		Quorum:    votestruct.Quorum,
+	}
+	answer.Required = quorumvotes(votestruct.Quorum, len(votestruct.Voters))
+	if answer.Required > len(votearr) {
+		answer.Missing = answer.Required - len(votearr)
+	}
+	answer.QuorumMet = answer.Missing == 0
+
+	sanswer, _ := json.Marshal(answer)
+	return shim.Success(sanswer)
+}
+
+// votes of registered voters which reach quorum percent
+func quorumvotes(quorum float64, registered int) int {
+	// rounding of float percent is not to require one more vote: 50% of 4 is 2
+	return int(math.Ceil(quorum*float64(registered)/100 - 1e-9))
+}
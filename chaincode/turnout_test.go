@@ -0,0 +1,90 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+func TestVotePending_Status(t *testing.T) {
+	stub := cckit.NewMockStub("crocc", new(SimpleChaincode))
+	stub.ClearCreatorAfterInvoke = false
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	voter1, voter2, voter3 := certvoter(t, stubsert1), certvoter(t, stubsert2), certvoter(t, stubsert3)
+
+	startvoting(t, stub, "VoteHash", voter1, voter2, voter3, "quorum=60", "org=Org2MSP:"+voter2)
+	castvote(t, stub, "Org1MSP", stubsert1, "VoteHash", "yes", "")
+
+	var pending votepending
+	query(t, stub, &pending, "votepending", "VoteHash")
+	if len(pending.Pending) != 2 || pending.Pending[0] != (pendingvoter{voter2, "Org2MSP"}) || pending.Pending[1] != (pendingvoter{voter3, unknownorg}) {
+		t.Errorf("pending voters %+v", pending.Pending)
+	}
+	orgs := []orgturnout{
+		{Org: "Org1MSP", voteturnout: voteturnout{Registered: 1, Voted: 1, Percent: 100}},
+		{Org: "Org2MSP", voteturnout: voteturnout{Registered: 1}},
+		{Org: unknownorg, voteturnout: voteturnout{Registered: 1}},
+	}
+	if len(pending.Orgs) != len(orgs) {
+		t.Fatalf("orgs %+v", pending.Orgs)
+	}
+	for i, o := range orgs {
+		if pending.Orgs[i] != o {
+			t.Errorf("org %d: %+v, expected %+v", i, pending.Orgs[i], o)
+		}
+	}
+
+	var status votestatus
+	query(t, stub, &status, "votestatus", "VoteHash")
+	if status.Status != "open" || status.Turnout.Voted != 1 || status.Quorum != 60 || status.Required != 2 || status.Missing != 1 || status.QuorumMet {
+		t.Errorf("status before quorum %+v", status)
+	}
+
+	castvote(t, stub, "Org2MSP", stubsert2, "VoteHash", "no", "")
+	stub.MockCreator("Org1MSP", []byte(stubsert1))
+	stub.MockInvoke("1", argsbytes("voteend", "VoteHash"))
+	query(t, stub, &status, "votestatus", "VoteHash")
+	if status.Status != "closed" || status.Missing != 0 || !status.QuorumMet {
+		t.Errorf("status after quorum %+v", status)
+	}
+
+	// without quorum option any turnout is enough
+	startvoting(t, stub, "NoQuorum", voter1)
+	query(t, stub, &status, "votestatus", "NoQuorum")
+	if status.Quorum != 0 || status.Required != 0 || !status.QuorumMet {
+		t.Errorf("status without quorum %+v", status)
+	}
+
+	if res := stub.MockInvoke("1", argsbytes("votepending", "Nothing")); res.Status != statusnotfound {
+		t.Errorf("votepending of unknown ballot: %d %s", res.Status, res.Message)
+	}
+}
+
+func TestVoteStart_QuorumOrg(t *testing.T) {
+	for _, arg := range []string{"quorum=0", "quorum=101", "quorum=many", "org=ca.org1.example.com", "org=Org9MSP:ca.nowhere"} {
+		if _, err := votelistfromargs([]string{"VoteHash", "https://git.repo", "10.04.2099", "ca.org1.example.com", arg}); err == nil {
+			t.Errorf("option %s is accepted", arg)
+		}
+	}
+
+	v, err := votelistfromargs([]string{"VoteHash", "https://git.repo", "10.04.2099", "quorum=50%", "org=Org1MSP:ca.org1.example.com, ca.org2.example.com", "ca.org1.example.com", "ca.org2.example.com"})
+	if err != nil || v.Quorum != 50 || v.Orgs["ca.org2.example.com"] != "Org1MSP" {
+		t.Errorf("ballot %+v %v", v, err)
+	}
+	if quorumvotes(50, 4) != 2 || quorumvotes(50, 3) != 2 || quorumvotes(100.0/3, 3) != 1 {
+		t.Errorf("votes for quorum")
+	}
+}
+
+// query of chaincode into value, fails test on error
+func query(t *testing.T, stub *cckit.MockStub, value interface{}, args ...string) {
+	res := stub.MockInvoke("1", argsbytes(args...))
+	if res.Status != shim.OK {
+		t.Fatalf("%s failed: %s", args[0], res.Message)
+	}
+	if err := json.Unmarshal(res.Payload, value); err != nil {
+		t.Fatalf("%s: %s", args[0], err)
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"strconv"
+)
+
+// views of analytics over all ballots
+const (
+	ViewParticipation = "participation"
+	ViewOrgTurnout    = "orgturnout"
+	ViewDecision      = "decision"
+	ViewOrgAgreement  = "orgagreement"
+)
+
+// Participation of voter in ballots
+type Participation struct {
+	Voter   string  `json:"voter"`
+	Ballots int     `json:"ballots"` // ballots with voter in list of voters
+	Voted   int     `json:"voted"`
+	Percent float64 `json:"percent"`
+}
+
+// OrgPeriodTurnout - turnout of org in ballots of month of end date
+type OrgPeriodTurnout struct {
+	Period     string  `json:"period"` // yyyy-mm, "unknown" for end dates in other format
+	Org        string  `json:"org"`
+	Ballots    int     `json:"ballots"`
+	Registered int     `json:"registered"`
+	Voted      int     `json:"voted"`
+	Percent    float64 `json:"percent"`
+}
+
+// DecisionAgreement - votes of voter the same as yes \ no decisions of closed ballots
+type DecisionAgreement struct {
+	Voter   string  `json:"voter"`
+	Decided int     `json:"decided"`
+	Agreed  int     `json:"agreed"`
+	Percent float64 `json:"percent"`
+}
+
+// OrgAgreement - closed ballots where majorities of votes of two orgs are the same
+type OrgAgreement struct {
+	Org1    string  `json:"org1"`
+	Org2    string  `json:"org2"`
+	Common  int     `json:"common"`
+	Agreed  int     `json:"agreed"`
+	Percent float64 `json:"percent"`
+}
+
+// page of view into rows, returns after of next page, empty on last page
+func (c *Client) analytics(ctx context.Context, view string, after string, limit int, rows interface{}) (string, error) {
+	args := []string{view, "after=" + after}
+	if limit > 0 {
+		args = append(args, "limit="+strconv.Itoa(limit))
+	}
+	payload, err := c.query(ctx, "voteanalytics", args...)
+	if err != nil {
+		return "", err
+	}
+	page := struct {
+		Rows interface{} `json:"rows"`
+		Next string      `json:"next"`
+	}{Rows: rows}
+	if err := unmarshal("voteanalytics", payload, &page); err != nil {
+		return "", err
+	}
+	return page.Next, nil
+}
+
+// Participation of voters in order of voters, page of limit rows after voter, all of them if limit is 0.
+// Returns after of next page
+func (c *Client) Participation(ctx context.Context, after string, limit int) ([]Participation, string, error) {
+	rows := []Participation{}
+	next, err := c.analytics(ctx, ViewParticipation, after, limit, &rows)
+	return rows, next, err
+}
+
+// OrgTurnout - turnout of orgs by months in order of periods and orgs, after is period|org
+func (c *Client) OrgTurnout(ctx context.Context, after string, limit int) ([]OrgPeriodTurnout, string, error) {
+	rows := []OrgPeriodTurnout{}
+	next, err := c.analytics(ctx, ViewOrgTurnout, after, limit, &rows)
+	return rows, next, err
+}
+
+// DecisionAgreement of voters in order of voters
+func (c *Client) DecisionAgreement(ctx context.Context, after string, limit int) ([]DecisionAgreement, string, error) {
+	rows := []DecisionAgreement{}
+	next, err := c.analytics(ctx, ViewDecision, after, limit, &rows)
+	return rows, next, err
+}
+
+// OrgAgreement of pairs of orgs in order of pairs, after is org1|org2
+func (c *Client) OrgAgreement(ctx context.Context, after string, limit int) ([]OrgAgreement, string, error) {
+	rows := []OrgAgreement{}
+	next, err := c.analytics(ctx, ViewOrgAgreement, after, limit, &rows)
+	return rows, next, err
+}
+
+// AnalyticsCSV - all rows of view as csv
+func (c *Client) AnalyticsCSV(ctx context.Context, view string, opts CSVOptions) ([]byte, error) {
+	args := append([]string{view, "format=csv"}, opts.options()...)
+	return c.query(ctx, "voteanalytics", args...)
+}
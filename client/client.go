@@ -0,0 +1,565 @@
+// Package client - typed Go client of cc_voting chaincode on top of Invoker of hlf-sdk-go.
+// Methods build args of chaincode functions, return typed answers and *Error for errors of chaincode
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/tally"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// Querier - part of api.Invoker, enough for queries
+type Querier interface {
+	Query(ctx context.Context, from msp.SigningIdentity, channel string, chaincode string, fn string, args [][]byte, transArgs api.TransArgs) (*peer.Response, error)
+}
+
+// Client of chaincode on channel, calls from identity
+type Client struct {
+	querier   Querier
+	invoker   api.Invoker // nil for client of queries only
+	identity  msp.SigningIdentity
+	channel   string
+	chaincode string
+}
+
+// New client of chaincode for invokes and queries
+func New(invoker api.Invoker, identity msp.SigningIdentity, channel string, chaincode string) *Client {
+	return &Client{querier: invoker, invoker: invoker, identity: identity, channel: channel, chaincode: chaincode}
+}
+
+// NewQuerier - client of chaincode for queries only, invokes return error
+func NewQuerier(querier Querier, identity msp.SigningIdentity, channel string, chaincode string) *Client {
+	return &Client{querier: querier, identity: identity, channel: channel, chaincode: chaincode}
+}
+
+// WithIdentity - copy of client, which calls from other identity
+func (c *Client) WithIdentity(identity msp.SigningIdentity) *Client {
+	cc := *c
+	cc.identity = identity
+	return &cc
+}
+
+// Identity of calls
+func (c *Client) Identity() msp.SigningIdentity {
+	return c.identity
+}
+
+// invoke function of chaincode, returns payload and id of tx
+func (c *Client) invoke(ctx context.Context, fn string, args ...string) ([]byte, string, error) {
+	if c.invoker == nil {
+		return nil, "", fmt.Errorf("%s: client is for queries only", fn)
+	}
+
+	res, tx, err := c.invoker.Invoke(ctx, c.identity, c.channel, c.chaincode, fn, argsbytes(args), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := responseerror(res.Status, res.Message, res.Payload); err != nil {
+		return nil, string(tx), err
+	}
+	return res.Payload, string(tx), nil
+}
+
+// query function of chaincode, returns payload
+func (c *Client) query(ctx context.Context, fn string, args ...string) ([]byte, error) {
+	res, err := c.querier.Query(ctx, c.identity, c.channel, c.chaincode, fn, argsbytes(args), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := responseerror(res.Status, res.Message, res.Payload); err != nil {
+		return nil, err
+	}
+	return res.Payload, nil
+}
+
+// parse json payload of function
+func unmarshal(fn string, payload []byte, v interface{}) error {
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%s: can't parse answer: %s", fn, err)
+	}
+	return nil
+}
+
+func argsbytes(args []string) [][]byte {
+	bargs := [][]byte{}
+	for _, a := range args {
+		bargs = append(bargs, []byte(a))
+	}
+	return bargs
+}
+
+//----------------- ballots
+
+// StartBallot starts voting, returns id of tx
+func (c *Client) StartBallot(ctx context.Context, req StartRequest) (string, error) {
+	if req.VoteID == "" || req.RepoURL == "" || req.EndDate == "" {
+		return "", fmt.Errorf("votestart: vote id, repo url and end date are required")
+	}
+	if len(req.Voters) == 0 && len(req.Groups) == 0 {
+		return "", fmt.Errorf("votestart: no voters")
+	}
+
+	args := []string{req.VoteID, req.RepoURL, req.EndDate}
+	for _, v := range req.Voters {
+		if strings.Contains(v, "=") { // key=value args are metadata
+			return "", fmt.Errorf("votestart: voter %q contains =", v)
+		}
+		args = append(args, v)
+	}
+	for _, kv := range [][2]string{{"title", req.Title}, {"description", req.Description}, {"proposer", req.Proposer}} {
+		if kv[1] != "" {
+			args = append(args, kv[0]+"="+kv[1])
+		}
+	}
+	for _, group := range req.Groups {
+		args = append(args, "group="+group)
+	}
+	for _, tag := range req.Tags {
+		args = append(args, "tag="+tag)
+	}
+	for _, a := range req.Artifacts {
+		args = append(args, "artifact="+a.arg())
+	}
+	if req.RequireAck {
+		args = append(args, "requireack=true")
+	}
+	if req.Quorum != 0 {
+		args = append(args, "quorum="+strconv.FormatFloat(req.Quorum, 'f', -1, 64))
+	}
+	if req.Majority != 0 {
+		args = append(args, "majority="+strconv.FormatFloat(req.Majority, 'f', -1, 64))
+	}
+	if req.Runoff != "" {
+		args = append(args, "runoff="+req.Runoff)
+	}
+	if req.MaxExtend != "" {
+		args = append(args, "maxextend="+req.MaxExtend)
+	}
+	for _, sponsor := range req.Sponsors {
+		args = append(args, "sponsor="+sponsor)
+	}
+	for _, endorser := range req.Endorsers {
+		args = append(args, "endorser="+endorser)
+	}
+	if req.Approvals != 0 {
+		args = append(args, "approvals="+strconv.Itoa(req.Approvals))
+	}
+	if req.StartDate != "" {
+		args = append(args, "start="+req.StartDate)
+	}
+	if req.Draft {
+		args = append(args, "draft=true")
+	}
+	if req.Reason != "" {
+		args = append(args, "reason="+req.Reason)
+	}
+	orgs, names := map[string][]string{}, []string{}
+	for voter, org := range req.Orgs {
+		if orgs[org] == nil {
+			names = append(names, org)
+		}
+		orgs[org] = append(orgs[org], voter)
+	}
+	sort.Strings(names)
+	for _, org := range names {
+		sort.Strings(orgs[org])
+		args = append(args, "org="+org+":"+strings.Join(orgs[org], ","))
+	}
+
+	_, tx, err := c.invoke(ctx, "votestart", args...)
+	return tx, err
+}
+
+// Ballot with metadata and voters
+func (c *Client) Ballot(ctx context.Context, voteid string) (*Ballot, error) {
+	payload, err := c.query(ctx, "voteinfo", voteid)
+	if err != nil {
+		return nil, err
+	}
+	b := &Ballot{}
+	return b, unmarshal("voteinfo", payload, b)
+}
+
+// Ballots - all ballots in order of ids
+func (c *Client) Ballots(ctx context.Context) ([]Ballot, error) {
+	payload, err := c.query(ctx, "votelist")
+	if err != nil {
+		return nil, err
+	}
+	ballots := []Ballot{}
+	return ballots, unmarshal("votelist", payload, &ballots)
+}
+
+// BallotsPage - at most limit ballots after ballot with id after, in order of ids.
+// Next page is after last ballot of page, page shorter than limit is the last one
+func (c *Client) BallotsPage(ctx context.Context, after string, limit int) ([]Ballot, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("votelist: limit %d is not positive", limit)
+	}
+	args := []string{fmt.Sprintf("limit=%d", limit)}
+	if after != "" {
+		args = append(args, "after="+after)
+	}
+	payload, err := c.query(ctx, "votelist", args...)
+	if err != nil {
+		return nil, err
+	}
+	ballots := []Ballot{}
+	return ballots, unmarshal("votelist", payload, &ballots)
+}
+
+// Pending - voters of ballot without vote and turnout of orgs
+func (c *Client) Pending(ctx context.Context, voteid string) (*Pending, error) {
+	payload, err := c.query(ctx, "votepending", voteid)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pending{}
+	return p, unmarshal("votepending", payload, p)
+}
+
+// Status - turnout of ballot against its quorum
+func (c *Client) Status(ctx context.Context, voteid string) (*Status, error) {
+	payload, err := c.query(ctx, "votestatus", voteid)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{}
+	return s, unmarshal("votestatus", payload, s)
+}
+
+// CreateGroup creates group of voters, invoker owns it
+func (c *Client) CreateGroup(ctx context.Context, name string, members []string, description string) (*Group, error) {
+	args := []string{name}
+	for _, m := range members {
+		if strings.Contains(m, "=") { // key=value args are options
+			return nil, fmt.Errorf("groupcreate: member %q contains =", m)
+		}
+		args = append(args, m)
+	}
+	if description != "" {
+		args = append(args, "description="+description)
+	}
+	return c.putgroup(ctx, "groupcreate", args)
+}
+
+// UpdateGroup makes next version of group, owner and admin orgs can. Started ballots keep their voters
+func (c *Client) UpdateGroup(ctx context.Context, name string, u GroupUpdate) (*Group, error) {
+	args := []string{name}
+	for _, m := range u.Members {
+		if strings.Contains(m, "=") {
+			return nil, fmt.Errorf("groupupdate: member %q contains =", m)
+		}
+		args = append(args, m)
+	}
+	for _, m := range u.Add {
+		args = append(args, "add="+m)
+	}
+	for _, m := range u.Remove {
+		args = append(args, "remove="+m)
+	}
+	if u.Description != "" {
+		args = append(args, "description="+u.Description)
+	}
+	return c.putgroup(ctx, "groupupdate", args)
+}
+
+// invoke groupcreate \ groupupdate, group of payload
+func (c *Client) putgroup(ctx context.Context, fn string, args []string) (*Group, error) {
+	payload, _, err := c.invoke(ctx, fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	g := &Group{}
+	return g, unmarshal(fn, payload, g)
+}
+
+// DeleteGroup deletes group, owner and admin orgs can. Returns id of tx
+func (c *Client) DeleteGroup(ctx context.Context, name string) (string, error) {
+	_, tx, err := c.invoke(ctx, "groupdelete", name)
+	return tx, err
+}
+
+// Group with members and version
+func (c *Client) Group(ctx context.Context, name string) (*Group, error) {
+	payload, err := c.query(ctx, "groupinfo", name)
+	if err != nil {
+		return nil, err
+	}
+	g := &Group{}
+	return g, unmarshal("groupinfo", payload, g)
+}
+
+// Groups - all groups in order of names
+func (c *Client) Groups(ctx context.Context) ([]Group, error) {
+	payload, err := c.query(ctx, "grouplist")
+	if err != nil {
+		return nil, err
+	}
+	groups := []Group{}
+	return groups, unmarshal("grouplist", payload, &groups)
+}
+
+// RegisterVoter registers voter bound to MSP and certificate, admin orgs and voter itself can
+func (c *Client) RegisterVoter(ctx context.Context, voter string, d VoterDetails) (*RegisteredVoter, error) {
+	return c.putvoter(ctx, "voterregister", voter, d.options())
+}
+
+// UpdateVoter changes binding or details of registered voter, activate activates deactivated one.
+// Voter itself changes only name and org, binding and activation are for admin orgs
+func (c *Client) UpdateVoter(ctx context.Context, voter string, d VoterDetails, activate bool) (*RegisteredVoter, error) {
+	options := d.options()
+	if activate {
+		options = append(options, "active=true")
+	}
+	return c.putvoter(ctx, "voterupdate", voter, options)
+}
+
+// DeactivateVoter deactivates registered voter: it can't vote and can't be voter of new ballots
+func (c *Client) DeactivateVoter(ctx context.Context, voter string, reason string) (*RegisteredVoter, error) {
+	args := []string{}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	return c.putvoter(ctx, "voterdeactivate", voter, args)
+}
+
+// invoke function of registry on voter, voter of payload
+func (c *Client) putvoter(ctx context.Context, fn string, voter string, args []string) (*RegisteredVoter, error) {
+	payload, _, err := c.invoke(ctx, fn, append([]string{voter}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	r := &RegisteredVoter{}
+	return r, unmarshal(fn, payload, r)
+}
+
+// key=value options of voterregister \ voterupdate
+func (d VoterDetails) options() []string {
+	options := []string{}
+	for _, kv := range [][2]string{{"mspid", d.MSPID}, {"fingerprint", d.Fingerprint}, {"subject", d.Subject}, {"name", d.Name}, {"org", d.Org}} {
+		if kv[1] != "" {
+			options = append(options, kv[0]+"="+kv[1])
+		}
+	}
+	return options
+}
+
+// Voter of registry, id is compared normalized
+func (c *Client) Voter(ctx context.Context, voter string) (*RegisteredVoter, error) {
+	payload, err := c.query(ctx, "voterinfo", voter)
+	if err != nil {
+		return nil, err
+	}
+	r := &RegisteredVoter{}
+	return r, unmarshal("voterinfo", payload, r)
+}
+
+// Voters - registered voters in order of ids
+func (c *Client) Voters(ctx context.Context) ([]RegisteredVoter, error) {
+	payload, err := c.query(ctx, "voterlist")
+	if err != nil {
+		return nil, err
+	}
+	voters := []RegisteredVoter{}
+	return voters, unmarshal("voterlist", payload, &voters)
+}
+
+// Config of chaincode: admin orgs, defaults of ballots, identity mode and applied migrations
+func (c *Client) Config(ctx context.Context) (*Config, error) {
+	payload, err := c.query(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	return cfg, unmarshal("config", payload, cfg)
+}
+
+// CloseBallot closes voting, only proposer can. Returns id of tx
+func (c *Client) CloseBallot(ctx context.Context, voteid string) (string, error) {
+	_, tx, err := c.invoke(ctx, "voteend", voteid)
+	return tx, err
+}
+
+// ExtendBallot requests or approves extension of end date of open voting, proposer and sponsor orgs can.
+// Extension is applied when orgs of Approvals of ballot approve it, one of them other than org of request
+func (c *Client) ExtendBallot(ctx context.Context, voteid string, enddate string, reason string) (*Extension, error) {
+	args := []string{voteid, enddate}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	payload, _, err := c.invoke(ctx, "voteextend", args...)
+	if err != nil {
+		return nil, err
+	}
+	ext := &Extension{}
+	return ext, unmarshal("voteextend", payload, ext)
+}
+
+// CancelBallot cancels draft or open voting with reason, only proposer can. Returns id of tx
+func (c *Client) CancelBallot(ctx context.Context, voteid string, reason string) (string, error) {
+	_, tx, err := c.invoke(ctx, "votecancel", voteid, reason)
+	return tx, err
+}
+
+// ArchiveBallot archives closed or cancelled voting, only proposer can. Returns id of tx
+func (c *Client) ArchiveBallot(ctx context.Context, voteid string, reason string) (string, error) {
+	args := []string{voteid}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	_, tx, err := c.invoke(ctx, "votearchive", args...)
+	return tx, err
+}
+
+//----------------- votes
+
+// CastVote - vote of identity of client, returns receipt
+func (c *Client) CastVote(ctx context.Context, req VoteRequest) (*Receipt, error) {
+	args := []string{req.VoteID, req.Vote, req.Comment}
+	if len(req.Ack) > 0 {
+		args = append(args, strings.Join(req.Ack, ","))
+	}
+
+	payload, _, err := c.invoke(ctx, "vote", args...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Receipt{}
+	return r, unmarshal("vote", payload, r)
+}
+
+// WithdrawVote - withdraw vote of identity of client while voting is open. Returns id of tx
+func (c *Client) WithdrawVote(ctx context.Context, voteid string) (string, error) {
+	_, tx, err := c.invoke(ctx, "votewithdraw", voteid)
+	return tx, err
+}
+
+// VerifyReceipt checks receipt against vote in ledger and its history
+func (c *Client) VerifyReceipt(ctx context.Context, r Receipt) (*ReceiptCheck, error) {
+	b, _ := json.Marshal(r)
+	payload, err := c.query(ctx, "verifyreceipt", string(b))
+	if err != nil {
+		return nil, err
+	}
+	check := &ReceiptCheck{}
+	return check, unmarshal("verifyreceipt", payload, check)
+}
+
+// Proof of inclusion of vote of voter in result, voter is identity of client if empty
+func (c *Client) Proof(ctx context.Context, voteid string, voter string) (*Proof, error) {
+	args := []string{voteid}
+	if voter != "" {
+		args = append(args, voter)
+	}
+	payload, err := c.query(ctx, "voteproof", args...)
+	if err != nil {
+		return nil, err
+	}
+	p := &Proof{}
+	return p, unmarshal("voteproof", payload, p)
+}
+
+// Verify proof offline: leaf is hash of canonical vote and path leads to root
+func (p Proof) Verify() bool {
+	leaf := tally.Leaf([]byte(p.Canonical))
+	if hex.EncodeToString(leaf) != p.LeafHash {
+		return false
+	}
+	root, err := hex.DecodeString(p.MerkleRoot)
+	if err != nil {
+		return false
+	}
+	path := [][]byte{}
+	for _, h := range p.Path {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return false
+		}
+		path = append(path, b)
+	}
+	return tally.Verify(leaf, p.Index, p.Leaves, path, root)
+}
+
+//----------------- results
+
+// key=value options of report
+func (f Filter) options() []string {
+	opts := []string{}
+	for _, kv := range [][2]string{{"org", f.Org}, {"vote", f.Vote}} {
+		if kv[1] != "" {
+			opts = append(opts, kv[0]+"="+kv[1])
+		}
+	}
+	if !f.From.IsZero() {
+		opts = append(opts, "from="+f.From.Format(time.RFC3339))
+	}
+	if !f.To.IsZero() {
+		opts = append(opts, "to="+f.To.Format(time.RFC3339))
+	}
+	if f.Sort != "" {
+		sort := f.Sort
+		if f.Desc {
+			sort = "-" + sort
+		}
+		opts = append(opts, "sort="+sort)
+	}
+	return opts
+}
+
+// Result - report of voting by query, nothing is written in ledger
+func (c *Client) Result(ctx context.Context, voteid string, filter Filter) (*Report, error) {
+	args := append([]string{voteid, "format=json"}, filter.options()...)
+	payload, err := c.query(ctx, "voteresultcsv", args...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	return r, unmarshal("voteresultcsv", payload, r)
+}
+
+// PublishResult counts result by invoke, which writes it in ledger, and returns report
+func (c *Client) PublishResult(ctx context.Context, voteid string, filter Filter) (*Report, error) {
+	args := append([]string{voteid, "format=json"}, filter.options()...)
+	payload, _, err := c.invoke(ctx, "voteresult", args...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	return r, unmarshal("voteresult", payload, r)
+}
+
+// Export report of voting in format of chaincode: json, jsonl, xml, markdown, html, text or csv
+func (c *Client) Export(ctx context.Context, voteid string, format string, filter Filter) ([]byte, error) {
+	args := append([]string{voteid, "format=" + format}, filter.options()...)
+	return c.query(ctx, "voteresultcsv", args...)
+}
+
+// ExportCSV - report of voting as csv
+func (c *Client) ExportCSV(ctx context.Context, voteid string, filter Filter, opts CSVOptions) ([]byte, error) {
+	args := append([]string{voteid, "format=csv"}, filter.options()...)
+	args = append(args, opts.options()...)
+	return c.query(ctx, "voteresultcsv", args...)
+}
+
+// key=value args of csv options
+func (o CSVOptions) options() []string {
+	opts := []string{fmt.Sprintf("header=%t", o.Header), fmt.Sprintf("bom=%t", o.BOM)}
+	if o.Delimiter != "" {
+		opts = append(opts, "delimiter="+o.Delimiter)
+	}
+	if o.LF {
+		opts = append(opts, "lineend=lf")
+	}
+	return opts
+}
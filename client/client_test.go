@@ -0,0 +1,385 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/ioserikov/cc_voting/chaincode"
+	"github.com/ioserikov/cc_voting/wallet"
+	"github.com/ioserikov/cc_voting/wallet/wallettest"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// chaincode names voters by common name of CA of their certificates
+const (
+	voter1 = "ca.org1.example.com"
+	voter2 = "ca.org2.example.com"
+)
+
+// clients of members of Org1 and Org2 to chaincode in MockStub behind MockInvoker
+func testclients(t *testing.T) (*Client, *Client, *cckit.MockStub) {
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stub := cckit.NewMockStub("crocc", new(chaincode.SimpleChaincode))
+	invoker := cckit.NewInvoker().WithChannel("mychannel", stub)
+
+	clients := []*Client{}
+	for _, org := range []struct{ mspid, ca string }{{"Org1MSP", voter1}, {"Org2MSP", voter2}} {
+		mspdir := filepath.Join(dir, org.mspid)
+		if err := wallettest.MSPDir(mspdir, org.ca, "User1"); err != nil {
+			t.Fatal(err)
+		}
+		identity, err := wallet.Load(mspdir, org.mspid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, New(invoker, identity, "mychannel", "crocc"))
+	}
+	return clients[0], clients[1], stub
+}
+
+func TestClient_Voting(t *testing.T) {
+	ctx := context.Background()
+	org1, org2, stub := testclients(t)
+
+	artifact := Artifact{Name: "release.tar.gz", SHA256: strings.Repeat("ab", 32)}
+	_, err := org1.StartBallot(ctx, StartRequest{
+		VoteID: "Vote1", RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: []string{voter1, voter2},
+		Title: "Release 1.0", Tags: []string{"release"}, Artifacts: []Artifact{artifact},
+		Quorum: 75, Orgs: map[string]string{voter2: "Org2MSP"}, MaxExtend: "48h", Sponsors: []string{"Org2MSP"}, Approvals: 2,
+		Endorsers: []string{"Org2MSP"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := org2.Ballot(ctx, "Vote1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.VoteID != "Vote1" || b.Proposer != voter1 || b.Title != "Release 1.0" || len(b.Artifacts) != 1 || b.Artifacts[0] != artifact ||
+		len(b.Endorsers) != 1 {
+		t.Errorf("wrong ballot %+v", b)
+	}
+
+	r, err := org1.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: Yes, Comment: "ship it", Ack: []string{"sha256:" + artifact.Digest()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Voter != voter1 || r.VoteHash == "" {
+		t.Errorf("wrong receipt %+v", r)
+	}
+
+	p, err := org2.Pending(ctx, "Vote1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Pending) != 1 || p.Pending[0] != (PendingVoter{voter2, "Org2MSP"}) || len(p.Orgs) != 2 || p.Orgs[0].Org != "Org1MSP" || p.Orgs[0].Percent != 100 {
+		t.Errorf("wrong pending %+v", p)
+	}
+	st, err := org2.Status(ctx, "Vote1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Quorum != 75 || st.Required != 2 || st.Missing != 1 || st.QuorumMet || st.Turnout.Percent != 50 {
+		t.Errorf("wrong status %+v", st)
+	}
+	if _, err := org2.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: No}); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := org2.Result(ctx, "Vote1", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Decision != "equal" || rep.Counts[Yes] != 1 || rep.Counts[No] != 1 || rep.Turnout.Voted != 2 || rep.Certificate.Leaves != 2 {
+		t.Errorf("wrong report %+v", rep)
+	}
+	if v, _ := stub.GetState("Vote1|result"); v != nil {
+		t.Errorf("Result writes in ledger")
+	}
+
+	if _, err := org2.WithdrawVote(ctx, "Vote1"); err != nil {
+		t.Fatal(err)
+	}
+	// extension is applied by approvals of both orgs
+	ext, err := org1.ExtendBallot(ctx, "Vote1", "02.05.2099", "holidays")
+	if err != nil || ext.AppliedAt != "" || ext.From != "01.05.2099" {
+		t.Fatalf("request of extension %+v %v", ext, err)
+	}
+	if _, err := org2.ExtendBallot(ctx, "Vote1", "04.05.2099", ""); !errors.Is(err, ErrBadArgs) {
+		t.Errorf("extension over maximum: %v", err)
+	}
+	if ext, err = org2.ExtendBallot(ctx, "Vote1", "02.05.2099", ""); err != nil || ext.AppliedAt == "" || len(ext.Approvals) != 2 {
+		t.Fatalf("approval of extension %+v %v", ext, err)
+	}
+	if b, err = org2.Ballot(ctx, "Vote1"); err != nil || b.EndDate != "02.05.2099" || len(b.Extensions) != 1 || b.Extensions[0].Reason != "holidays" {
+		t.Errorf("extended ballot %+v %v", b, err)
+	}
+
+	if _, err := org2.CloseBallot(ctx, "Vote1"); !errors.Is(err, ErrNotProposer) {
+		t.Errorf("close by not proposer: %v", err)
+	}
+	if _, err := org1.CloseBallot(ctx, "Vote1"); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err = org1.PublishResult(ctx, "Vote1", Filter{Org: "Org1MSP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Decision != Yes || len(rep.Votes) != 1 || rep.Votes[0].Comment != "ship it" || rep.Votes[0].Time().IsZero() {
+		t.Errorf("wrong published report %+v", rep)
+	}
+	if v, _ := stub.GetState("Vote1|result"); v == nil {
+		t.Errorf("PublishResult doesn't write result")
+	}
+
+	check, err := org2.VerifyReceipt(ctx, *r)
+	if err != nil || !check.Valid || check.Status != ReceiptCurrent {
+		t.Errorf("receipt check %+v %v", check, err)
+	}
+
+	proof, err := org2.Proof(ctx, "Vote1", voter1)
+	if err != nil || !proof.Verify() || proof.MerkleRoot != rep.Certificate.MerkleRoot {
+		t.Errorf("proof %+v %v", proof, err)
+	}
+
+	ballots, err := org2.Ballots(ctx)
+	if err != nil || len(ballots) != 1 || !ballots[0].Closed {
+		t.Errorf("ballots %+v %v", ballots, err)
+	}
+	page, err := org2.BallotsPage(ctx, "Vote1", 10)
+	if err != nil || len(page) != 0 {
+		t.Errorf("page after Vote1 %+v %v", page, err)
+	}
+
+	// draft is opened by proposer, cancelled with reason and archived
+	draft := StartRequest{VoteID: "Vote2", RepoURL: "https://git.repo", EndDate: "01.06.2099", Voters: []string{voter1, voter2}, Draft: true}
+	if _, err := org1.StartBallot(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org1.CastVote(ctx, VoteRequest{VoteID: "Vote2", Vote: Yes}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("vote in draft: %v", err)
+	}
+	draft.Draft, draft.Reason = false, "reviewed"
+	if _, err := org1.StartBallot(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org2.CancelBallot(ctx, "Vote2", "obsolete"); !errors.Is(err, ErrNotProposer) {
+		t.Errorf("cancel by not proposer: %v", err)
+	}
+	if _, err := org1.CancelBallot(ctx, "Vote2", "obsolete"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org1.ArchiveBallot(ctx, "Vote2", ""); err != nil {
+		t.Fatal(err)
+	}
+	b, err = org2.Ballot(ctx, "Vote2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.CurrentState() != StateArchived || b.Archive == nil || b.Archive.Pruned != 0 || len(b.Transitions) != 4 || b.Transitions[1].Reason != "reviewed" || b.Transitions[2].Reason != "obsolete" {
+		t.Errorf("wrong lifecycle %+v", b)
+	}
+}
+
+func TestClient_Export(t *testing.T) {
+	ctx := context.Background()
+	org1, org2, _ := testclients(t)
+
+	if _, err := org1.StartBallot(ctx, StartRequest{VoteID: "Vote1", RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: []string{voter1, voter2}}); err != nil {
+		t.Fatal(err)
+	}
+	org1.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: Yes, Comment: "ok, ship it"})
+	org2.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: Neutral})
+
+	csv, err := org2.ExportCSV(ctx, "Vote1", Filter{Sort: "vote", Desc: true}, CSVOptions{Header: true, Delimiter: "comma", LF: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "voteid,") || !strings.Contains(lines[1], `,yes,`) || !strings.Contains(lines[1], `"ok, ship it"`) {
+		t.Errorf("csv:\n%s", csv)
+	}
+
+	csv, err = org2.ExportCSV(ctx, "Vote1", Filter{Vote: Neutral}, CSVOptions{})
+	if err != nil || strings.Count(string(csv), "\r\n") != 1 || !strings.Contains(string(csv), ";neutral;") {
+		t.Errorf("csv of chaincode defaults: %q %v", csv, err)
+	}
+
+	md, err := org2.Export(ctx, "Vote1", "markdown", Filter{})
+	if err != nil || !strings.Contains(string(md), "|") {
+		t.Errorf("markdown: %s %v", md, err)
+	}
+
+	org1.CloseBallot(ctx, "Vote1")
+	rows, next, err := org2.Participation(ctx, "", 1)
+	if err != nil || len(rows) != 1 || rows[0].Voter != voter1 || rows[0].Voted != 1 || next != voter1 {
+		t.Errorf("participation %+v %q %v", rows, next, err)
+	}
+	if rows, next, err := org2.Participation(ctx, next, 1); err != nil || len(rows) != 1 || rows[0].Voter != voter2 || next != "" {
+		t.Errorf("participation after %s: %+v %q %v", voter1, rows, next, err)
+	}
+	if rows, _, err := org2.OrgAgreement(ctx, "", 0); err != nil || len(rows) != 1 || rows[0].Org1 != "Org1MSP" || rows[0].Agreed != 0 {
+		t.Errorf("agreement of orgs %+v %v", rows, err)
+	}
+	csv, err = org2.AnalyticsCSV(ctx, ViewDecision, CSVOptions{Header: true, LF: true})
+	if err != nil || string(csv) != "voter;decided;agreed;percent\n"+voter1+";1;1;100.00\n"+voter2+";1;0;0.00\n" {
+		t.Errorf("csv of decisions: %q %v", csv, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	org1, org2, stub := testclients(t)
+
+	if _, err := org1.StartBallot(ctx, StartRequest{VoteID: "Vote1", RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: []string{voter1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := org1.Ballot(ctx, "NoVote")
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeBallotNotFound || e.Status != 404 || e.Details["voteid"] != "NoVote" {
+		t.Errorf("expected BALLOT_NOT_FOUND, got %v", err)
+	}
+
+	if _, err := org2.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: Yes}); !errors.Is(err, ErrNotEligible) {
+		t.Errorf("expected NOT_ELIGIBLE, got %v", err)
+	}
+	if _, err := org1.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: "maybe"}); !errors.Is(err, ErrBadArgs) {
+		t.Errorf("expected BAD_ARGS, got %v", err)
+	}
+	if _, err := org1.WithdrawVote(ctx, "Vote1"); !errors.Is(err, ErrVoteNotFound) {
+		t.Errorf("expected VOTE_NOT_FOUND, got %v", err)
+	}
+
+	// checked before call
+	if _, err := org1.StartBallot(ctx, StartRequest{VoteID: "Vote2", RepoURL: "https://git.repo", EndDate: "01.05.2099"}); err == nil {
+		t.Errorf("ballot without voters is started")
+	}
+	if _, err := org1.StartBallot(ctx, StartRequest{VoteID: "Vote2", RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: []string{"title=x"}}); err == nil {
+		t.Errorf("voter with = is taken")
+	}
+
+	// client of queries only
+	querier := cckit.NewInvoker().WithChannel("mychannel", stub)
+	ro := NewQuerier(querier, org1.Identity(), "mychannel", "crocc")
+	if _, err := ro.Ballot(ctx, "Vote1"); err != nil {
+		t.Errorf("query of querier client: %v", err)
+	}
+	if _, err := ro.CloseBallot(ctx, "Vote1"); err == nil {
+		t.Errorf("querier client invokes")
+	}
+}
+
+func TestClient_Config(t *testing.T) {
+	ctx := context.Background()
+	org1, _, stub := testclients(t)
+
+	if res := stub.MockInit("1", argsbytes([]string{"init", "admin=Org2MSP", "tally=majority:60"})); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	cfg, err := org1.Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TallyRule != TallyMajority || cfg.Majority != 60 || cfg.IdentityMode != IdentityIssuer || len(cfg.Admins) != 1 || len(cfg.Migrations) != cfg.Version {
+		t.Errorf("wrong config %+v", cfg)
+	}
+}
+
+func TestClient_Groups(t *testing.T) {
+	ctx := context.Background()
+	org1, org2, _ := testclients(t)
+
+	if _, err := org1.CreateGroup(ctx, "board", []string{voter1}, "board of directors"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org1.CreateGroup(ctx, "board", []string{voter2}, ""); !errors.Is(err, ErrGroupExists) {
+		t.Errorf("create of existing group: %v", err)
+	}
+	if _, err := org2.UpdateGroup(ctx, "board", GroupUpdate{Add: []string{voter2}}); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("update by not owner: %v", err)
+	}
+	g, err := org1.UpdateGroup(ctx, "board", GroupUpdate{Add: []string{voter2}})
+	if err != nil || g.Version != 2 || len(g.Members) != 2 {
+		t.Fatalf("updated group %+v: %v", g, err)
+	}
+
+	if _, err := org1.StartBallot(ctx, StartRequest{VoteID: "Vote1", RepoURL: "https://git.repo", EndDate: "01.05.2099", Groups: []string{"board"}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := org2.Ballot(ctx, "Vote1")
+	if err != nil || len(b.Voters) != 2 || b.Groups[0] != (GroupRef{"board", 2}) {
+		t.Errorf("ballot of group %+v: %v", b, err)
+	}
+
+	if _, err := org1.DeleteGroup(ctx, "board"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org2.Group(ctx, "board"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("deleted group: %v", err)
+	}
+	if groups, err := org2.Groups(ctx); err != nil || len(groups) != 0 {
+		t.Errorf("groups %+v: %v", groups, err)
+	}
+}
+
+func TestClient_Registry(t *testing.T) {
+	ctx := context.Background()
+	org1, org2, stub := testclients(t)
+
+	if _, err := org1.RegisterVoter(ctx, voter2, VoterDetails{MSPID: "Org2MSP", Subject: "User1"}); !errors.Is(err, ErrNotRegistrar) {
+		t.Errorf("register of voter of other MSP: %v", err)
+	}
+	r, err := org2.RegisterVoter(ctx, voter2, VoterDetails{MSPID: "Org2MSP", Subject: "User1", Name: "Bob"})
+	if err != nil || !r.Active || r.RegisteredBy != voter2 {
+		t.Fatalf("registered voter %+v: %v", r, err)
+	}
+	if _, err := org2.DeactivateVoter(ctx, voter2, "left board"); err != nil {
+		t.Fatal(err)
+	}
+	err = startballot(ctx, org1, "Vote1", voter1, voter2)
+	if !errors.Is(err, ErrVoterInactive) {
+		t.Errorf("ballot of deactivated voter: %v", err)
+	}
+	if _, err := org2.UpdateVoter(ctx, voter2, VoterDetails{Org: "Org Two"}, true); !errors.Is(err, ErrNotRegistrar) {
+		t.Errorf("activation by voter itself: %v", err)
+	}
+	if res := stub.MockInit("1", argsbytes([]string{"init", "admin=Org1MSP"})); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	if r, err := org1.UpdateVoter(ctx, voter2, VoterDetails{Org: "Org Two"}, true); err != nil || !r.Active || r.Org != "Org Two" {
+		t.Fatalf("activated voter %+v: %v", r, err)
+	}
+	if err := startballot(ctx, org1, "Vote1", voter1, voter2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := org2.CastVote(ctx, VoteRequest{VoteID: "Vote1", Vote: Yes}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := org1.Voter(ctx, voter1); !errors.Is(err, ErrVoterNotFound) {
+		t.Errorf("voter out of registry: %v", err)
+	}
+	if voters, err := org1.Voters(ctx); err != nil || len(voters) != 1 || voters[0].Name != "Bob" {
+		t.Errorf("registry %+v: %v", voters, err)
+	}
+}
+
+// StartBallot of voters with the only required args
+func startballot(ctx context.Context, c *Client, voteid string, voters ...string) error {
+	_, err := c.StartBallot(ctx, StartRequest{VoteID: voteid, RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: voters})
+	return err
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// codes of errors of chaincode
+const (
+	CodeBadArgs         = "BAD_ARGS"
+	CodeUnknownFunction = "UNKNOWN_FUNCTION"
+	CodeDuplicateVoters = "DUPLICATE_VOTERS"
+	CodeBadIdentity     = "BAD_IDENTITY"
+	CodeNotEligible     = "NOT_ELIGIBLE"
+	CodeNotAcknowledged = "NOT_ACKNOWLEDGED"
+	CodeNotProposer     = "NOT_PROPOSER"
+	CodeNotApprover     = "NOT_APPROVER"
+	CodeNotOwner        = "NOT_OWNER"
+	CodeNotRegistrar    = "NOT_REGISTRAR"
+	CodeVoterInactive   = "VOTER_INACTIVE"
+	CodeCertMismatch    = "CERT_MISMATCH"
+	CodeBallotClosed    = "BALLOT_CLOSED"
+	CodeInvalidState    = "INVALID_STATE"
+	CodeDeadlinePassed  = "DEADLINE_PASSED"
+	CodeBallotNotFound  = "BALLOT_NOT_FOUND"
+	CodeGroupNotFound   = "GROUP_NOT_FOUND"
+	CodeGroupExists     = "GROUP_EXISTS"
+	CodeVoterNotFound   = "VOTER_NOT_FOUND"
+	CodeVoterExists     = "VOTER_EXISTS"
+	CodeVoteNotFound    = "VOTE_NOT_FOUND"
+	CodeKeyNotFound     = "KEY_NOT_FOUND"
+	CodeCountFailed     = "COUNT_FAILED"
+	CodeLedger          = "LEDGER_ERROR"
+	CodeInternal        = "INTERNAL"
+	CodeUnknown         = "UNKNOWN" // error response without error of catalogue
+)
+
+// errors to match with errors.Is, by code
+var (
+	ErrBadArgs         = &Error{Code: CodeBadArgs}
+	ErrNotEligible     = &Error{Code: CodeNotEligible}
+	ErrNotAcknowledged = &Error{Code: CodeNotAcknowledged}
+	ErrNotProposer     = &Error{Code: CodeNotProposer}
+	ErrNotApprover     = &Error{Code: CodeNotApprover}
+	ErrNotOwner        = &Error{Code: CodeNotOwner}
+	ErrNotRegistrar    = &Error{Code: CodeNotRegistrar}
+	ErrVoterInactive   = &Error{Code: CodeVoterInactive}
+	ErrCertMismatch    = &Error{Code: CodeCertMismatch}
+	ErrBallotClosed    = &Error{Code: CodeBallotClosed}
+	ErrInvalidState    = &Error{Code: CodeInvalidState}
+	ErrDeadlinePassed  = &Error{Code: CodeDeadlinePassed}
+	ErrBallotNotFound  = &Error{Code: CodeBallotNotFound}
+	ErrGroupNotFound   = &Error{Code: CodeGroupNotFound}
+	ErrGroupExists     = &Error{Code: CodeGroupExists}
+	ErrVoterNotFound   = &Error{Code: CodeVoterNotFound}
+	ErrVoterExists     = &Error{Code: CodeVoterExists}
+	ErrVoteNotFound    = &Error{Code: CodeVoteNotFound}
+)
+
+// Error returned by chaincode - stable code, status as of HTTP, message and ids of ballot, voter...
+type Error struct {
+	Code    string            `json:"code"`
+	Status  int32             `json:"status"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Is - errors of the same code are the same
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// error of response with error status, nil for success
+func responseerror(status int32, message string, payload []byte) error {
+	if status < shim.ERRORTHRESHOLD {
+		return nil
+	}
+	e := &Error{}
+	if err := json.Unmarshal(payload, e); err != nil || e.Code == "" {
+		return &Error{Code: CodeUnknown, Status: status, Message: message}
+	}
+	return e
+}
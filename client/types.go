@@ -0,0 +1,417 @@
+package client
+
+import (
+	"time"
+
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// options of vote
+const (
+	Yes     = "yes"
+	No      = "no"
+	Neutral = "neutral"
+)
+
+// states of ballot
+const (
+	StateDraft     = "draft"
+	StateOpen      = "open"
+	StateClosed    = "closed"
+	StateCancelled = "cancelled"
+	StateArchived  = "archived"
+)
+
+// StatusScheduled - status of open ballot before its start date
+const StatusScheduled = "scheduled"
+
+// tally rules and identity modes of Config
+const (
+	TallyPlurality  = "plurality"
+	TallyMajority   = "majority"
+	IdentityIssuer  = "issuer"  // voter is common name of issuer of certificate
+	IdentitySubject = "subject" // voter is common name of subject of certificate
+)
+
+// Artifact of proposal, pinned by sha256 of content or git commit
+type Artifact struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"` // hex of sha256 of content
+	Commit string `json:"commit,omitempty"` // hash of git commit
+}
+
+// Digest which voters acknowledge - sha256 or commit
+func (a Artifact) Digest() string {
+	if a.SHA256 != "" {
+		return a.SHA256
+	}
+	return a.Commit
+}
+
+// arg of votestart: name@sha256:digest or name@git:commit
+func (a Artifact) arg() string {
+	if a.SHA256 != "" {
+		return a.Name + "@sha256:" + a.SHA256
+	}
+	return a.Name + "@git:" + a.Commit
+}
+
+// Ballot - voting with metadata and voters, as voteinfo and votelist return it
+type Ballot struct {
+	VoteID      string            `json:"voteid"`
+	RepoURL     string            `json:"repourl"`
+	EndDate     string            `json:"enddate"`
+	StartDate   string            `json:"startdate,omitempty"` // opening of vote
+	Voters      []string          `json:"voters"`
+	Groups      []GroupRef        `json:"groups,omitempty"` // groups which members are in voters, at versions of start
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Proposer    string            `json:"proposer,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Artifacts   []Artifact        `json:"artifacts,omitempty"`
+	RequireAck  bool              `json:"requireack,omitempty"`
+	Quorum      float64           `json:"quorum,omitempty"`    // percent of voters to vote
+	Orgs        map[string]string `json:"orgs,omitempty"`      // MSP ID by voter
+	Majority    float64           `json:"majority,omitempty"`  // percent of counted votes for option of decision
+	Runoff      string            `json:"runoff,omitempty"`    // duration after close or end date of runoff
+	Options     []string          `json:"options,omitempty"`   // options of vote of runoff, all if empty
+	Round       int               `json:"round,omitempty"`     // round of runoff, 0 for first round
+	PrevRound   string            `json:"prevround,omitempty"` // ballot of previous round
+	NextRound   string            `json:"nextround,omitempty"` // runoff opened by close
+	MaxExtend   string            `json:"maxextend,omitempty"` // duration, maximum total extension of end date
+	Sponsors    []string          `json:"sponsors,omitempty"`  // MSP IDs of orgs which approve extensions
+	Approvals   int               `json:"approvals,omitempty"` // orgs to approve extension, 1 if 0
+	Endorsers   []string          `json:"endorsers,omitempty"` // MSP IDs of orgs which endorse amendments with org of creator
+	Extensions  []Extension       `json:"extensions,omitempty"`
+	Closed      bool              `json:"closed,omitempty"`
+	ClosedAt    string            `json:"closedat,omitempty"` // RFC3339
+	State       string            `json:"state,omitempty"`    // empty for ballots started before states
+	Transitions []Transition      `json:"transitions,omitempty"`
+	Archive     *Archive          `json:"archive,omitempty"` // record of votes of archived ballot
+}
+
+// CurrentState of ballot, ballots started before states are open or closed
+func (b Ballot) CurrentState() string {
+	switch {
+	case b.State != "":
+		return b.State
+	case b.Closed:
+		return StateClosed
+	}
+	return StateOpen
+}
+
+// StatusAt - current state, StatusScheduled for open ballot before its start date
+func (b Ballot) StatusAt(now time.Time) string {
+	state := b.CurrentState()
+	if opening, ok := tally.Opening(b.StartDate); ok && state == StateOpen && now.Before(opening) {
+		return StatusScheduled
+	}
+	return state
+}
+
+// Extension - request of extension of end date, as voteextend returns it and ballot logs it
+type Extension struct {
+	From      string     `json:"from"`    // end date before request
+	EndDate   string     `json:"enddate"` // requested end date
+	By        string     `json:"by"`
+	Org       string     `json:"org"`
+	At        string     `json:"at"`
+	Reason    string     `json:"reason,omitempty"`
+	Approvals []Approval `json:"approvals"`
+	AppliedAt string     `json:"appliedat,omitempty"` // empty while extension waits for approvals
+}
+
+// Archive - compact record of archived ballot, its vote keys are deleted from state
+type Archive struct {
+	Counts     map[string]int `json:"counts"`
+	Decision   string         `json:"decision"`
+	Algorithm  string         `json:"algorithm"`
+	MerkleRoot string         `json:"merkleroot"`
+	Votes      []ArchivedVote `json:"votes"` // in order of leaves
+	Pruned     int            `json:"pruned"`
+}
+
+// ArchivedVote - counted vote in record of archived ballot, without comment
+type ArchivedVote struct {
+	Voter string `json:"voter"`
+	Org   string `json:"org,omitempty"`
+	Vote  string `json:"vote"`
+	TxID  string `json:"txid"`
+}
+
+// Approval of extension by member of org
+type Approval struct {
+	By  string `json:"by"`
+	Org string `json:"org"`
+	At  string `json:"at"`
+}
+
+// Transition of ballot to state
+type Transition struct {
+	From   string `json:"from,omitempty"` // empty for start
+	To     string `json:"to"`
+	By     string `json:"by"`
+	Org    string `json:"org,omitempty"` // MSP ID of invoker
+	At     string `json:"at"`            // RFC3339
+	Reason string `json:"reason,omitempty"`
+}
+
+// StartRequest - args of StartBallot
+type StartRequest struct {
+	VoteID      string
+	RepoURL     string
+	EndDate     string
+	StartDate   string // opening of vote, dd.mm.yyyy[.hh.mm] or RFC3339; immediately if empty
+	Voters      []string
+	Groups      []string // names of voter groups, their members come before Voters
+	Title       string
+	Description string
+	Proposer    string // invoker, the only one allowed
+	Tags        []string
+	Artifacts   []Artifact
+	RequireAck  bool              // voters are to acknowledge digests of all artifacts
+	Quorum      float64           // percent of voters to vote, no quorum if 0
+	Orgs        map[string]string // MSP ID by voter, for turnout of orgs before they vote
+	Majority    float64           // percent of counted votes for option of decision
+	Runoff      string            // duration after close or end date: close without decision opens runoff of yes and no
+	MaxExtend   string            // duration, maximum total extension of end date by ExtendBallot, no extension if empty
+	Sponsors    []string          // MSP IDs of orgs which approve extensions with proposer
+	Approvals   int               // orgs to approve extension, 1 if 0
+	Endorsers   []string          // MSP IDs of orgs which endorse amendments of ballot with org of creator
+	Draft       bool              // start draft, StartBallot of proposer with the same id opens it
+	Reason      string            // reason of start in transition log
+}
+
+// Group - named group of voters, StartRequest.Groups adds its members to voters
+type Group struct {
+	Name        string   `json:"name"`
+	Version     int      `json:"version"` // 1 on create, every update increments it
+	Members     []string `json:"members"`
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner"` // with admin orgs of Config it updates and deletes group
+	OwnerOrg    string   `json:"ownerorg"`
+	CreatedAt   string   `json:"createdat"`
+	UpdatedBy   string   `json:"updatedby,omitempty"`
+	UpdatedAt   string   `json:"updatedat,omitempty"`
+}
+
+// GroupRef - version of group which members ballot took at start
+type GroupRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// GroupUpdate - changes of UpdateGroup: Members replace members, Add and Remove change them
+type GroupUpdate struct {
+	Members     []string
+	Add         []string
+	Remove      []string
+	Description string
+}
+
+// RegisteredVoter - voter of registry bound to MSP and certificate
+type RegisteredVoter struct {
+	ID           string `json:"id"` // normalized, name of voter in ballots
+	MSPID        string `json:"mspid"`
+	Fingerprint  string `json:"fingerprint,omitempty"` // sha256 of DER of certificate, hex
+	Subject      string `json:"subject,omitempty"`     // common name of subject of certificate
+	Name         string `json:"name,omitempty"`
+	Org          string `json:"org,omitempty"`
+	Active       bool   `json:"active"`
+	Reason       string `json:"reason,omitempty"` // reason of deactivation
+	RegisteredBy string `json:"registeredby"`
+	RegisteredAt string `json:"registeredat"`
+	UpdatedBy    string `json:"updatedby,omitempty"`
+	UpdatedAt    string `json:"updatedat,omitempty"`
+}
+
+// VoterDetails - binding and details of RegisterVoter and UpdateVoter, empty fields are not set
+type VoterDetails struct {
+	MSPID       string
+	Fingerprint string // sha256 of certificate, hex
+	Subject     string // common name of subject of certificate
+	Name        string
+	Org         string
+}
+
+// VoteRequest - args of CastVote
+type VoteRequest struct {
+	VoteID  string
+	Vote    string // Yes \ No \ Neutral
+	Comment string
+	Ack     []string // digests of reviewed artifacts
+}
+
+// Vote of voter as it is counted in report
+type Vote struct {
+	Voter     string   `json:"voter"`
+	Org       string   `json:"org"` // MSP ID of voter
+	Vote      string   `json:"vote"`
+	Comment   string   `json:"comment"`
+	Ack       []string `json:"ack,omitempty"`
+	TxID      string   `json:"txid"`
+	Timestamp string   `json:"timestamp"` // RFC3339, empty for votes written before it
+}
+
+// Time of vote, zero for votes written without it
+func (v Vote) Time() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, v.Timestamp)
+	return t
+}
+
+// Turnout of voting
+type Turnout struct {
+	Registered int     `json:"registered"` // voters in list of vote
+	Voted      int     `json:"voted"`      // voters with vote in ledger
+	Percent    float64 `json:"percent"`
+}
+
+// PendingVoter - registered voter without vote
+type PendingVoter struct {
+	Voter string `json:"voter"`
+	Org   string `json:"org"` // "unknown" if voter has no org
+}
+
+// OrgTurnout - turnout of voters of org
+type OrgTurnout struct {
+	Org string `json:"org"`
+	Turnout
+}
+
+// Pending - voters without vote and turnout of orgs, answer of votepending
+type Pending struct {
+	VoteID  string         `json:"voteid"`
+	Pending []PendingVoter `json:"pending"`
+	Orgs    []OrgTurnout   `json:"orgs"`
+	Turnout Turnout        `json:"turnout"`
+}
+
+// Status - turnout of ballot against its quorum, answer of votestatus
+type Status struct {
+	VoteID    string  `json:"voteid"`
+	Status    string  `json:"status"` // state of ballot, StatusScheduled before start date
+	StartDate string  `json:"startdate,omitempty"`
+	EndDate   string  `json:"enddate"`
+	Turnout   Turnout `json:"turnout"`
+	Quorum    float64 `json:"quorum"`   // percent, 0 - no quorum
+	Required  int     `json:"required"` // votes for quorum
+	Missing   int     `json:"missing"`  // votes to reach quorum
+	QuorumMet bool    `json:"quorummet"`
+}
+
+// Config - configuration of chaincode written by Init, answer of config
+type Config struct {
+	Version      int         `json:"version"`            // schema version of state
+	Admins       []string    `json:"admins,omitempty"`   // MSP IDs of admin orgs, they approve extensions
+	Options      []string    `json:"options,omitempty"`  // default options of vote, all if empty
+	TallyRule    string      `json:"tallyrule"`          // TallyPlurality \ TallyMajority
+	Majority     float64     `json:"majority,omitempty"` // percent of TallyMajority
+	IdentityMode string      `json:"identitymode"`       // IdentityIssuer \ IdentitySubject
+	MaxVoters    int         `json:"maxvoters,omitempty"`
+	Registry     bool        `json:"registry,omitempty"` // voters of ballots are to be registered
+	Migrations   []Migration `json:"migrations,omitempty"`
+}
+
+// Migration of state applied by Init
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Records int    `json:"records"` // keys rewritten
+	At      string `json:"at"`
+}
+
+// Certificate of result - Merkle root over all counted votes
+type Certificate struct {
+	Algorithm  string   `json:"algorithm"`
+	MerkleRoot string   `json:"merkleroot"` // hex
+	Leaves     int      `json:"leaves"`
+	TxIDs      []string `json:"txids"` // in order of leaves
+}
+
+// Report of voting
+type Report struct {
+	VoteID      string         `json:"voteid"`
+	RepoURL     string         `json:"repourl"`
+	EndDate     string         `json:"enddate"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Proposer    string         `json:"proposer,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Artifacts   []Artifact     `json:"artifacts,omitempty"`
+	Counts      map[string]int `json:"counts"` // votes per option
+	Turnout     Turnout        `json:"turnout"`
+	Decision    string         `json:"decision"` // yes \ no \ equal
+	Decided     bool           `json:"decided"`  // decision is not equal and reaches majority of ballot
+	Votes       []Vote         `json:"votes"`
+	Abstained   []string       `json:"abstained"` // voters without vote
+	Certificate Certificate    `json:"certificate"`
+	Rounds      []Round        `json:"rounds,omitempty"` // rounds of ballot with runoff, from the first one
+}
+
+// Round of ballot with runoff
+type Round struct {
+	VoteID   string         `json:"voteid"`
+	Round    int            `json:"round"`
+	Options  []string       `json:"options"`
+	Counts   map[string]int `json:"counts"`
+	Decision string         `json:"decision"`
+	Decided  bool           `json:"decided"` // decision is not equal and reaches majority
+	Closed   bool           `json:"closed"`
+}
+
+// Filter and order of entries of report. Counts, decision and certificate are always of all votes
+type Filter struct {
+	Org  string    // MSP ID of voter
+	Vote string    // Yes \ No \ Neutral
+	From time.Time // votes cast not before
+	To   time.Time // votes cast not after
+	Sort string    // org \ vote \ time
+	Desc bool      // reverse order of sort
+}
+
+// CSVOptions of ExportCSV, zero value is csv of chaincode: no header, semicolon, crlf
+type CSVOptions struct {
+	Header    bool
+	BOM       bool   // utf-8 byte order mark
+	Delimiter string // comma \ semicolon \ tab or one symbol, semicolon by default
+	LF        bool   // lines end with lf, crlf of RFC 4180 by default
+}
+
+// Receipt of vote, returned by CastVote
+type Receipt struct {
+	VoteID    string `json:"voteid"`
+	Voter     string `json:"voter"`
+	TxID      string `json:"txid"`
+	Timestamp string `json:"timestamp"`
+	VoteHash  string `json:"votehash"` // hex of Merkle leaf hash of canonical vote
+}
+
+// statuses of ReceiptCheck
+const (
+	ReceiptCurrent     = "current"     // vote in state is the vote of receipt
+	ReceiptOverwritten = "overwritten" // vote of receipt is in history, but was changed later
+	ReceiptMismatch    = "mismatch"    // tx of receipt wrote other vote than in receipt
+	ReceiptNotFound    = "notfound"    // no tx of receipt for vote key
+	ReceiptArchived    = "archived"    // vote of receipt is counted in record of archived ballot
+)
+
+// ReceiptCheck - answer of VerifyReceipt
+type ReceiptCheck struct {
+	Valid   bool     `json:"valid"`
+	Status  string   `json:"status"`
+	Current *Receipt `json:"current"` // receipt of vote in state, nil if no vote
+}
+
+// Proof of inclusion of vote in Merkle root of result
+type Proof struct {
+	VoteID     string   `json:"voteid"`
+	Voter      string   `json:"voter"`
+	TxID       string   `json:"txid"`
+	Canonical  string   `json:"canonical"` // canonical encoding of vote - leaf data
+	LeafHash   string   `json:"leafhash"`
+	Index      int      `json:"index"`
+	Leaves     int      `json:"leaves"`
+	Path       []string `json:"path"`
+	MerkleRoot string   `json:"merkleroot"`
+}
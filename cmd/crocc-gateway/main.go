@@ -0,0 +1,104 @@
+// crocc-gateway - HTTP/REST gateway of cc_voting chaincode.
+//
+//	crocc-gateway -wallet dir -tokens file [-listen :8080] [-tls-cert file -tls-key file] [-channel mychannel] [-cc crocc] [-config file]
+//
+// Wallet directory holds MSP directories of identities as <MSP ID>/<name>/. Tokens file binds bearer tokens
+// of callers to them, as json {"<token>": "<MSP ID>/<name>"}: request calls from identity of its token,
+// requests without known token are rejected, gateway does not start without tokens. API is described at /openapi.json
+//
+// Client of hlf-sdk-go is not in vendor, so crocc-gateway built from this tree is a stub: it exits with error
+// of connection until newinvoker is set to Invoker of a Fabric network.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/ioserikov/cc_voting/gateway"
+	"github.com/ioserikov/cc_voting/wallet"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// invoker of Fabric network by config of connection - to be set in init of file built with client of hlf-sdk-go,
+// which is not in vendor yet
+var newinvoker = noinvoker
+
+// crocc-gateway connects by noinvoker, init of file with client of hlf-sdk-go resets it with newinvoker
+var stub = true
+
+func main() {
+	listen := flag.String("listen", ":8080", "address of http server")
+	dir := flag.String("wallet", "", "wallet directory: <MSP ID>/<name>/ MSP directories")
+	tokensfile := flag.String("tokens", "", `bearer tokens of callers: json {"<token>": "<MSP ID>/<name>"}`)
+	tlscert := flag.String("tls-cert", "", "certificate of https server, plain http if empty")
+	tlskey := flag.String("tls-key", "", "key of certificate of https server")
+	channel := flag.String("channel", "mychannel", "channel of chaincode")
+	chaincode := flag.String("cc", "crocc", "name of chaincode")
+	config := flag.String("config", "", "config of connection to Fabric network")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: crocc-gateway -wallet dir -tokens file [flags]\n")
+		flag.PrintDefaults()
+		if stub {
+			fmt.Fprintf(flag.CommandLine.Output(), "\ncrocc-gateway is a stub: it is built without Fabric client of hlf-sdk-go and can't connect to network\n")
+		}
+	}
+	flag.Parse()
+
+	if *dir == "" || *tokensfile == "" {
+		fmt.Fprintln(os.Stderr, "crocc-gateway: -wallet and -tokens are required, gateway does not serve unauthenticated callers")
+		os.Exit(2)
+	}
+
+	loaded, err := wallet.Open(*dir)
+	if err != nil {
+		log.Fatalf("crocc-gateway: %s", err)
+	}
+	identities := map[string]msp.SigningIdentity{}
+	for name, i := range loaded {
+		identities[name] = i
+	}
+	tokens, err := readtokens(*tokensfile)
+	if err != nil {
+		log.Fatalf("crocc-gateway: %s", err)
+	}
+
+	invoker, err := newinvoker(*config)
+	if err != nil {
+		log.Fatalf("crocc-gateway: %s", err)
+	}
+	g, err := gateway.New(invoker, *channel, *chaincode, identities, tokens)
+	if err != nil {
+		log.Fatalf("crocc-gateway: %s", err)
+	}
+
+	log.Printf("crocc-gateway: %d identities, %d tokens, listening on %s", len(identities), len(tokens), *listen)
+	if *tlscert != "" {
+		log.Fatal(http.ListenAndServeTLS(*listen, *tlscert, *tlskey, g))
+	}
+	log.Print("crocc-gateway: serving plain http, tokens of callers are not protected in transit - set -tls-cert and -tls-key")
+	log.Fatal(http.ListenAndServe(*listen, g))
+}
+
+// tokens of callers of file, identity of wallet by bearer token
+func readtokens(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens := map[string]string{}
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return nil, fmt.Errorf("tokens file %s: %s", path, err)
+	}
+	return tokens, nil
+}
+
+// error of connection when gateway is built without client of hlf-sdk-go
+func noinvoker(config string) (api.Invoker, error) {
+	return nil, fmt.Errorf("crocc-gateway is a stub built without Fabric client of hlf-sdk-go, add it to vendor and set newinvoker")
+}
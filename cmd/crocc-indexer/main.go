@@ -0,0 +1,80 @@
+// crocc-indexer - local projection of ballots, votes and tallies of cc_voting chaincode.
+//
+//	crocc-indexer -store index.db [-listen :8081] [-channel mychannel] [-cc crocc] [-config file]
+//
+// Indexer subscribes on blocks of channel by DeliverClient of hlf-sdk-go from block after the last one
+// in store, applies chaincode events and serves queries: /status, /ballots, /ballots/{id},
+// /ballots/{id}/votes, /ballots/{id}/tally
+//
+// With -hooks it notifies webhooks of json file of hooks: [{"url": ..., "secret": ..., "types": [...], "ballots": [...]}]
+// about opened ballots, votes, deadlines in 24 hours and final results, deliveries wait for retries in -queue file
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ioserikov/cc_voting/indexer"
+	"github.com/ioserikov/cc_voting/notifier"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// deliver client of Fabric network by config of connection - to be set in init of file built with client
+// of hlf-sdk-go, which is not in vendor yet
+var newdeliver = nodeliver
+
+func main() {
+	path := flag.String("store", "index.db", "bbolt file of projection")
+	listen := flag.String("listen", ":8081", "address of http server of queries")
+	channel := flag.String("channel", "mychannel", "channel of chaincode")
+	chaincode := flag.String("cc", "crocc", "name of chaincode")
+	config := flag.String("config", "", "config of connection to Fabric network")
+	hooks := flag.String("hooks", "", "json file of webhooks, no notifications if empty")
+	queue := flag.String("queue", "notify.json", "file of queue of webhook deliveries")
+	interval := flag.Duration("notify-interval", 10*time.Second, "interval of deliveries and reminders of deadlines")
+	flag.Parse()
+
+	store, err := indexer.Open(*path)
+	if err != nil {
+		log.Fatalf("crocc-indexer: %s", err)
+	}
+	defer store.Close()
+	deliver, err := newdeliver(*config)
+	if err != nil {
+		log.Fatalf("crocc-indexer: %s", err)
+	}
+
+	ix := indexer.New(deliver, *channel, *chaincode, store)
+
+	if *hooks != "" {
+		list, err := notifier.LoadHooks(*hooks)
+		if err != nil {
+			log.Fatalf("crocc-indexer: %s", err)
+		}
+		n, err := notifier.New(list, *queue, store.Ballots)
+		if err != nil {
+			log.Fatalf("crocc-indexer: %s", err)
+		}
+		ix.Observe(n.Observe)
+		go func() {
+			log.Fatalf("crocc-indexer: notifier stopped: %s", n.Run(context.Background(), *interval))
+		}()
+	}
+
+	go func() {
+		log.Fatal(http.ListenAndServe(*listen, indexer.Handler(store)))
+	}()
+
+	log.Printf("crocc-indexer: indexing %s/%s from block %d", *channel, *chaincode, store.Next())
+	err = ix.Run(context.Background())
+	log.Fatalf("crocc-indexer: stopped at block %d: %s", store.Next(), err)
+}
+
+// error of connection when indexer is built without client of hlf-sdk-go
+func nodeliver(config string) (api.DeliverClient, error) {
+	return nil, fmt.Errorf("crocc-indexer is built without Fabric client of hlf-sdk-go, add it to vendor and set newdeliver")
+}
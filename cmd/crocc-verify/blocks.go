@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// struct for writes of one valid tx to namespace of chaincode
+type txwrites struct {
+	Block     uint64
+	TxID      string
+	Timestamp string // tx time, RFC3339, as chaincode writes it in votes
+	Writes    []*kvrwset.KVWrite
+}
+
+// read blocks from every file of dir - one marshaled block per file, as peer channel fetch writes them.
+// Blocks are sorted by number and are to go one by one from block 0, replay of a part of chain can't rebuild state
+func readblocks(dir string) ([]*common.Block, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []*common.Block{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		block := &common.Block{}
+		if err := proto.Unmarshal(data, block); err != nil || block.Header == nil {
+			return nil, fmt.Errorf("%s is not a block: %v", f.Name(), err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Header.Number < blocks[j].Header.Number })
+
+	for i, block := range blocks {
+		if block.Header.Number != uint64(i) {
+			return nil, fmt.Errorf("block %d is missing, blocks are to go one by one from block 0", i)
+		}
+	}
+
+	return blocks, nil
+}
+
+// writes of valid endorser txs of block to namespace, in order of txs
+func nswrites(block *common.Block, namespace string) ([]txwrites, error) {
+	var filter []byte // validation codes of txs, absent in blocks of orderer
+	if md := block.Metadata; md != nil && len(md.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		filter = md.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	txs := []txwrites{}
+	for i, data := range block.Data.Data {
+		if i < len(filter) && pb.TxValidationCode(filter[i]) != pb.TxValidationCode_VALID {
+			continue
+		}
+
+		env, err := utils.GetEnvelopeFromBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %d: %s", block.Header.Number, i, err)
+		}
+		payload, err := utils.UnmarshalPayload(env.Payload)
+		if err != nil || payload.Header == nil {
+			return nil, fmt.Errorf("block %d tx %d: bad payload: %v", block.Header.Number, i, err)
+		}
+		chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %d: %s", block.Header.Number, i, err)
+		}
+		if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue // config and other txs don't write to chaincode
+		}
+
+		tx := txwrites{Block: block.Header.Number, TxID: chdr.TxId}
+		if ts := chdr.Timestamp; ts != nil {
+			tx.Timestamp = time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format(time.RFC3339Nano)
+		}
+
+		writes, err := txnswrites(payload.Data, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %s: %s", block.Header.Number, chdr.TxId, err)
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		tx.Writes = writes
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// writes to namespace of all actions of endorser tx
+func txnswrites(data []byte, namespace string) ([]*kvrwset.KVWrite, error) {
+	tx, err := utils.GetTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := []*kvrwset.KVWrite{}
+	for _, action := range tx.Actions {
+		ccpayload, err := utils.GetChaincodeActionPayload(action.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if ccpayload.Action == nil {
+			continue
+		}
+		prp, err := utils.GetProposalResponsePayload(ccpayload.Action.ProposalResponsePayload)
+		if err != nil {
+			return nil, err
+		}
+		ca, err := utils.GetChaincodeAction(prp.Extension)
+		if err != nil {
+			return nil, err
+		}
+
+		txrwset := &rwset.TxReadWriteSet{}
+		if err := proto.Unmarshal(ca.Results, txrwset); err != nil {
+			return nil, fmt.Errorf("bad read-write set: %s", err)
+		}
+		for _, ns := range txrwset.NsRwset {
+			if ns.Namespace != namespace {
+				continue
+			}
+			kv := &kvrwset.KVRWSet{}
+			if err := proto.Unmarshal(ns.Rwset, kv); err != nil {
+				return nil, fmt.Errorf("bad read-write set of %s: %s", namespace, err)
+			}
+			writes = append(writes, kv.Writes...)
+		}
+	}
+
+	return writes, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+const testcc = "crocc"
+
+// tx of test block
+type testtx struct {
+	txid      string
+	invalid   bool   // marked invalid in filter of block
+	namespace string // testcc if empty
+	writes    []*kvrwset.KVWrite
+}
+
+func write(key string, value string) *kvrwset.KVWrite {
+	return &kvrwset.KVWrite{Key: key, Value: []byte(value)}
+}
+
+func mustmarshal(t *testing.T, m proto.Message) []byte {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// block of endorser txs, as peer delivers it - with filter of validation codes
+func testblock(t *testing.T, number uint64, txs ...testtx) *common.Block {
+	block := &common.Block{
+		Header:   &common.BlockHeader{Number: number},
+		Data:     &common.BlockData{},
+		Metadata: &common.BlockMetadata{Metadata: make([][]byte, 4)},
+	}
+	filter := []byte{}
+
+	for i, tx := range txs {
+		ns := tx.namespace
+		if ns == "" {
+			ns = testcc
+		}
+		results := mustmarshal(t, &rwset.TxReadWriteSet{
+			DataModel: rwset.TxReadWriteSet_KV,
+			NsRwset:   []*rwset.NsReadWriteSet{{Namespace: ns, Rwset: mustmarshal(t, &kvrwset.KVRWSet{Writes: tx.writes})}},
+		})
+		prp := mustmarshal(t, &pb.ProposalResponsePayload{Extension: mustmarshal(t, &pb.ChaincodeAction{Results: results})})
+		ccpayload := mustmarshal(t, &pb.ChaincodeActionPayload{Action: &pb.ChaincodeEndorsedAction{ProposalResponsePayload: prp}})
+		chdr := mustmarshal(t, &common.ChannelHeader{
+			Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+			TxId:      tx.txid,
+			Timestamp: &timestamp.Timestamp{Seconds: 1556000000 + int64(number)*60 + int64(i)},
+		})
+		payload := mustmarshal(t, &common.Payload{
+			Header: &common.Header{ChannelHeader: chdr},
+			Data:   mustmarshal(t, &pb.Transaction{Actions: []*pb.TransactionAction{{Payload: ccpayload}}}),
+		})
+		block.Data.Data = append(block.Data.Data, mustmarshal(t, &common.Envelope{Payload: payload}))
+
+		code := pb.TxValidationCode_VALID
+		if tx.invalid {
+			code = pb.TxValidationCode_MVCC_READ_CONFLICT
+		}
+		filter = append(filter, byte(code))
+	}
+
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = filter
+	return block
+}
+
+// dir with blocks in files, as peer channel fetch writes them
+func blocksdir(t *testing.T, blocks ...*common.Block) string {
+	dir, err := ioutil.TempDir("", "crocc-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		name := filepath.Join(dir, "mychannel_"+strconv.FormatUint(b.Header.Number, 10)+".block")
+		if err := ioutil.WriteFile(name, mustmarshal(t, b), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadBlocks(t *testing.T) {
+	// files are read in order of names, blocks are sorted by numbers
+	dir := blocksdir(t, testblock(t, 10), testblock(t, 2))
+	defer os.RemoveAll(dir)
+	for i := uint64(0); i < 12; i++ {
+		if i != 2 && i != 10 {
+			ioutil.WriteFile(filepath.Join(dir, "mychannel_"+strconv.FormatUint(i, 10)+".block"), mustmarshal(t, testblock(t, i)), 0644)
+		}
+	}
+
+	blocks, err := readblocks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range blocks {
+		if b.Header.Number != uint64(i) {
+			t.Fatalf("block %d in place of %d", b.Header.Number, i)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "mychannel_5.block"))
+	if _, err := readblocks(dir); err == nil {
+		t.Errorf("gap in blocks is not detected")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a block"), 0644)
+	if _, err := readblocks(dir); err == nil {
+		t.Errorf("file which is not a block is read")
+	}
+}
+
+func TestNsWrites(t *testing.T) {
+	block := testblock(t, 0,
+		testtx{txid: "tx1", writes: []*kvrwset.KVWrite{write("v1", "{}")}},
+		testtx{txid: "tx2", invalid: true, writes: []*kvrwset.KVWrite{write("v2", "{}")}},
+		testtx{txid: "tx3", namespace: "lscc", writes: []*kvrwset.KVWrite{write(testcc, "definition")}},
+		testtx{txid: "tx4", writes: []*kvrwset.KVWrite{write("v1|voter", "{}"), {Key: "v3", IsDelete: true}}})
+
+	txs, err := nswrites(block, testcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[0].TxID != "tx1" || txs[1].TxID != "tx4" {
+		t.Fatalf("expected valid txs of chaincode tx1 and tx4, got %+v", txs)
+	}
+	if len(txs[1].Writes) != 2 || !txs[1].Writes[1].IsDelete {
+		t.Errorf("writes of tx4 are not read: %v", txs[1].Writes)
+	}
+	if txs[0].Timestamp != "2019-04-23T06:13:20Z" {
+		t.Errorf("wrong time of tx: %s", txs[0].Timestamp)
+	}
+
+	// blocks of orderer have no filter, all txs are taken
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = nil
+	if txs, _ := nswrites(block, testcc); len(txs) != 3 {
+		t.Errorf("expected 3 txs without filter, got %d", len(txs))
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+const constresultsuffix = "|result" // key of published result is voteid|result
+
+const constreservedprefix = "|" // keys of config, groups and registry - not ballots, votes or results
+
+// struct for ballot as chaincode writes it, only fields for counting
+type ballotrecord struct {
+	Voters   []string `json:"voters"`
+	Majority float64  `json:"majority"` // majority of ballot or default one of config, set at votestart
+}
+
+// struct for vote as chaincode writes it
+type voterecord struct {
+	Voter     string `json:"voter"`
+	Org       string `json:"org"`
+	Vote      string `json:"vote"`
+	Comment   string `json:"comment"`
+	TxID      string `json:"txid"`
+	Timestamp string `json:"timestamp"`
+}
+
+// struct for published result, only fields to check
+type resultrecord struct {
+	Counts      map[string]int `json:"counts"`
+	Decision    string         `json:"decision"`
+	Decided     *bool          `json:"decided"` // nil in results written before it
+	Certificate struct {
+		MerkleRoot string `json:"merkleroot"`
+		Leaves     int    `json:"leaves"`
+	} `json:"certificate"`
+}
+
+// struct for result recomputed from replayed state
+type recount struct {
+	Counts     map[string]int `json:"counts"`
+	Decision   string         `json:"decision"`
+	Decided    bool           `json:"decided"`
+	MerkleRoot string         `json:"merkleroot"`
+	Leaves     int            `json:"leaves"`
+}
+
+// struct for published result which disagrees with recount
+type mismatch struct {
+	Block      uint64 `json:"block"`
+	TxID       string `json:"txid"`
+	VoteID     string `json:"voteid"`
+	Field      string `json:"field"` // ballot \ decision \ decided \ counts \ merkleroot \ leaves
+	Published  string `json:"published"`
+	Recomputed string `json:"recomputed"`
+}
+
+// struct for report of verification
+type verification struct {
+	Blocks     int        `json:"blocks"`
+	Txs        int        `json:"txs"`     // valid txs with writes to chaincode
+	Ballots    int        `json:"ballots"` // ballots in state after replay
+	Votes      int        `json:"votes"`   // votes in state after replay
+	Results    int        `json:"results"` // published results checked
+	Mismatches []mismatch `json:"mismatches"`
+}
+
+// state of chaincode rebuilt from writes
+type ledgerstate map[string][]byte
+
+// replay writes of chaincode from blocks and check every published result
+// against result recomputed from state the result tx read
+func replay(blocks []*common.Block, namespace string) (*verification, error) {
+	state := ledgerstate{}
+	ver := &verification{Blocks: len(blocks), Mismatches: []mismatch{}}
+
+	for _, block := range blocks {
+		txs, err := nswrites(block, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range txs {
+			ver.Txs++
+
+			// results first - they are counted on state before writes of their tx
+			for _, w := range tx.Writes {
+				if w.IsDelete || strings.HasPrefix(w.Key, constreservedprefix) || !strings.HasSuffix(w.Key, constresultsuffix) {
+					continue
+				}
+				voteid := strings.TrimSuffix(w.Key, constresultsuffix)
+				ver.Results++
+				for _, m := range state.check(voteid, w.Value) {
+					m.Block, m.TxID = tx.Block, tx.TxID
+					ver.Mismatches = append(ver.Mismatches, m)
+				}
+			}
+
+			for _, w := range tx.Writes {
+				if w.IsDelete {
+					delete(state, w.Key)
+					continue
+				}
+				state[w.Key] = w.Value
+			}
+		}
+	}
+
+	for key := range state {
+		i := strings.Index(key, "|")
+		switch {
+		case strings.HasPrefix(key, constreservedprefix), strings.HasSuffix(key, constresultsuffix):
+		case i < 0:
+			ver.Ballots++
+		default: // vote is voteid|voter of known ballot
+			if _, ok := state[key[:i]]; ok {
+				ver.Votes++
+			}
+		}
+	}
+
+	return ver, nil
+}
+
+// ballot - json, or {repourl enddate [voters]} of ballots written before
+func parseballot(b []byte) ballotrecord {
+	var ballot ballotrecord
+	if err := json.Unmarshal(b, &ballot); err == nil {
+		return ballot
+	}
+	_, _, ballot.Voters = tally.ParseLegacyBallot(b)
+	return ballot
+}
+
+// vote - json, or {voter vote comment} of votes written before
+func parsevote(b []byte) voterecord {
+	var v voterecord
+	if err := json.Unmarshal(b, &v); err == nil {
+		return v
+	}
+	v.Voter, v.Vote, v.Comment = tally.ParseLegacyVote(b)
+	return v
+}
+
+// result of ballot by the rules of chaincode: votes of voters in order of ballot,
+// counts, decision and Merkle root over canonical votes
+func (s ledgerstate) recount(voteid string) (*recount, error) {
+	b, ok := s[voteid]
+	if !ok {
+		return nil, fmt.Errorf("no ballot %s", voteid)
+	}
+
+	ballot := parseballot(b)
+	votes, leaves := []string{}, [][]byte{}
+	for _, voter := range ballot.Voters {
+		val, ok := s[voteid+"|"+voter]
+		if !ok { // voter has not voted
+			continue
+		}
+		v := parsevote(val)
+		v.Voter = voter // owner of vote is in key
+		votes = append(votes, v.Vote)
+		leaves = append(leaves, tally.Leaf(tally.Canonical(voteid, v.Voter, v.Org, v.Vote, v.Comment, v.TxID, v.Timestamp)))
+	}
+
+	counts := tally.Count(votes)
+	decision, err := tally.Decide(counts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recount{
+		Counts:     counts,
+		Decision:   decision,
+		Decided:    tally.Decides(counts, decision, ballot.Majority),
+		MerkleRoot: hex.EncodeToString(tally.Root(leaves)),
+		Leaves:     len(leaves),
+	}, nil
+}
+
+// differences of published result from recount of state; result written before json reports
+// has only decision to check
+func (s ledgerstate) check(voteid string, published []byte) []mismatch {
+	rc, err := s.recount(voteid)
+	if err != nil {
+		return []mismatch{{VoteID: voteid, Field: "ballot", Published: "result", Recomputed: err.Error()}}
+	}
+
+	var res resultrecord
+	if err := json.Unmarshal(published, &res); err != nil {
+		decision := legacydecision(published)
+		if decision != rc.Decision {
+			return []mismatch{{VoteID: voteid, Field: "decision", Published: decision, Recomputed: rc.Decision}}
+		}
+		return nil
+	}
+
+	mismatches := []mismatch{}
+	add := func(field, published, recomputed string) {
+		if published != recomputed {
+			mismatches = append(mismatches, mismatch{VoteID: voteid, Field: field, Published: published, Recomputed: recomputed})
+		}
+	}
+	add("decision", res.Decision, rc.Decision)
+	if res.Decided != nil {
+		add("decided", fmt.Sprint(*res.Decided), fmt.Sprint(rc.Decided))
+	}
+	add("counts", countsstring(res.Counts), countsstring(rc.Counts))
+	add("merkleroot", res.Certificate.MerkleRoot, rc.MerkleRoot)
+	add("leaves", fmt.Sprint(res.Certificate.Leaves), fmt.Sprint(rc.Leaves))
+	return mismatches
+}
+
+// decision of result written as text before json reports - "Resolution of Voting: yes"
+func legacydecision(b []byte) string {
+	const label = "Resolution of Voting: "
+	i := bytes.Index(b, []byte(label))
+	if i < 0 {
+		return ""
+	}
+	fields := strings.Fields(string(b[i+len(label):]))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// counts as option=count, sorted by option
+func countsstring(counts map[string]int) string {
+	options := []string{}
+	for option, n := range counts {
+		if n != 0 {
+			options = append(options, option)
+		}
+	}
+	sort.Strings(options)
+
+	s := []string{}
+	for _, option := range options {
+		s = append(s, fmt.Sprintf("%s=%d", option, counts[option]))
+	}
+	return strings.Join(s, " ")
+}
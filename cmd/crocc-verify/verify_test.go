@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+func jsonof(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// published result as voteresult writes it, for votes in order of ballot
+func published(t *testing.T, voteid string, decision string, votes ...voterecord) string {
+	counted, leaves := []string{}, [][]byte{}
+	for _, v := range votes {
+		counted = append(counted, v.Vote)
+		leaves = append(leaves, tally.Leaf(tally.Canonical(voteid, v.Voter, v.Org, v.Vote, v.Comment, v.TxID, v.Timestamp)))
+	}
+	res := resultrecord{Counts: tally.Count(counted), Decision: decision}
+	res.Certificate.MerkleRoot = hex.EncodeToString(tally.Root(leaves))
+	res.Certificate.Leaves = len(votes)
+	return jsonof(t, res)
+}
+
+// chain of ballot v1 with voters of Org1 and Org2, both vote, Org1 then changes vote
+func votechain(t *testing.T) ([]*common.Block, voterecord, voterecord, voterecord) {
+	ballot := jsonof(t, map[string]interface{}{"repourl": "https://git.repo", "enddate": "01.05.2019", "voters": []string{"ca.org1", "ca.org2"}})
+	yes1 := voterecord{Voter: "ca.org1", Org: "Org1MSP", Vote: "yes", TxID: "tx2", Timestamp: "2019-04-23T06:14:20Z"}
+	yes2 := voterecord{Voter: "ca.org2", Org: "Org2MSP", Vote: "yes", Comment: "fine", TxID: "tx3", Timestamp: "2019-04-23T06:14:21Z"}
+	no1 := voterecord{Voter: "ca.org1", Org: "Org1MSP", Vote: "no", TxID: "tx5", Timestamp: "2019-04-23T06:15:20Z"}
+
+	blocks := []*common.Block{
+		testblock(t, 0),
+		testblock(t, 1,
+			testtx{txid: "tx1", writes: []*kvrwset.KVWrite{write("v1", ballot)}},
+			testtx{txid: "tx2", writes: []*kvrwset.KVWrite{write("v1|ca.org1", jsonof(t, yes1))}}),
+		testblock(t, 2,
+			testtx{txid: "tx3", writes: []*kvrwset.KVWrite{write("v1|ca.org2", jsonof(t, yes2))}},
+			testtx{txid: "tx4", writes: []*kvrwset.KVWrite{write("v1|result", published(t, "v1", "yes", yes1, yes2))}}),
+		testblock(t, 3,
+			testtx{txid: "tx5", writes: []*kvrwset.KVWrite{write("v1|ca.org1", jsonof(t, no1))}}),
+	}
+	return blocks, yes1, yes2, no1
+}
+
+func TestReplay_Agree(t *testing.T) {
+	blocks, _, yes2, no1 := votechain(t)
+	// result after change of vote and its rewrite in invalid tx
+	blocks = append(blocks, testblock(t, 4,
+		testtx{txid: "tx6", writes: []*kvrwset.KVWrite{write("v1|result", published(t, "v1", "equal", no1, yes2))}},
+		testtx{txid: "tx7", invalid: true, writes: []*kvrwset.KVWrite{write("v1|ca.org2", "forged")}}))
+	// config, group and registry keys are neither ballots nor votes, key of unknown ballot is no vote
+	blocks = append(blocks, testblock(t, 5,
+		testtx{txid: "tx8", writes: []*kvrwset.KVWrite{write("|config", `{"version":2,"tallyrule":"plurality"}`)}},
+		testtx{txid: "tx9", writes: []*kvrwset.KVWrite{
+			write("|voter|ca.org1", `{"id":"ca.org1","mspid":"Org1MSP","subject":"ca.org1","active":true}`),
+			write("|group|result", `{"name":"result","members":["ca.org1"]}`),
+			write("v9|ca.org1", jsonof(t, no1))}}))
+
+	ver, err := replay(blocks, testcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ver.Mismatches) != 0 {
+		t.Errorf("expected no mismatches, got %+v", ver.Mismatches)
+	}
+	if ver.Blocks != 6 || ver.Txs != 8 || ver.Ballots != 1 || ver.Votes != 2 || ver.Results != 2 {
+		t.Errorf("wrong totals: %+v", ver)
+	}
+}
+
+func TestReplay_Disagree(t *testing.T) {
+	blocks, yes1, yes2, _ := votechain(t)
+	// result published after change of vote still says yes with old votes
+	blocks = append(blocks, testblock(t, 4,
+		testtx{txid: "tx6", writes: []*kvrwset.KVWrite{write("v1|result", published(t, "v1", "yes", yes1, yes2))}},
+		testtx{txid: "tx7", writes: []*kvrwset.KVWrite{write("v2|result", published(t, "v2", "yes"))}}))
+
+	ver, err := replay(blocks, testcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]bool{}
+	for _, m := range ver.Mismatches {
+		if m.Block != 4 {
+			t.Errorf("mismatch in block %d, expected only block 4: %+v", m.Block, m)
+		}
+		fields[m.VoteID+" "+m.Field] = true
+	}
+	for _, f := range []string{"v1 decision", "v1 counts", "v1 merkleroot", "v2 ballot"} {
+		if !fields[f] {
+			t.Errorf("mismatch %s is not found in %+v", f, ver.Mismatches)
+		}
+	}
+	if fields["v1 leaves"] {
+		t.Errorf("leaves are the same, but reported")
+	}
+}
+
+func TestReplay_Legacy(t *testing.T) {
+	// ballot, votes and result written with %v before json records
+	blocks := []*common.Block{testblock(t, 0,
+		testtx{txid: "tx1", writes: []*kvrwset.KVWrite{write("v1", "{https://git.repo 10.04.2019.10.00 [ca.org1 ca.org2]}")}},
+		testtx{txid: "tx2", writes: []*kvrwset.KVWrite{write("v1|ca.org1", "{ca.org1 Yes Because I can}")}},
+		testtx{txid: "tx3", writes: []*kvrwset.KVWrite{write("v1|ca.org2", "{ca.org2 yes}")}},
+		testtx{txid: "tx4", writes: []*kvrwset.KVWrite{write("v1|result",
+			"{\n Vote ID: v1 \nRepo Url: https://git.repo \nResolution of Voting: yes\n [{\nVoter: ca.org1 \nVoice: yes \nComment: Because I can}]}")}},
+		testtx{txid: "tx5", writes: []*kvrwset.KVWrite{write("v1|result", "{\n Vote ID: v1 \nResolution of Voting: no\n []}")}})}
+
+	ver, err := replay(blocks, testcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ver.Mismatches) != 1 || ver.Mismatches[0].TxID != "tx5" || ver.Mismatches[0].Published != "no" || ver.Mismatches[0].Recomputed != "yes" {
+		t.Errorf("expected only mismatch of decision in tx5, got %+v", ver.Mismatches)
+	}
+}
+
+func TestReplay_Majority(t *testing.T) {
+	// ballot of majority 70, set by it or by config: yes of 2 votes of 3 leads without deciding
+	ballot := jsonof(t, map[string]interface{}{"repourl": "https://git.repo", "enddate": "01.05.2019", "voters": []string{"ca.org1", "ca.org2", "ca.org3"}, "majority": 70})
+	yes1 := voterecord{Voter: "ca.org1", Org: "Org1MSP", Vote: "yes", TxID: "tx2"}
+	yes2 := voterecord{Voter: "ca.org2", Org: "Org2MSP", Vote: "yes", TxID: "tx3"}
+	no3 := voterecord{Voter: "ca.org3", Org: "Org3MSP", Vote: "no", TxID: "tx4"}
+	result := func(decided bool) string {
+		var res map[string]interface{}
+		json.Unmarshal([]byte(published(t, "v1", "yes", yes1, yes2, no3)), &res)
+		res["decided"] = decided
+		return jsonof(t, res)
+	}
+
+	blocks := []*common.Block{testblock(t, 0,
+		testtx{txid: "tx1", writes: []*kvrwset.KVWrite{write("v1", ballot)}},
+		testtx{txid: "tx2", writes: []*kvrwset.KVWrite{write("v1|ca.org1", jsonof(t, yes1))}},
+		testtx{txid: "tx3", writes: []*kvrwset.KVWrite{write("v1|ca.org2", jsonof(t, yes2))}},
+		testtx{txid: "tx4", writes: []*kvrwset.KVWrite{write("v1|ca.org3", jsonof(t, no3))}},
+		testtx{txid: "tx5", writes: []*kvrwset.KVWrite{write("v1|result", result(false))}},
+		testtx{txid: "tx6", writes: []*kvrwset.KVWrite{write("v1|result", result(true))}})}
+
+	ver, err := replay(blocks, testcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ver.Mismatches) != 1 || ver.Mismatches[0].TxID != "tx6" || ver.Mismatches[0].Field != "decided" {
+		t.Errorf("expected only mismatch of decided in tx6, got %+v", ver.Mismatches)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/wallet"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// exit statuses
+const (
+	exitok    = 0
+	exitfail  = 1 // chaincode or network returned error
+	exitusage = 2 // wrong command or flags
+)
+
+// outputs of commands
+const (
+	outputtable = "table"
+	outputjson  = "json"
+)
+
+// struct for state of one run of crocc
+type cli struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	connect func(config string, identity msp.SigningIdentity) (api.Invoker, error)
+	notice  string // printed after usage, as of build which can't connect to network
+
+	// global flags
+	channel   string
+	chaincode string
+	mspdir    string
+	mspid     string
+	config    string
+	output    *choice
+
+	client *client.Client
+}
+
+// struct for subcommand - group and name, as ballot create
+type command struct {
+	group string
+	name  string
+	doc   string
+	run   func(c *cli, ctx context.Context, args []string) error
+}
+
+// error of usage - wrong command or flags
+type usageerror struct {
+	error
+}
+
+// run command of args, returns exit status
+func (c *cli) run(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("crocc", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.channel, "channel", env("CROCC_CHANNEL", "mychannel"), "channel of chaincode")
+	fs.StringVar(&c.chaincode, "cc", env("CROCC_CC", "crocc"), "name of chaincode")
+	fs.StringVar(&c.mspdir, "msp", env("CROCC_MSP", ""), "MSP directory of identity: signcerts and keystore")
+	fs.StringVar(&c.mspid, "mspid", env("CROCC_MSPID", ""), "MSP ID of identity")
+	fs.StringVar(&c.config, "config", env("CROCC_CONFIG", ""), "config of connection to Fabric network")
+	c.output = newchoice(outputtable, outputtable, outputjson)
+	fs.Var(c.output, "output", "output: table or json")
+	fs.Usage = func() { c.usage(fs) }
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitok
+		}
+		return exitusage
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 || rest[0] == "help" {
+		c.usage(fs)
+		if len(rest) == 0 {
+			return exitusage
+		}
+		return exitok
+	}
+	if len(rest) < 2 {
+		fmt.Fprintf(c.stderr, "crocc: %s: command is missing, run crocc help\n", rest[0])
+		return exitusage
+	}
+
+	for _, cmd := range commands {
+		if cmd.group != rest[0] || cmd.name != rest[1] {
+			continue
+		}
+
+		err := cmd.run(c, ctx, rest[2:])
+		switch err.(type) {
+		case nil:
+			return exitok
+		case usageerror:
+			fmt.Fprintf(c.stderr, "crocc %s %s: %s\n", cmd.group, cmd.name, err)
+			return exitusage
+		}
+		if err == flag.ErrHelp {
+			return exitok
+		}
+		fmt.Fprintf(c.stderr, "crocc %s %s: %s\n", cmd.group, cmd.name, err)
+		return exitfail
+	}
+
+	fmt.Fprintf(c.stderr, "crocc: unknown command %s %s, run crocc help\n", rest[0], rest[1])
+	return exitusage
+}
+
+func (c *cli) usage(fs *flag.FlagSet) {
+	fmt.Fprintf(c.stderr, "usage: crocc [global flags] <command> [flags]\n\ncommands:\n")
+	w := tabwriter.NewWriter(c.stderr, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.group, cmd.name, cmd.doc)
+	}
+	w.Flush()
+	fmt.Fprintf(c.stderr, "\nglobal flags:\n")
+	fs.PrintDefaults()
+	if c.notice != "" {
+		fmt.Fprintf(c.stderr, "\n%s\n", c.notice)
+	}
+}
+
+// flag set of subcommand, prints its usage in stderr
+func (c *cli) flags(cmd string) *flag.FlagSet {
+	fs := flag.NewFlagSet("crocc "+cmd, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse flags of subcommand, errors of flags are usage errors
+func parseflags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return usageerror{err}
+	}
+	if fs.NArg() > 0 {
+		return usageerror{fmt.Errorf("unexpected args %v", fs.Args())}
+	}
+	return nil
+}
+
+// error of missing required flag
+func required(name string) error {
+	return usageerror{fmt.Errorf("flag -%s is required", name)}
+}
+
+func env(name string, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// client of chaincode from identity of MSP directory, created on first call
+func (c *cli) connection() (*client.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	if c.mspdir == "" {
+		return nil, usageerror{errors.New("identity is not set, use -msp and -mspid")}
+	}
+
+	identity, err := wallet.Load(c.mspdir, c.mspid)
+	if err != nil {
+		return nil, err
+	}
+	invoker, err := c.connect(c.config, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	c.client = client.New(invoker, identity, c.channel, c.chaincode)
+	return c.client, nil
+}
+
+// print answer: indented json of v, or table made by table func
+func (c *cli) print(v interface{}, table func(w io.Writer)) error {
+	if c.output.value == outputjson {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", b)
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+//----------------- typed flags
+
+// repeatable flag: -voter a -voter b
+type multiflag []string
+
+func (m *multiflag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiflag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// flag with one of fixed values
+type choice struct {
+	value   string
+	choices []string
+}
+
+func newchoice(def string, choices ...string) *choice {
+	return &choice{value: def, choices: choices}
+}
+
+func (c *choice) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.value
+}
+
+func (c *choice) Set(value string) error {
+	for _, v := range c.choices {
+		if strings.ToLower(value) == v {
+			c.value = v
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", value, strings.Join(c.choices, ", "))
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/ioserikov/cc_voting/chaincode"
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/wallet/wallettest"
+	cckit "github.com/s7techlab/cckit/testing"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// chaincode names voters by common name of CA of their certificates
+const (
+	voter1 = "ca.org1.example.com"
+	voter2 = "ca.org2.example.com"
+)
+
+// struct for network of test: chaincode in MockStub behind MockInvoker and MSP directories of two orgs
+type testnet struct {
+	invoker *cckit.MockInvoker
+	dir     string
+	msps    map[string]string // MSP ID -> MSP directory
+}
+
+func newtestnet(t *testing.T) *testnet {
+	dir, err := ioutil.TempDir("", "crocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net := &testnet{
+		invoker: cckit.NewInvoker().WithChannel("mychannel", cckit.NewMockStub("crocc", new(chaincode.SimpleChaincode))),
+		dir:     dir,
+		msps:    map[string]string{},
+	}
+	for mspid, ca := range map[string]string{"Org1MSP": voter1, "Org2MSP": voter2} {
+		net.msps[mspid] = filepath.Join(dir, mspid)
+		if err := wallettest.MSPDir(net.msps[mspid], ca, "User1@"+strings.TrimPrefix(ca, "ca.")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return net
+}
+
+// run crocc as member of org, returns exit status, stdout and stderr
+func (net *testnet) run(mspid string, args ...string) (int, string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c := &cli{
+		stdout: stdout,
+		stderr: stderr,
+		connect: func(config string, identity msp.SigningIdentity) (api.Invoker, error) {
+			return net.invoker, nil
+		},
+	}
+	global := []string{}
+	if mspid != "" {
+		global = append(global, "-msp", net.msps[mspid], "-mspid", mspid)
+	}
+	code := c.run(context.Background(), append(global, args...))
+	return code, stdout.String(), stderr.String()
+}
+
+// run crocc, fails test if status is not expected
+func (net *testnet) expect(t *testing.T, code int, mspid string, args ...string) string {
+	got, stdout, stderr := net.run(mspid, args...)
+	if got != code {
+		t.Fatalf("%v: exit status %d, expected %d\nstdout: %s\nstderr: %s", args, got, code, stdout, stderr)
+	}
+	if code != exitok {
+		return stderr
+	}
+	return stdout
+}
+
+func TestCLI_Voting(t *testing.T) {
+	net := newtestnet(t)
+	defer os.RemoveAll(net.dir)
+
+	out := net.expect(t, exitok, "Org1MSP", "ballot", "create", "-id", "Vote1", "-repo", "https://git.repo", "-end", "01.05.2099",
+		"-voter", voter1, "-voter", voter2, "-title", "Release 1.0", "-tag", "release", "-quorum", "50", "-org", "Org2MSP:"+voter2)
+	if !strings.Contains(out, "Vote1") {
+		t.Errorf("ballot create: %s", out)
+	}
+
+	var ballots []client.Ballot
+	out = net.expect(t, exitok, "Org2MSP", "-output", "json", "ballot", "list")
+	if err := json.Unmarshal([]byte(out), &ballots); err != nil || len(ballots) != 1 {
+		t.Fatalf("ballot list: %s %v", out, err)
+	}
+	if b := ballots[0]; b.VoteID != "Vote1" || b.Proposer != voter1 || b.Title != "Release 1.0" || len(b.Voters) != 2 {
+		t.Errorf("wrong ballot %+v", b)
+	}
+
+	out = net.expect(t, exitok, "Org2MSP", "config", "show")
+	for _, line := range []string{"Options:         yes, no, neutral", "Tally Rule:      plurality", "Identity:        issuer"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("config show has no %q:\n%s", line, out)
+		}
+	}
+
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "show", "-id", "Vote1")
+	for _, line := range []string{"Title:        Release 1.0", "Status:       open", "Voters:       " + voter1 + ", " + voter2} {
+		if !strings.Contains(out, line) {
+			t.Errorf("ballot show has no %q:\n%s", line, out)
+		}
+	}
+
+	var r client.Receipt
+	out = net.expect(t, exitok, "Org1MSP", "-output", "json", "vote", "cast", "-id", "Vote1", "-vote", "Yes")
+	if err := json.Unmarshal([]byte(out), &r); err != nil || r.Voter != voter1 || r.VoteHash == "" {
+		t.Errorf("vote cast: %s %v", out, err)
+	}
+
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "status", "-id", "Vote1")
+	for _, line := range []string{"Turnout:      1 of 2 (50.0%)", "Quorum:       50% (1 votes), met", "Org Org2MSP:  0 of 1 (0.0%)", "Pending:      " + voter2 + " (Org2MSP)"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("ballot status has no %q:\n%s", line, out)
+		}
+	}
+	net.expect(t, exitok, "Org2MSP", "vote", "cast", "-id", "Vote1", "-vote", "no", "-comment", "not ready")
+	net.expect(t, exitok, "Org2MSP", "vote", "withdraw", "-id", "Vote1")
+
+	// only proposer closes, no votes after close
+	if stderr := net.expect(t, exitfail, "Org2MSP", "ballot", "close", "-id", "Vote1"); !strings.Contains(stderr, "NOT_PROPOSER") {
+		t.Errorf("close by not proposer: %s", stderr)
+	}
+	net.expect(t, exitok, "Org1MSP", "ballot", "close", "-id", "Vote1")
+	if stderr := net.expect(t, exitfail, "Org2MSP", "vote", "cast", "-id", "Vote1", "-vote", "no"); !strings.Contains(stderr, "BALLOT_CLOSED") {
+		t.Errorf("vote after close: %s", stderr)
+	}
+
+	out = net.expect(t, exitok, "Org2MSP", "result", "show", "-id", "Vote1")
+	for _, line := range []string{"Decision:     yes", "Counts:       yes 1, no 0, neutral 0", "Abstained:    " + voter2} {
+		if !strings.Contains(out, line) {
+			t.Errorf("result show has no %q:\n%s", line, out)
+		}
+	}
+
+	var rep client.Report
+	out = net.expect(t, exitok, "Org1MSP", "-output", "json", "result", "show", "-id", "Vote1", "-publish")
+	if err := json.Unmarshal([]byte(out), &rep); err != nil || rep.Decision != "yes" || rep.Certificate.MerkleRoot == "" {
+		t.Errorf("result show -publish: %s %v", out, err)
+	}
+	stub, _ := net.invoker.Chaincode("mychannel", "crocc")
+	if v, _ := stub.GetState("Vote1|result"); v == nil {
+		t.Errorf("result is not published")
+	}
+
+	out = net.expect(t, exitok, "Org2MSP", "analytics", "export", "-view", "decision", "-delimiter", "comma", "-lineend", "lf")
+	if out != "voter,decided,agreed,percent\n"+voter1+",1,1,100.00\n" {
+		t.Errorf("analytics export:\n%s", out)
+	}
+
+	// closed ballot is archived, draft is cancelled with reason
+	net.expect(t, exitok, "Org1MSP", "ballot", "archive", "-id", "Vote1")
+	net.expect(t, exitok, "Org1MSP", "ballot", "create", "-id", "Vote2", "-repo", "https://git.repo", "-end", "01.06.2099", "-voter", voter1, "-draft")
+	net.expect(t, exitusage, "Org1MSP", "ballot", "cancel", "-id", "Vote2")
+	net.expect(t, exitok, "Org1MSP", "ballot", "cancel", "-id", "Vote2", "-reason", "obsolete")
+	if stderr := net.expect(t, exitfail, "Org1MSP", "ballot", "close", "-id", "Vote2"); !strings.Contains(stderr, "INVALID_STATE") {
+		t.Errorf("close of cancelled: %s", stderr)
+	}
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "show", "-id", "Vote1")
+	if !strings.Contains(out, "Status:       archived") || !strings.Contains(out, "closed -> archived by "+voter1) || !strings.Contains(out, "Archive:      yes, 1 votes") {
+		t.Errorf("ballot show of archived:\n%s", out)
+	}
+	net.expect(t, exitok, "Org1MSP", "ballot", "create", "-id", "Vote3", "-repo", "https://git.repo", "-end", "02.01.2100", "-start", "01.01.2100", "-voter", voter1,
+		"-maxextend", "48h", "-sponsor", "Org2MSP", "-approvals", "2", "-endorser", "Org2MSP")
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "extend", "-id", "Vote3", "-end", "03.01.2100", "-reason", "holidays")
+	if !strings.Contains(out, "End Date:   02.01.2100 -> 03.01.2100") || strings.Contains(out, "Applied") {
+		t.Errorf("ballot extend:\n%s", out)
+	}
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "show", "-id", "Vote3")
+	if !strings.Contains(out, "Status:         scheduled") || !strings.Contains(out, "Start Date:     01.01.2100") ||
+		!strings.Contains(out, "Extension:      02.01.2100 -> 03.01.2100 by "+voter2) || !strings.Contains(out, "pending, 1 of 2 approvals") ||
+		!strings.Contains(out, "Endorsers:      Org2MSP") {
+		t.Errorf("ballot show of scheduled:\n%s", out)
+	}
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "show", "-id", "Vote2")
+	if !strings.Contains(out, "Status:       cancelled") || !strings.Contains(out, "draft -> cancelled by "+voter1) || !strings.Contains(out, ": obsolete") {
+		t.Errorf("ballot show of cancelled:\n%s", out)
+	}
+}
+
+func TestCLI_Export(t *testing.T) {
+	net := newtestnet(t)
+	defer os.RemoveAll(net.dir)
+
+	net.expect(t, exitok, "Org1MSP", "ballot", "create", "-id", "Vote1", "-repo", "https://git.repo", "-end", "01.05.2099", "-voter", voter1, "-voter", voter2)
+	net.expect(t, exitok, "Org1MSP", "vote", "cast", "-id", "Vote1", "-vote", "yes", "-comment", "ok, ship it")
+	net.expect(t, exitok, "Org2MSP", "vote", "cast", "-id", "Vote1", "-vote", "neutral")
+
+	out := net.expect(t, exitok, "Org1MSP", "result", "export", "-id", "Vote1", "-delimiter", "comma", "-lineend", "lf", "-vote", "yes")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"ok, ship it"`) {
+		t.Errorf("csv of yes votes:\n%s", out)
+	}
+
+	out = net.expect(t, exitok, "Org1MSP", "result", "export", "-id", "Vote1", "-header", "-lineend", "lf")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "voteid") {
+		t.Errorf("csv with header:\n%s", out)
+	}
+
+	file := filepath.Join(net.dir, "report.md")
+	net.expect(t, exitok, "Org1MSP", "result", "export", "-id", "Vote1", "-format", "markdown", "-out", file)
+	if b, err := ioutil.ReadFile(file); err != nil || !bytes.Contains(b, []byte("|")) {
+		t.Errorf("markdown report in file: %s %v", b, err)
+	}
+}
+
+func TestCLI_Usage(t *testing.T) {
+	net := newtestnet(t)
+	defer os.RemoveAll(net.dir)
+
+	cases := []struct {
+		mspid  string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"Org1MSP", []string{}, exitusage, "usage: crocc"},
+		{"Org1MSP", []string{"help"}, exitok, ""},
+		{"Org1MSP", []string{"ballot"}, exitusage, "command is missing"},
+		{"Org1MSP", []string{"ballot", "delete"}, exitusage, "unknown command"},
+		{"Org1MSP", []string{"ballot", "create", "-id", "Vote1"}, exitusage, "-repo is required"},
+		{"Org1MSP", []string{"vote", "cast", "-id", "Vote1", "-vote", "maybe"}, exitusage, "is not one of yes, no, neutral"},
+		{"Org1MSP", []string{"-output", "xml", "ballot", "list"}, exitusage, "is not one of table, json"},
+		{"Org1MSP", []string{"ballot", "show", "Vote1"}, exitusage, "unexpected args"},
+		{"", []string{"ballot", "list"}, exitusage, "identity is not set"},
+		{"Org1MSP", []string{"ballot", "show", "-id", "NoVote"}, exitfail, "BALLOT_NOT_FOUND"},
+	}
+
+	for _, c := range cases {
+		code, _, stderr := net.run(c.mspid, c.args...)
+		if code != c.code || !strings.Contains(stderr, c.stderr) {
+			t.Errorf("%v: exit status %d, expected %d, stderr: %s", c.args, code, c.code, stderr)
+		}
+	}
+
+	// stub build says so in usage and fails to connect
+	stderr := new(bytes.Buffer)
+	stub := &cli{stdout: new(bytes.Buffer), stderr: stderr, connect: noinvoker, notice: stubnotice}
+	if code := stub.run(context.Background(), []string{"help"}); code != exitok || !strings.Contains(stderr.String(), "crocc is a stub") {
+		t.Errorf("usage of stub build: %d %s", code, stderr)
+	}
+	args := []string{"-msp", net.msps["Org1MSP"], "-mspid", "Org1MSP", "ballot", "list"}
+	if code := stub.run(context.Background(), args); code != exitfail || !strings.Contains(stderr.String(), "built without Fabric client") {
+		t.Errorf("call of stub build: %d %s", code, stderr)
+	}
+}
+
+func TestCLI_Groups(t *testing.T) {
+	net := newtestnet(t)
+	defer os.RemoveAll(net.dir)
+
+	net.expect(t, exitok, "Org1MSP", "group", "create", "-name", "board", "-member", voter1, "-description", "board of directors")
+	out := net.expect(t, exitok, "Org1MSP", "group", "update", "-name", "board", "-add", voter2)
+	if !strings.Contains(out, "Version:      2") {
+		t.Errorf("group update: %s", out)
+	}
+	if stderr := net.expect(t, exitfail, "Org2MSP", "group", "delete", "-name", "board"); !strings.Contains(stderr, "NOT_OWNER") {
+		t.Errorf("delete by not owner: %s", stderr)
+	}
+
+	net.expect(t, exitok, "Org1MSP", "ballot", "create", "-id", "Vote1", "-repo", "https://git.repo", "-end", "01.05.2099", "-group", "board")
+	out = net.expect(t, exitok, "Org2MSP", "ballot", "show", "-id", "Vote1")
+	for _, line := range []string{"Voters:       " + voter1 + ", " + voter2, "Group:        board version 2"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("ballot show has no %q:\n%s", line, out)
+		}
+	}
+
+	net.expect(t, exitok, "Org1MSP", "group", "delete", "-name", "board")
+	if out := net.expect(t, exitok, "Org2MSP", "group", "list"); strings.Contains(out, "board") {
+		t.Errorf("group list after delete: %s", out)
+	}
+}
+
+func TestCLI_Registry(t *testing.T) {
+	net := newtestnet(t)
+	defer os.RemoveAll(net.dir)
+
+	if stderr := net.expect(t, exitusage, "Org2MSP", "voter", "register", "-id", voter2, "-mspid", "Org2MSP"); !strings.Contains(stderr, "-fingerprint or -subject") {
+		t.Errorf("register without binding: %s", stderr)
+	}
+	net.expect(t, exitok, "Org2MSP", "voter", "register", "-id", voter2, "-mspid", "Org2MSP", "-subject", "User1@org2.example.com", "-name", "Bob")
+	out := net.expect(t, exitok, "Org2MSP", "voter", "deactivate", "-id", voter2, "-reason", "left board")
+	if !strings.Contains(out, "Active:      no, left board") {
+		t.Errorf("voter deactivate: %s", out)
+	}
+	if stderr := net.expect(t, exitfail, "Org1MSP", "ballot", "create", "-id", "Vote1", "-repo", "https://git.repo", "-end", "01.05.2099", "-voter", voter2); !strings.Contains(stderr, "VOTER_INACTIVE") {
+		t.Errorf("ballot of deactivated voter: %s", stderr)
+	}
+	if stderr := net.expect(t, exitfail, "Org2MSP", "voter", "update", "-id", voter2, "-activate"); !strings.Contains(stderr, "NOT_REGISTRAR") {
+		t.Errorf("activation by voter itself: %s", stderr)
+	}
+	stub, _ := net.invoker.Chaincode("mychannel", "crocc")
+	if res := stub.MockInit("1", [][]byte{[]byte("init"), []byte("admin=Org1MSP")}); res.Status != shim.OK {
+		t.Fatal(res.Message)
+	}
+	net.expect(t, exitok, "Org1MSP", "voter", "update", "-id", voter2, "-activate")
+	if out := net.expect(t, exitok, "Org1MSP", "voter", "list"); !strings.Contains(out, "Bob") || !strings.Contains(out, "true") {
+		t.Errorf("voter list: %s", out)
+	}
+}
@@ -0,0 +1,1057 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ioserikov/cc_voting/client"
+)
+
+var commands = []command{
+	{"ballot", "create", "start voting with list of voters", ballotcreate},
+	{"ballot", "list", "list all ballots", ballotlist},
+	{"ballot", "show", "show ballot with metadata and voters", ballotshow},
+	{"ballot", "status", "show turnout against quorum and voters without vote", ballotstatus},
+	{"ballot", "close", "close voting, only proposer can", ballotclose},
+	{"ballot", "extend", "request or approve extension of end date, proposer and sponsor orgs can", ballotextend},
+	{"ballot", "cancel", "cancel draft or open voting with reason, only proposer can", ballotcancel},
+	{"ballot", "archive", "archive closed or cancelled voting, only proposer can", ballotarchive},
+	{"vote", "cast", "vote yes, no or neutral, prints receipt", votecast},
+	{"vote", "withdraw", "withdraw own vote while voting is open", votewithdraw},
+	{"result", "show", "show result of voting, -publish writes it in ledger", resultshow},
+	{"result", "export", "export report of voting as csv, json, xml, markdown, html or text", resultexport},
+	{"analytics", "export", "export analytics over all ballots as csv", analyticsexport},
+	{"group", "create", "create group of voters, invoker owns it", groupcreate},
+	{"group", "update", "next version of group, owner and admin orgs can", groupupdate},
+	{"group", "delete", "delete group, owner and admin orgs can", groupdelete},
+	{"group", "show", "show group with members and version", groupshow},
+	{"group", "list", "list all groups", grouplist},
+	{"voter", "register", "register voter bound to MSP and certificate, admin orgs and voter itself can", voterregister},
+	{"voter", "update", "change binding or details of registered voter, -activate activates it; binding and -activate are for admin orgs", voterupdate},
+	{"voter", "deactivate", "deactivate registered voter, it can't vote and can't be voter of new ballots", voterdeactivate},
+	{"voter", "show", "show registered voter", votershow},
+	{"voter", "list", "list registered voters", voterlist},
+	{"config", "show", "show configuration of chaincode and applied migrations", configshow},
+}
+
+// vote options of chaincode
+var voteoptions = []string{client.Yes, client.No, client.Neutral}
+
+// formats of reports of chaincode
+var reportformats = []string{"csv", "json", "jsonl", "xml", "markdown", "html", "text"}
+
+// struct for answer of invoke without payload
+type txanswer struct {
+	VoteID string `json:"voteid"`
+	TxID   string `json:"txid"`
+}
+
+// print answer of invoke without payload
+func (c *cli) printtx(voteid string, tx string) error {
+	return c.print(txanswer{VoteID: voteid, TxID: tx}, func(w io.Writer) {
+		fmt.Fprintf(w, "VOTEID\tTXID\n%s\t%s\n", voteid, tx)
+	})
+}
+
+func status(b client.Ballot) string {
+	return b.StatusAt(time.Now())
+}
+
+// -id flag of commands on one voting
+func voteidflag(fs *flag.FlagSet) *string {
+	return fs.String("id", "", "id of voting (required)")
+}
+
+//----------------- ballot
+
+func ballotcreate(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot create")
+	id := voteidflag(fs)
+	repo := fs.String("repo", "", "url of repository of proposal (required)")
+	end := fs.String("end", "", "end date of voting (required)")
+	title := fs.String("title", "", "title of proposal")
+	description := fs.String("description", "", "description of proposal")
+	proposer := fs.String("proposer", "", "proposer, invoker by default")
+	requireack := fs.Bool("requireack", false, "voters are to acknowledge digests of all artifacts")
+	quorum := fs.Float64("quorum", 0, "percent of voters to vote, no quorum by default")
+	majority := fs.Float64("majority", 0, "percent of counted votes for option of decision")
+	runoff := fs.String("runoff", "", "duration after close or end date of runoff of yes and no, opened by close without decision")
+	maxextend := fs.String("maxextend", "", "maximum total extension of end date as duration, 72h; no extension by default")
+	approvals := fs.Int("approvals", 0, "orgs of proposer and sponsors to approve extension, 1 by default")
+	start := fs.String("start", "", "opening of vote, votes before it are rejected")
+	draft := fs.Bool("draft", false, "start draft, create of proposer with the same id opens it")
+	reason := fs.String("reason", "", "reason of start in transition log")
+	var voters, groups, tags, artifacts, orgs, sponsors, endorsers multiflag
+	fs.Var(&voters, "voter", "voter, repeat for each voter (at least one voter or group)")
+	fs.Var(&groups, "group", "group of voters, its members are voters before -voter ones, repeatable")
+	fs.Var(&tags, "tag", "tag, repeatable")
+	fs.Var(&artifacts, "artifact", "artifact name@sha256:digest or name@git:commit, repeatable")
+	fs.Var(&orgs, "org", "org of voter mspid:voter, repeatable")
+	fs.Var(&sponsors, "sponsor", "MSP ID of org to approve extensions, repeatable")
+	fs.Var(&endorsers, "endorser", "MSP ID of org to endorse amendments of ballot with org of creator, repeatable")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+
+	switch {
+	case *id == "":
+		return required("id")
+	case *repo == "":
+		return required("repo")
+	case *end == "":
+		return required("end")
+	case len(voters) == 0 && len(groups) == 0:
+		return required("voter")
+	}
+
+	req := client.StartRequest{
+		VoteID:      *id,
+		RepoURL:     *repo,
+		EndDate:     *end,
+		Voters:      voters,
+		Groups:      groups,
+		Title:       *title,
+		Description: *description,
+		Proposer:    *proposer,
+		Tags:        tags,
+		RequireAck:  *requireack,
+		Quorum:      *quorum,
+		Majority:    *majority,
+		Runoff:      *runoff,
+		StartDate:   *start,
+		MaxExtend:   *maxextend,
+		Sponsors:    sponsors,
+		Approvals:   *approvals,
+		Endorsers:   endorsers,
+		Draft:       *draft,
+		Reason:      *reason,
+	}
+	for _, arg := range orgs {
+		kv := strings.SplitN(arg, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return usageerror{fmt.Errorf("org %q is not mspid:voter", arg)}
+		}
+		if req.Orgs == nil {
+			req.Orgs = map[string]string{}
+		}
+		req.Orgs[kv[1]] = kv[0]
+	}
+	for _, arg := range artifacts {
+		a, err := artifactfromflag(arg)
+		if err != nil {
+			return usageerror{err}
+		}
+		req.Artifacts = append(req.Artifacts, a)
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.StartBallot(ctx, req)
+	if err != nil {
+		return err
+	}
+	return c.printtx(*id, tx)
+}
+
+// artifact of -artifact name@sha256:digest or name@git:commit, digest is checked by chaincode
+func artifactfromflag(value string) (client.Artifact, error) {
+	at := strings.LastIndex(value, "@")
+	kind := strings.SplitN(value[at+1:], ":", 2)
+	if at <= 0 || len(kind) != 2 {
+		return client.Artifact{}, fmt.Errorf("artifact %q is not name@sha256:digest or name@git:commit", value)
+	}
+
+	a := client.Artifact{Name: value[:at]}
+	switch strings.ToLower(kind[0]) {
+	case "sha256":
+		a.SHA256 = kind[1]
+	case "git":
+		a.Commit = kind[1]
+	default:
+		return client.Artifact{}, fmt.Errorf("artifact %s: unknown digest %q", a.Name, kind[0])
+	}
+	return a, nil
+}
+
+func ballotlist(c *cli, ctx context.Context, args []string) error {
+	if err := parseflags(c.flags("ballot list"), args); err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	ballots, err := cl.Ballots(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.print(ballots, func(w io.Writer) {
+		fmt.Fprintf(w, "VOTEID\tTITLE\tENDDATE\tPROPOSER\tVOTERS\tSTATUS\n")
+		for _, b := range ballots {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", b.VoteID, b.Title, b.EndDate, b.Proposer, len(b.Voters), status(b))
+		}
+	})
+}
+
+func ballotshow(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot show")
+	id := voteidflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	b, err := cl.Ballot(ctx, *id)
+	if err != nil {
+		return err
+	}
+
+	return c.print(b, func(w io.Writer) {
+		fmt.Fprintf(w, "Vote ID:\t%s\n", b.VoteID)
+		fmt.Fprintf(w, "Title:\t%s\n", b.Title)
+		fmt.Fprintf(w, "Description:\t%s\n", b.Description)
+		fmt.Fprintf(w, "Repo Url:\t%s\n", b.RepoURL)
+		fmt.Fprintf(w, "End Date:\t%s\n", b.EndDate)
+		if b.StartDate != "" {
+			fmt.Fprintf(w, "Start Date:\t%s\n", b.StartDate)
+		}
+		fmt.Fprintf(w, "Proposer:\t%s\n", b.Proposer)
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(b.Tags, ", "))
+		fmt.Fprintf(w, "Status:\t%s\n", status(*b))
+		fmt.Fprintf(w, "Voters:\t%s\n", strings.Join(b.Voters, ", "))
+		for _, g := range b.Groups {
+			fmt.Fprintf(w, "Group:\t%s version %d\n", g.Name, g.Version)
+		}
+		for _, a := range b.Artifacts {
+			digest := "sha256:" + a.SHA256
+			if a.SHA256 == "" {
+				digest = "git:" + a.Commit
+			}
+			fmt.Fprintf(w, "Artifact:\t%s %s\n", a.Name, digest)
+		}
+		if b.RequireAck {
+			fmt.Fprintf(w, "Require Ack:\tyes\n")
+		}
+		if b.Quorum != 0 {
+			fmt.Fprintf(w, "Quorum:\t%g%%\n", b.Quorum)
+		}
+		if b.Majority != 0 {
+			fmt.Fprintf(w, "Majority:\t%g%%\n", b.Majority)
+		}
+		if b.Runoff != "" {
+			fmt.Fprintf(w, "Runoff:\t%s\n", b.Runoff)
+		}
+		if b.PrevRound != "" {
+			fmt.Fprintf(w, "Round:\t%d, runoff of %s, options %s\n", b.Round, b.PrevRound, strings.Join(b.Options, ", "))
+		}
+		if b.NextRound != "" {
+			fmt.Fprintf(w, "Next Round:\t%s\n", b.NextRound)
+		}
+		if b.MaxExtend != "" {
+			fmt.Fprintf(w, "Max Extension:\t%s, sponsors %s, approvals %d\n", b.MaxExtend, strings.Join(b.Sponsors, ", "), approvalsof(*b))
+		}
+		if len(b.Endorsers) > 0 {
+			fmt.Fprintf(w, "Endorsers:\t%s\n", strings.Join(b.Endorsers, ", "))
+		}
+		for _, ext := range b.Extensions {
+			fmt.Fprintf(w, "Extension:\t%s -> %s by %s at %s, %s\n", ext.From, ext.EndDate, ext.By, ext.At, extensionstatus(*b, ext))
+		}
+		if a := b.Archive; a != nil {
+			fmt.Fprintf(w, "Archive:\t%s, %d votes, merkle root %s, %d keys pruned\n", a.Decision, len(a.Votes), a.MerkleRoot, a.Pruned)
+		}
+		for _, tr := range b.Transitions {
+			states := tr.To
+			if tr.From != "" {
+				states = tr.From + " -> " + tr.To
+			}
+			fmt.Fprintf(w, "Transition:\t%s by %s at %s", states, tr.By, tr.At)
+			if tr.Reason != "" {
+				fmt.Fprintf(w, ": %s", tr.Reason)
+			}
+			fmt.Fprintln(w)
+		}
+	})
+}
+
+// struct for answer of ballot status
+type statusanswer struct {
+	*client.Status
+	Pending []client.PendingVoter `json:"pending"`
+	Orgs    []client.OrgTurnout   `json:"orgs"`
+}
+
+func ballotstatus(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot status")
+	id := voteidflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	st, err := cl.Status(ctx, *id)
+	if err != nil {
+		return err
+	}
+	p, err := cl.Pending(ctx, *id)
+	if err != nil {
+		return err
+	}
+
+	return c.print(statusanswer{Status: st, Pending: p.Pending, Orgs: p.Orgs}, func(w io.Writer) {
+		fmt.Fprintf(w, "Vote ID:\t%s\n", st.VoteID)
+		fmt.Fprintf(w, "Status:\t%s\n", st.Status)
+		fmt.Fprintf(w, "Turnout:\t%d of %d (%.1f%%)\n", st.Turnout.Voted, st.Turnout.Registered, st.Turnout.Percent)
+		if st.Quorum != 0 {
+			met := "not met, " + strconv.Itoa(st.Missing) + " votes missing"
+			if st.QuorumMet {
+				met = "met"
+			}
+			fmt.Fprintf(w, "Quorum:\t%g%% (%d votes), %s\n", st.Quorum, st.Required, met)
+		}
+		for _, o := range p.Orgs {
+			fmt.Fprintf(w, "Org %s:\t%d of %d (%.1f%%)\n", o.Org, o.Voted, o.Registered, o.Percent)
+		}
+		for _, v := range p.Pending {
+			fmt.Fprintf(w, "Pending:\t%s (%s)\n", v.Voter, v.Org)
+		}
+	})
+}
+
+func ballotclose(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot close")
+	id := voteidflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.CloseBallot(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return c.printtx(*id, tx)
+}
+
+// orgs to approve extension of ballot
+func approvalsof(b client.Ballot) int {
+	if b.Approvals == 0 {
+		return 1
+	}
+	return b.Approvals
+}
+
+// status of extension: applied, pending with approvals or superseded by later request
+func extensionstatus(b client.Ballot, ext client.Extension) string {
+	switch {
+	case ext.AppliedAt != "":
+		return "applied"
+	case ext.At != b.Extensions[len(b.Extensions)-1].At:
+		return "superseded"
+	case len(ext.Approvals) >= approvalsof(b):
+		return "pending, approval of org other than " + ext.Org
+	}
+	return fmt.Sprintf("pending, %d of %d approvals", len(ext.Approvals), approvalsof(b))
+}
+
+func ballotextend(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot extend")
+	id := voteidflag(fs)
+	end := fs.String("end", "", "new end date of voting (required)")
+	reason := fs.String("reason", "", "reason of extension")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *id == "":
+		return required("id")
+	case *end == "":
+		return required("end")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	ext, err := cl.ExtendBallot(ctx, *id, *end, *reason)
+	if err != nil {
+		return err
+	}
+	return c.print(ext, func(w io.Writer) {
+		fmt.Fprintf(w, "Vote ID:\t%s\n", *id)
+		fmt.Fprintf(w, "End Date:\t%s -> %s\n", ext.From, ext.EndDate)
+		fmt.Fprintf(w, "Approvals:\t%d\n", len(ext.Approvals))
+		if ext.AppliedAt != "" {
+			fmt.Fprintf(w, "Applied:\t%s\n", ext.AppliedAt)
+		}
+	})
+}
+
+func ballotcancel(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot cancel")
+	id := voteidflag(fs)
+	reason := fs.String("reason", "", "reason of cancel (required)")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *id == "":
+		return required("id")
+	case *reason == "":
+		return required("reason")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.CancelBallot(ctx, *id, *reason)
+	if err != nil {
+		return err
+	}
+	return c.printtx(*id, tx)
+}
+
+func ballotarchive(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("ballot archive")
+	id := voteidflag(fs)
+	reason := fs.String("reason", "", "reason of archive")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.ArchiveBallot(ctx, *id, *reason)
+	if err != nil {
+		return err
+	}
+	return c.printtx(*id, tx)
+}
+
+//----------------- vote
+
+func votecast(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("vote cast")
+	id := voteidflag(fs)
+	vote := newchoice("", voteoptions...)
+	fs.Var(vote, "vote", "yes, no or neutral (required)")
+	comment := fs.String("comment", "", "comment of vote")
+	var ack multiflag
+	fs.Var(&ack, "ack", "digest of reviewed artifact, repeatable")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *id == "":
+		return required("id")
+	case vote.value == "":
+		return required("vote")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	r, err := cl.CastVote(ctx, client.VoteRequest{VoteID: *id, Vote: vote.value, Comment: *comment, Ack: ack})
+	if err != nil {
+		return err
+	}
+
+	return c.print(r, func(w io.Writer) {
+		fmt.Fprintf(w, "VOTEID\tVOTER\tTXID\tTIMESTAMP\tVOTEHASH\n")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.VoteID, r.Voter, r.TxID, r.Timestamp, r.VoteHash)
+	})
+}
+
+func votewithdraw(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("vote withdraw")
+	id := voteidflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.WithdrawVote(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return c.printtx(*id, tx)
+}
+
+//----------------- result
+
+// struct for flags of filter and order of report
+type reportflags struct {
+	org  *string
+	vote *choice
+	sort *string
+	from *string
+	to   *string
+}
+
+func newreportflags(fs *flag.FlagSet) reportflags {
+	f := reportflags{
+		org:  fs.String("org", "", "only votes of MSP ID"),
+		vote: newchoice("", voteoptions...),
+		sort: fs.String("sort", "", "order of votes: org, vote or time, - prefix for descending"),
+		from: fs.String("from", "", "only votes cast not before, RFC3339"),
+		to:   fs.String("to", "", "only votes cast not after, RFC3339"),
+	}
+	fs.Var(f.vote, "vote", "only votes yes, no or neutral")
+	return f
+}
+
+// filter of report by flags
+func (f reportflags) filter() (client.Filter, error) {
+	filter := client.Filter{Org: *f.org, Vote: f.vote.value, Sort: strings.TrimPrefix(*f.sort, "-"), Desc: strings.HasPrefix(*f.sort, "-")}
+
+	for _, t := range []struct {
+		name  string
+		value string
+		to    *time.Time
+	}{{"from", *f.from, &filter.From}, {"to", *f.to, &filter.To}} {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, t.value)
+		if err != nil {
+			return filter, usageerror{fmt.Errorf("flag -%s: %s", t.name, err)}
+		}
+		*t.to = parsed
+	}
+	return filter, nil
+}
+
+func resultshow(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("result show")
+	id := voteidflag(fs)
+	flags := newreportflags(fs)
+	publish := fs.Bool("publish", false, "count result by invoke and write it in ledger")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+	filter, err := flags.filter()
+	if err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	var rep *client.Report
+	if *publish {
+		rep, err = cl.PublishResult(ctx, *id, filter)
+	} else {
+		rep, err = cl.Result(ctx, *id, filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.print(rep, func(w io.Writer) {
+		fmt.Fprintf(w, "Vote ID:\t%s\n", rep.VoteID)
+		decision := rep.Decision
+		if !rep.Decided && rep.Decision != "equal" {
+			decision += " (no decision)"
+		}
+		fmt.Fprintf(w, "Decision:\t%s\n", decision)
+		fmt.Fprintf(w, "Counts:\tyes %d, no %d, neutral %d\n", rep.Counts[client.Yes], rep.Counts[client.No], rep.Counts[client.Neutral])
+		fmt.Fprintf(w, "Turnout:\t%d of %d (%.1f%%)\n", rep.Turnout.Voted, rep.Turnout.Registered, rep.Turnout.Percent)
+		fmt.Fprintf(w, "Merkle Root:\t%s\n", rep.Certificate.MerkleRoot)
+		if len(rep.Abstained) > 0 {
+			fmt.Fprintf(w, "Abstained:\t%s\n", strings.Join(rep.Abstained, ", "))
+		}
+		for _, r := range rep.Rounds {
+			decision := r.Decision
+			if !r.Closed {
+				decision += " (open)"
+			} else if !r.Decided {
+				decision += " (no decision)"
+			}
+			fmt.Fprintf(w, "Round %d:\t%s %s\n", r.Round, r.VoteID, decision)
+		}
+		fmt.Fprintf(w, "\nVOTER\tORG\tVOTE\tTIME\tCOMMENT\n")
+		for _, v := range rep.Votes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Voter, v.Org, v.Vote, v.Timestamp, v.Comment)
+		}
+	})
+}
+
+func resultexport(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("result export")
+	id := voteidflag(fs)
+	flags := newreportflags(fs)
+	format := newchoice("csv", reportformats...)
+	fs.Var(format, "format", "format of report: "+strings.Join(reportformats, ", "))
+	out := fs.String("out", "", "file of report, stdout by default")
+	delimiter := fs.String("delimiter", "", "csv: comma, semicolon, tab or one symbol, semicolon by default")
+	lineend := newchoice("crlf", "crlf", "lf")
+	fs.Var(lineend, "lineend", "csv: crlf or lf")
+	header := fs.Bool("header", false, "csv: header row")
+	bom := fs.Bool("bom", false, "csv: utf-8 byte order mark")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return required("id")
+	}
+	filter, err := flags.filter()
+	if err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	if format.value == "csv" {
+		payload, err = cl.ExportCSV(ctx, *id, filter, client.CSVOptions{Header: *header, BOM: *bom, Delimiter: *delimiter, LF: lineend.value == "lf"})
+	} else {
+		payload, err = cl.Export(ctx, *id, format.value, filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		return ioutil.WriteFile(*out, payload, 0644)
+	}
+	_, err = c.stdout.Write(payload)
+	return err
+}
+
+//----------------- analytics
+
+// views of analytics of chaincode
+var analyticsviews = []string{client.ViewParticipation, client.ViewOrgTurnout, client.ViewDecision, client.ViewOrgAgreement}
+
+func analyticsexport(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("analytics export")
+	view := newchoice(client.ViewParticipation, analyticsviews...)
+	fs.Var(view, "view", "view: "+strings.Join(analyticsviews, ", "))
+	out := fs.String("out", "", "file of csv, stdout by default")
+	delimiter := fs.String("delimiter", "", "comma, semicolon, tab or one symbol, semicolon by default")
+	lineend := newchoice("crlf", "crlf", "lf")
+	fs.Var(lineend, "lineend", "crlf or lf")
+	header := fs.Bool("header", true, "header row")
+	bom := fs.Bool("bom", false, "utf-8 byte order mark")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	payload, err := cl.AnalyticsCSV(ctx, view.value, client.CSVOptions{Header: *header, BOM: *bom, Delimiter: *delimiter, LF: lineend.value == "lf"})
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		return ioutil.WriteFile(*out, payload, 0644)
+	}
+	_, err = c.stdout.Write(payload)
+	return err
+}
+
+//----------------- group
+
+// -name flag of commands on one group
+func groupnameflag(fs *flag.FlagSet) *string {
+	return fs.String("name", "", "name of group (required)")
+}
+
+// print group with members and version
+func (c *cli) printgroup(g *client.Group) error {
+	return c.print(g, func(w io.Writer) {
+		fmt.Fprintf(w, "Name:\t%s\n", g.Name)
+		fmt.Fprintf(w, "Version:\t%d\n", g.Version)
+		fmt.Fprintf(w, "Description:\t%s\n", g.Description)
+		fmt.Fprintf(w, "Owner:\t%s (%s)\n", g.Owner, g.OwnerOrg)
+		fmt.Fprintf(w, "Members:\t%s\n", strings.Join(g.Members, ", "))
+		if g.UpdatedAt != "" {
+			fmt.Fprintf(w, "Updated:\t%s by %s\n", g.UpdatedAt, g.UpdatedBy)
+		}
+	})
+}
+
+func groupcreate(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("group create")
+	name := groupnameflag(fs)
+	description := fs.String("description", "", "description of group")
+	var members multiflag
+	fs.Var(&members, "member", "voter, repeat for each member (at least one)")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *name == "":
+		return required("name")
+	case len(members) == 0:
+		return required("member")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	g, err := cl.CreateGroup(ctx, *name, members, *description)
+	if err != nil {
+		return err
+	}
+	return c.printgroup(g)
+}
+
+func groupupdate(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("group update")
+	name := groupnameflag(fs)
+	description := fs.String("description", "", "description of group")
+	var members, add, remove multiflag
+	fs.Var(&members, "member", "voter, members replace all members of group, repeatable")
+	fs.Var(&add, "add", "voter to add, repeatable")
+	fs.Var(&remove, "remove", "voter to remove, repeatable")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *name == "":
+		return required("name")
+	case len(members) == 0 && len(add) == 0 && len(remove) == 0 && *description == "":
+		return usageerror{fmt.Errorf("no changes, set -member, -add, -remove or -description")}
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	g, err := cl.UpdateGroup(ctx, *name, client.GroupUpdate{Members: members, Add: add, Remove: remove, Description: *description})
+	if err != nil {
+		return err
+	}
+	return c.printgroup(g)
+}
+
+func groupdelete(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("group delete")
+	name := groupnameflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return required("name")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := cl.DeleteGroup(ctx, *name)
+	if err != nil {
+		return err
+	}
+	return c.print(struct {
+		Name string `json:"name"`
+		TxID string `json:"txid"`
+	}{*name, tx}, func(w io.Writer) {
+		fmt.Fprintf(w, "NAME\tTXID\n%s\t%s\n", *name, tx)
+	})
+}
+
+func groupshow(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("group show")
+	name := groupnameflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return required("name")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	g, err := cl.Group(ctx, *name)
+	if err != nil {
+		return err
+	}
+	return c.printgroup(g)
+}
+
+func grouplist(c *cli, ctx context.Context, args []string) error {
+	if err := parseflags(c.flags("group list"), args); err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	groups, err := cl.Groups(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.print(groups, func(w io.Writer) {
+		fmt.Fprintf(w, "NAME\tVERSION\tOWNER\tMEMBERS\n")
+		for _, g := range groups {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", g.Name, g.Version, g.Owner, len(g.Members))
+		}
+	})
+}
+
+//----------------- voter
+
+// -id flag of commands on one registered voter
+func voterflag(fs *flag.FlagSet) *string {
+	return fs.String("id", "", "id of voter (required)")
+}
+
+// flags of binding and details of registered voter
+func voterdetailsflags(fs *flag.FlagSet) func() client.VoterDetails {
+	mspid := fs.String("mspid", "", "MSP ID of voter")
+	fingerprint := fs.String("fingerprint", "", "sha256 of certificate of voter, hex")
+	subject := fs.String("subject", "", "common name of subject of certificate of voter")
+	name := fs.String("name", "", "display name of voter")
+	org := fs.String("org", "", "organization of voter")
+	return func() client.VoterDetails {
+		return client.VoterDetails{MSPID: *mspid, Fingerprint: *fingerprint, Subject: *subject, Name: *name, Org: *org}
+	}
+}
+
+// print registered voter
+func (c *cli) printvoter(r *client.RegisteredVoter) error {
+	return c.print(r, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", r.ID)
+		fmt.Fprintf(w, "MSP ID:\t%s\n", r.MSPID)
+		if r.Fingerprint != "" {
+			fmt.Fprintf(w, "Fingerprint:\t%s\n", r.Fingerprint)
+		}
+		if r.Subject != "" {
+			fmt.Fprintf(w, "Subject:\t%s\n", r.Subject)
+		}
+		fmt.Fprintf(w, "Name:\t%s\n", r.Name)
+		fmt.Fprintf(w, "Org:\t%s\n", r.Org)
+		active := "yes"
+		if !r.Active {
+			active = "no"
+			if r.Reason != "" {
+				active += ", " + r.Reason
+			}
+		}
+		fmt.Fprintf(w, "Active:\t%s\n", active)
+		fmt.Fprintf(w, "Registered:\t%s by %s\n", r.RegisteredAt, r.RegisteredBy)
+		if r.UpdatedAt != "" {
+			fmt.Fprintf(w, "Updated:\t%s by %s\n", r.UpdatedAt, r.UpdatedBy)
+		}
+	})
+}
+
+func voterregister(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("voter register")
+	voter := voterflag(fs)
+	details := voterdetailsflags(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	d := details()
+	switch {
+	case *voter == "":
+		return required("id")
+	case d.MSPID == "":
+		return required("mspid")
+	case d.Fingerprint == "" && d.Subject == "":
+		return usageerror{fmt.Errorf("voter is to be bound to certificate, set -fingerprint or -subject")}
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	r, err := cl.RegisterVoter(ctx, *voter, d)
+	if err != nil {
+		return err
+	}
+	return c.printvoter(r)
+}
+
+func voterupdate(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("voter update")
+	voter := voterflag(fs)
+	details := voterdetailsflags(fs)
+	activate := fs.Bool("activate", false, "activate deactivated voter")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	d := details()
+	switch {
+	case *voter == "":
+		return required("id")
+	case d == client.VoterDetails{} && !*activate:
+		return usageerror{fmt.Errorf("no changes, set details of voter or -activate")}
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	r, err := cl.UpdateVoter(ctx, *voter, d, *activate)
+	if err != nil {
+		return err
+	}
+	return c.printvoter(r)
+}
+
+func voterdeactivate(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("voter deactivate")
+	voter := voterflag(fs)
+	reason := fs.String("reason", "", "reason of deactivation")
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *voter == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	r, err := cl.DeactivateVoter(ctx, *voter, *reason)
+	if err != nil {
+		return err
+	}
+	return c.printvoter(r)
+}
+
+func votershow(c *cli, ctx context.Context, args []string) error {
+	fs := c.flags("voter show")
+	voter := voterflag(fs)
+	if err := parseflags(fs, args); err != nil {
+		return err
+	}
+	if *voter == "" {
+		return required("id")
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	r, err := cl.Voter(ctx, *voter)
+	if err != nil {
+		return err
+	}
+	return c.printvoter(r)
+}
+
+func voterlist(c *cli, ctx context.Context, args []string) error {
+	if err := parseflags(c.flags("voter list"), args); err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	voters, err := cl.Voters(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.print(voters, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\tMSPID\tNAME\tORG\tACTIVE\n")
+		for _, r := range voters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", r.ID, r.MSPID, r.Name, r.Org, r.Active)
+		}
+	})
+}
+
+//----------------- config
+
+func configshow(c *cli, ctx context.Context, args []string) error {
+	if err := parseflags(c.flags("config show"), args); err != nil {
+		return err
+	}
+
+	cl, err := c.connection()
+	if err != nil {
+		return err
+	}
+	cfg, err := cl.Config(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.print(cfg, func(w io.Writer) {
+		fmt.Fprintf(w, "Schema Version:\t%d\n", cfg.Version)
+		if len(cfg.Admins) > 0 {
+			fmt.Fprintf(w, "Admins:\t%s\n", strings.Join(cfg.Admins, ", "))
+		}
+		options := cfg.Options
+		if len(options) == 0 {
+			options = voteoptions
+		}
+		fmt.Fprintf(w, "Options:\t%s\n", strings.Join(options, ", "))
+		rule := cfg.TallyRule
+		if cfg.TallyRule == client.TallyMajority {
+			rule += fmt.Sprintf(" %g%%", cfg.Majority)
+		}
+		fmt.Fprintf(w, "Tally Rule:\t%s\n", rule)
+		fmt.Fprintf(w, "Identity:\t%s\n", cfg.IdentityMode)
+		if cfg.MaxVoters != 0 {
+			fmt.Fprintf(w, "Max Voters:\t%d\n", cfg.MaxVoters)
+		}
+		if cfg.Registry {
+			fmt.Fprintf(w, "Registry:\trequired\n")
+		}
+		for _, m := range cfg.Migrations {
+			fmt.Fprintf(w, "Migration:\t%d %s, %d records at %s\n", m.Version, m.Name, m.Records, m.At)
+		}
+	})
+}
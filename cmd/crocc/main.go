@@ -0,0 +1,45 @@
+// crocc - command line client of cc_voting chaincode.
+//
+//	crocc [global flags] ballot create|list|show|close [flags]
+//	crocc [global flags] vote cast|withdraw [flags]
+//	crocc [global flags] result show|export [flags]
+//
+// Global flags select channel, chaincode, identity (MSP directory and MSP ID) and output (table or json).
+// Calls go through Invoker of hlf-sdk-go, run crocc help for flags of commands.
+//
+// Client of hlf-sdk-go is not in vendor, so crocc built from this tree is a stub: every command which calls
+// chaincode fails with error of connection until newinvoker is set to Invoker of a Fabric network.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// invoker of Fabric network by config of connection - to be set in init of file built with client of hlf-sdk-go,
+// which is not in vendor yet
+var newinvoker = noinvoker
+
+// notice of usage of stub build
+const stubnotice = "crocc is a stub: it is built without Fabric client of hlf-sdk-go and can't connect to network,\n" +
+	"commands which call chaincode fail; add client of hlf-sdk-go to vendor and set newinvoker of cmd/crocc"
+
+func main() {
+	c := &cli{stdout: os.Stdout, stderr: os.Stderr, connect: newinvoker}
+	if stub {
+		c.notice = stubnotice
+	}
+	os.Exit(c.run(context.Background(), os.Args[1:]))
+}
+
+// crocc connects by noinvoker, init of file with client of hlf-sdk-go resets it with newinvoker
+var stub = true
+
+// error of connection when crocc is built without client of hlf-sdk-go
+func noinvoker(config string, identity msp.SigningIdentity) (api.Invoker, error) {
+	return nil, fmt.Errorf("crocc is a stub built without Fabric client of hlf-sdk-go, add it to vendor and set newinvoker")
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ioserikov/cc_voting/client"
+)
+
+// codes of errors of gateway, errors of chaincode keep their codes
+const (
+	CodeInvalidRequest   = "INVALID_REQUEST"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	CodeNoIdentity       = "NO_IDENTITY"
+	CodeUnauthenticated  = "UNAUTHENTICATED"
+	CodeGateway          = "GATEWAY_ERROR" // network or peers failed, not chaincode
+)
+
+func errinvalid(field string, message string) *client.Error {
+	return &client.Error{Code: CodeInvalidRequest, Status: http.StatusBadRequest, Message: message, Details: map[string]string{"field": field}}
+}
+
+func errnotfound(path string) *client.Error {
+	return &client.Error{Code: CodeNotFound, Status: http.StatusNotFound, Message: "no route " + path, Details: map[string]string{"path": path}}
+}
+
+func errmethod(method string, path string) *client.Error {
+	return &client.Error{Code: CodeMethodNotAllowed, Status: http.StatusMethodNotAllowed, Message: method + " is not allowed for " + path,
+		Details: map[string]string{"method": method, "path": path}}
+}
+
+func errnoidentity() *client.Error {
+	return &client.Error{Code: CodeNoIdentity, Status: http.StatusUnauthorized, Message: "Authorization: Bearer <token> header is missing"}
+}
+
+func errunauthenticated() *client.Error {
+	return &client.Error{Code: CodeUnauthenticated, Status: http.StatusUnauthorized, Message: "token of request is bound to no identity"}
+}
+
+// write error as json - status of errors of chaincode and gateway, 502 for others
+func writeerror(w http.ResponseWriter, err error) {
+	e, ok := err.(*client.Error)
+	if !ok || e.Status < 400 || e.Status > 599 {
+		e = &client.Error{Code: CodeGateway, Status: http.StatusBadGateway, Message: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(e.Status))
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,321 @@
+// Package gateway - HTTP/REST gateway of cc_voting chaincode. Calls go through Invoker of hlf-sdk-go
+// by typed client, from identity of wallet bound to bearer token of caller.
+//
+//	POST /ballots                   start voting
+//	GET  /ballots                   ballots in order of ids, ?limit=&after= give pages
+//	GET  /ballots/{id}              ballot with metadata and voters
+//	POST /ballots/{id}/votes        vote of identity of request, returns receipt
+//	GET  /ballots/{id}/result       report of voting by query
+//	GET  /ballots/{id}/result.csv   report of voting as csv
+//	GET  /openapi.json              OpenAPI description of gateway
+//
+// Callers authenticate by Authorization: Bearer <token> header, every token is bound to one identity
+// of wallet, requests without known token are rejected. Tokens are secrets of callers - serve gateway over TLS.
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// minimum length of bearer token
+const mintoken = 16
+
+// pages of ballots
+const (
+	defaultlimit = 20
+	maxlimit     = 100
+)
+
+// Gateway - http.Handler of REST API of chaincode
+type Gateway struct {
+	client     *client.Client
+	identities map[string]msp.SigningIdentity
+	tokens     map[string]string // identity of wallet by bearer token of caller
+}
+
+// New gateway to chaincode on channel. Tokens bind bearer tokens of callers to identities of wallet,
+// gateway without tokens is not created - it does not serve unauthenticated callers
+func New(invoker api.Invoker, channel string, chaincode string, identities map[string]msp.SigningIdentity, tokens map[string]string) (*Gateway, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no identities")
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens of callers, gateway does not serve unauthenticated requests")
+	}
+	for token, name := range tokens {
+		if _, ok := identities[name]; !ok {
+			return nil, fmt.Errorf("no identity %s of token", name)
+		}
+		if len(token) < mintoken {
+			return nil, fmt.Errorf("token of %s is shorter than %d", name, mintoken)
+		}
+	}
+	return &Gateway{client: client.New(invoker, nil, channel, chaincode), identities: identities, tokens: tokens}, nil
+}
+
+// ServeHTTP routes request by method and path
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		u, err := url.PathUnescape(s)
+		if err != nil {
+			writeerror(w, errnotfound(r.URL.Path))
+			return
+		}
+		segments[i] = u
+	}
+
+	switch {
+	case path == "openapi.json" && r.Method == http.MethodGet: // no identity is needed
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(openapidoc))
+	case path == "ballots":
+		g.route(w, r, nil, map[string]handler{http.MethodGet: (*Gateway).ballots, http.MethodPost: (*Gateway).start})
+	case len(segments) == 2 && segments[0] == "ballots":
+		g.route(w, r, segments[1:], map[string]handler{http.MethodGet: (*Gateway).ballot})
+	case len(segments) == 3 && segments[0] == "ballots" && segments[2] == "votes":
+		g.route(w, r, segments[1:2], map[string]handler{http.MethodPost: (*Gateway).vote})
+	case len(segments) == 3 && segments[0] == "ballots" && segments[2] == "result":
+		g.route(w, r, segments[1:2], map[string]handler{http.MethodGet: (*Gateway).result})
+	case len(segments) == 3 && segments[0] == "ballots" && segments[2] == "result.csv":
+		g.route(w, r, segments[1:2], map[string]handler{http.MethodGet: (*Gateway).resultcsv})
+	default:
+		writeerror(w, errnotfound(r.URL.Path))
+	}
+}
+
+// handler of route with client of identity of request and path params
+type handler func(g *Gateway, w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error
+
+// call handler of method with client of identity of request, write error if any
+func (g *Gateway) route(w http.ResponseWriter, r *http.Request, params []string, methods map[string]handler) {
+	h, ok := methods[r.Method]
+	if !ok {
+		allowed := []string{}
+		for m := range methods {
+			allowed = append(allowed, m)
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeerror(w, errmethod(r.Method, r.URL.Path))
+		return
+	}
+
+	c, err := g.clientof(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeerror(w, err)
+		return
+	}
+	if err := h(g, w, r, c, params); err != nil {
+		writeerror(w, err)
+	}
+}
+
+// client of identity bound to bearer token of request
+func (g *Gateway) clientof(r *http.Request) (*client.Client, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errnoidentity()
+	}
+	name, ok := g.caller(strings.TrimPrefix(auth, "Bearer "))
+	if !ok {
+		return nil, errunauthenticated()
+	}
+	return g.client.WithIdentity(g.identities[name]), nil
+}
+
+// identity of wallet bound to token, every token is compared in constant time
+func (g *Gateway) caller(token string) (string, bool) {
+	name, found := "", false
+	for t, n := range g.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			name, found = n, true
+		}
+	}
+	return name, found
+}
+
+//----------------- ballots
+
+// POST /ballots
+func (g *Gateway) start(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	req := ballotrequest{}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	tx, err := c.StartBallot(r.Context(), req.startrequest())
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/ballots/"+url.PathEscape(req.VoteID))
+	return writejson(w, http.StatusCreated, txanswer{VoteID: req.VoteID, TxID: tx})
+}
+
+// GET /ballots?limit=&after=
+func (g *Gateway) ballots(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	q := r.URL.Query()
+	limit := defaultlimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxlimit {
+			return errinvalid("limit", fmt.Sprintf("limit is to be number from 1 to %d", maxlimit))
+		}
+		limit = n
+	}
+
+	// one more ballot tells whether there is next page
+	ballots, err := c.BallotsPage(r.Context(), q.Get("after"), limit+1)
+	if err != nil {
+		return err
+	}
+	page := ballotspage{Items: ballots}
+	if len(ballots) > limit {
+		page.Items = ballots[:limit]
+		page.Next = ballots[limit-1].VoteID
+	}
+	return writejson(w, http.StatusOK, page)
+}
+
+// GET /ballots/{id}
+func (g *Gateway) ballot(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	b, err := c.Ballot(r.Context(), params[0])
+	if err != nil {
+		return err
+	}
+	return writejson(w, http.StatusOK, b)
+}
+
+//----------------- votes
+
+// POST /ballots/{id}/votes
+func (g *Gateway) vote(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	req := voterequest{}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	receipt, err := c.CastVote(r.Context(), client.VoteRequest{VoteID: params[0], Vote: req.Vote, Comment: req.Comment, Ack: req.Ack})
+	if err != nil {
+		return err
+	}
+	return writejson(w, http.StatusCreated, receipt)
+}
+
+//----------------- results
+
+// GET /ballots/{id}/result?org=&vote=&from=&to=&sort=
+func (g *Gateway) result(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	filter, err := filterof(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	rep, err := c.Result(r.Context(), params[0], filter)
+	if err != nil {
+		return err
+	}
+	return writejson(w, http.StatusOK, rep)
+}
+
+// GET /ballots/{id}/result.csv?...&header=&bom=&delimiter=&lineend=
+func (g *Gateway) resultcsv(w http.ResponseWriter, r *http.Request, c *client.Client, params []string) error {
+	q := r.URL.Query()
+	filter, err := filterof(q)
+	if err != nil {
+		return err
+	}
+	opts := client.CSVOptions{Delimiter: q.Get("delimiter")}
+	for _, kv := range []struct {
+		name string
+		flag *bool
+	}{{"header", &opts.Header}, {"bom", &opts.BOM}} {
+		if v := q.Get(kv.name); v != "" {
+			if *kv.flag, err = strconv.ParseBool(v); err != nil {
+				return errinvalid(kv.name, kv.name+" is to be true or false")
+			}
+		}
+	}
+	switch q.Get("lineend") {
+	case "", "crlf":
+	case "lf":
+		opts.LF = true
+	default:
+		return errinvalid("lineend", "lineend is to be crlf or lf")
+	}
+
+	csv, err := c.ExportCSV(r.Context(), params[0], filter, opts)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", params[0]+".csv"))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(csv)
+	return err
+}
+
+// filter of report by query params
+func filterof(q url.Values) (client.Filter, error) {
+	f := client.Filter{Org: q.Get("org"), Vote: q.Get("vote")}
+	if f.Vote != "" && !isvote(f.Vote) {
+		return f, errinvalid("vote", "vote is to be yes, no or neutral")
+	}
+	for _, kv := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(kv.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, errinvalid(kv.name, kv.name+" is to be RFC3339 time")
+			}
+			*kv.t = t
+		}
+	}
+	if sort := q.Get("sort"); sort != "" {
+		f.Desc = strings.HasPrefix(sort, "-")
+		f.Sort = strings.TrimPrefix(sort, "-")
+		if f.Sort != "org" && f.Sort != "vote" && f.Sort != "time" {
+			return f, errinvalid("sort", "sort is to be org, vote or time, - before it for reverse order")
+		}
+	}
+	return f, nil
+}
+
+//----------------- answers
+
+// answer of invokes without answer of chaincode
+type txanswer struct {
+	VoteID string `json:"voteid"`
+	TxID   string `json:"txid"`
+}
+
+// page of ballots, next is after param of next page, empty for last page
+type ballotspage struct {
+	Items []client.Ballot `json:"items"`
+	Next  string          `json:"next,omitempty"`
+}
+
+func writejson(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
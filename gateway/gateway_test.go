@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/msp"
+	"github.com/ioserikov/cc_voting/chaincode"
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/wallet"
+	"github.com/ioserikov/cc_voting/wallet/wallettest"
+	cckit "github.com/s7techlab/cckit/testing"
+)
+
+// chaincode names voters by common name of CA of their certificates
+const (
+	voter1 = "ca.org1.example.com"
+	voter2 = "ca.org2.example.com"
+)
+
+// bearer tokens of callers by identities of wallet, requests without identity call as Org1MSP/User1
+var testtokens = map[string]string{
+	"Org1MSP/User1": "token-of-org1-user1",
+	"Org2MSP/User1": "token-of-org2-user1",
+	"":              "token-of-org1-user1",
+}
+
+// gateway server to chaincode in MockStub behind MockInvoker, with identities Org1MSP/User1 and Org2MSP/User1
+// bound to testtokens
+func testgateway(t *testing.T) *httptest.Server {
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wallettest.MSPDir(filepath.Join(dir, "Org1MSP", "User1"), voter1, "User1@org1.example.com")
+	wallettest.MSPDir(filepath.Join(dir, "Org2MSP", "User1"), voter2, "User1@org2.example.com")
+	loaded, err := wallet.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities := map[string]msp.SigningIdentity{}
+	for name, i := range loaded {
+		identities[name] = i
+	}
+
+	invoker := cckit.NewInvoker().WithChannel("mychannel", cckit.NewMockStub("crocc", new(chaincode.SimpleChaincode)))
+	g, err := New(invoker, "mychannel", "crocc", identities, map[string]string{
+		testtokens["Org1MSP/User1"]: "Org1MSP/User1",
+		testtokens["Org2MSP/User1"]: "Org2MSP/User1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(g)
+}
+
+// request to server by token of identity, token as is if identity is not in testtokens; returns status and body
+func call(t *testing.T, srv *httptest.Server, identity string, method string, path string, body string) (int, http.Header, []byte) {
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, ok := testtokens[identity]
+	if !ok {
+		token = identity
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	return res.StatusCode, res.Header, b
+}
+
+func TestGateway_Voting(t *testing.T) {
+	srv := testgateway(t)
+	defer srv.Close()
+
+	for _, id := range []string{"Vote1", "Vote2", "Vote3"} {
+		status, header, body := call(t, srv, "", http.MethodPost, "/ballots",
+			`{"voteid":"`+id+`","repourl":"https://git.repo","enddate":"01.05.2099","voters":["`+voter1+`","`+voter2+`"],"title":"Release"}`)
+		if status != http.StatusCreated || header.Get("Location") != "/ballots/"+id {
+			t.Fatalf("start %s: %d %s", id, status, body)
+		}
+	}
+
+	// pages of two ballots
+	status, _, body := call(t, srv, "", http.MethodGet, "/ballots?limit=2", "")
+	page := ballotspage{}
+	json.Unmarshal(body, &page)
+	if status != http.StatusOK || len(page.Items) != 2 || page.Next != "Vote2" {
+		t.Errorf("first page: %d %s", status, body)
+	}
+	_, _, body = call(t, srv, "", http.MethodGet, "/ballots?limit=2&after="+page.Next, "")
+	page = ballotspage{}
+	json.Unmarshal(body, &page)
+	if len(page.Items) != 1 || page.Items[0].VoteID != "Vote3" || page.Next != "" {
+		t.Errorf("last page: %s", body)
+	}
+
+	status, _, body = call(t, srv, "Org2MSP/User1", http.MethodGet, "/ballots/Vote1", "")
+	b := client.Ballot{}
+	json.Unmarshal(body, &b)
+	if status != http.StatusOK || b.Title != "Release" || b.Proposer != voter1 {
+		t.Errorf("ballot: %d %s", status, body)
+	}
+
+	// votes of identities of requests
+	for _, v := range []struct{ identity, vote, voter string }{{"Org1MSP/User1", "yes", voter1}, {"Org2MSP/User1", "no", voter2}} {
+		status, _, body := call(t, srv, v.identity, http.MethodPost, "/ballots/Vote1/votes", `{"vote":"`+v.vote+`","comment":"ok"}`)
+		r := client.Receipt{}
+		json.Unmarshal(body, &r)
+		if status != http.StatusCreated || r.Voter != v.voter {
+			t.Errorf("vote of %s: %d %s", v.identity, status, body)
+		}
+	}
+
+	status, _, body = call(t, srv, "", http.MethodGet, "/ballots/Vote1/result?sort=-vote", "")
+	rep := client.Report{}
+	json.Unmarshal(body, &rep)
+	if status != http.StatusOK || rep.Decision != "equal" || len(rep.Votes) != 2 || rep.Votes[0].Vote != "yes" {
+		t.Errorf("result: %d %s", status, body)
+	}
+
+	status, header, body := call(t, srv, "", http.MethodGet, "/ballots/Vote1/result.csv?header=true&delimiter=comma&lineend=lf&org=Org2MSP", "")
+	expected := "voteid,repourl,enddate,voter,vote,result,comment\nVote1,https://git.repo,01.05.2099," + voter2 + ",no,equal,ok\n"
+	if status != http.StatusOK || !strings.HasPrefix(header.Get("Content-Type"), "text/csv") || string(body) != expected {
+		t.Errorf("result.csv: %d %q", status, body)
+	}
+}
+
+func TestGateway_Errors(t *testing.T) {
+	srv := testgateway(t)
+	defer srv.Close()
+	call(t, srv, "", http.MethodPost, "/ballots", `{"voteid":"Vote1","repourl":"https://git.repo","enddate":"01.05.2099","voters":["`+voter2+`"]}`)
+
+	cases := []struct {
+		identity, method, path, body string
+		status                       int
+		code                         string
+	}{
+		{"", http.MethodPost, "/ballots", `{"voteid":"Vote2"`, 400, CodeInvalidRequest},
+		{"", http.MethodPost, "/ballots", `{"voteid":"Vote2","repourl":"https://git.repo","enddate":"01.05.2099","voters":["a"],"owner":"x"}`, 400, CodeInvalidRequest},
+		{"", http.MethodPost, "/ballots", `{"voteid":"Vote2","repourl":"git.repo","enddate":"01.05.2099","voters":["a"]}`, 400, CodeInvalidRequest},
+		{"", http.MethodPost, "/ballots", `{"voteid":"Vote2","repourl":"https://git.repo","enddate":"01.05.2099","voters":["a","a"]}`, 400, CodeInvalidRequest},
+		{"", http.MethodPost, "/ballots", `{"voteid":"Vote2","repourl":"https://git.repo","enddate":"01.05.2099","voters":["a"],"artifacts":[{"name":"x","sha256":"ab"}]}`, 400, CodeInvalidRequest},
+		{"", http.MethodPost, "/ballots/Vote1/votes", `{"vote":"maybe"}`, 400, CodeInvalidRequest},
+		{"", http.MethodGet, "/ballots?limit=1000", "", 400, CodeInvalidRequest},
+		{"", http.MethodGet, "/ballots/Vote1/result?sort=voter", "", 400, CodeInvalidRequest},
+		{"", http.MethodGet, "/ballots/Vote1/result.csv?lineend=cr", "", 400, CodeInvalidRequest},
+		{"", http.MethodDelete, "/ballots/Vote1", "", 405, CodeMethodNotAllowed},
+		{"", http.MethodGet, "/votes", "", 404, CodeNotFound},
+		{"forged-token-of-org2-user1", http.MethodGet, "/ballots", "", 401, CodeUnauthenticated},
+
+		// errors of chaincode keep their codes and statuses
+		{"", http.MethodGet, "/ballots/NoVote", "", 404, client.CodeBallotNotFound},
+		{"", http.MethodPost, "/ballots/Vote1/votes", `{"vote":"yes"}`, 403, client.CodeNotEligible},
+	}
+
+	for _, c := range cases {
+		status, _, body := call(t, srv, c.identity, c.method, c.path, c.body)
+		e := client.Error{}
+		if err := json.Unmarshal(body, &e); err != nil || status != c.status || e.Code != c.code || int(e.Status) != status {
+			t.Errorf("%s %s %s: %d %s, expected %d %s", c.method, c.path, c.body, status, body, c.status, c.code)
+		}
+	}
+}
+
+func TestGateway_NoIdentity(t *testing.T) {
+	identities := map[string]msp.SigningIdentity{"Org1MSP/User1": nil, "Org2MSP/User1": nil}
+	g, err := New(cckit.NewInvoker(), "mychannel", "crocc", identities, map[string]string{testtokens["Org1MSP/User1"]: "Org1MSP/User1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ballots", nil))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), CodeNoIdentity) || w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("request without token: %d %s", w.Code, w.Body)
+	}
+
+	// identity is bound to token, header of identity of earlier gateway selects nothing
+	r := httptest.NewRequest(http.MethodGet, "/ballots", nil)
+	r.Header.Set("X-Fabric-Identity", "Org2MSP/User1")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("request with identity header and no token: %d %s", w.Code, w.Body)
+	}
+	r.Header.Set("Authorization", "Bearer "+testtokens["Org2MSP/User1"])
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), CodeUnauthenticated) {
+		t.Errorf("request with token of other gateway: %d %s", w.Code, w.Body)
+	}
+
+	for _, tokens := range []map[string]string{nil, {testtokens["Org1MSP/User1"]: "Org3MSP/User1"}, {"short": "Org1MSP/User1"}} {
+		if _, err := New(cckit.NewInvoker(), "mychannel", "crocc", identities, tokens); err == nil {
+			t.Errorf("gateway with tokens %v", tokens)
+		}
+	}
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	srv := testgateway(t)
+	defer srv.Close()
+
+	status, _, body := call(t, srv, "", http.MethodGet, "/openapi.json", "")
+	doc := struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}{}
+	if err := json.Unmarshal(body, &doc); err != nil || status != http.StatusOK || doc.OpenAPI == "" {
+		t.Fatalf("openapi: %d %v", status, err)
+	}
+	for _, path := range []string{"/ballots", "/ballots/{id}", "/ballots/{id}/votes", "/ballots/{id}/result", "/ballots/{id}/result.csv"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("no path %s in openapi", path)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package gateway
+
+// OpenAPI 3 description of gateway, served at /openapi.json
+const openapidoc = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "cc_voting gateway",
+    "version": "1.0.0",
+    "description": "REST API of cc_voting chaincode. Calls are made from identity of wallet bound to bearer token of caller."
+  },
+  "security": [{"bearer": []}],
+  "paths": {
+    "/ballots": {
+      "post": {
+        "summary": "Start voting",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BallotRequest"}}}},
+        "responses": {
+          "201": {"description": "Voting is started", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TxAnswer"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      },
+      "get": {
+        "summary": "Ballots in order of ids",
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100, "default": 20}},
+          {"name": "after", "in": "query", "description": "next of previous page", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Page of ballots", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BallotsPage"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/ballots/{id}": {
+      "get": {
+        "summary": "Ballot with metadata and voters",
+        "parameters": [{"$ref": "#/components/parameters/id"}],
+        "responses": {
+          "200": {"description": "Ballot", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Ballot"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/ballots/{id}/votes": {
+      "post": {
+        "summary": "Vote of identity of request",
+        "parameters": [{"$ref": "#/components/parameters/id"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/VoteRequest"}}}},
+        "responses": {
+          "201": {"description": "Receipt of vote", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Receipt"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/ballots/{id}/result": {
+      "get": {
+        "summary": "Report of voting, nothing is written in ledger",
+        "parameters": [
+          {"$ref": "#/components/parameters/id"},
+          {"$ref": "#/components/parameters/org"}, {"$ref": "#/components/parameters/vote"},
+          {"$ref": "#/components/parameters/from"}, {"$ref": "#/components/parameters/to"}, {"$ref": "#/components/parameters/sort"}
+        ],
+        "responses": {
+          "200": {"description": "Report", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Report"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      }
+    },
+    "/ballots/{id}/result.csv": {
+      "get": {
+        "summary": "Report of voting as csv",
+        "parameters": [
+          {"$ref": "#/components/parameters/id"},
+          {"$ref": "#/components/parameters/org"}, {"$ref": "#/components/parameters/vote"},
+          {"$ref": "#/components/parameters/from"}, {"$ref": "#/components/parameters/to"}, {"$ref": "#/components/parameters/sort"},
+          {"name": "header", "in": "query", "schema": {"type": "boolean", "default": false}},
+          {"name": "bom", "in": "query", "schema": {"type": "boolean", "default": false}},
+          {"name": "delimiter", "in": "query", "description": "comma, semicolon, tab or one symbol", "schema": {"type": "string", "default": "semicolon"}},
+          {"name": "lineend", "in": "query", "schema": {"type": "string", "enum": ["crlf", "lf"], "default": "crlf"}}
+        ],
+        "responses": {
+          "200": {"description": "Rows of votes, then rows of abstained voters", "content": {"text/csv": {"schema": {"type": "string"}}}},
+          "default": {"$ref": "#/components/responses/error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer", "description": "token of caller, bound to identity of wallet <MSP ID>/<name>"}
+    },
+    "parameters": {
+      "id": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      "org": {"name": "org", "in": "query", "description": "MSP ID of voter", "schema": {"type": "string"}},
+      "vote": {"name": "vote", "in": "query", "schema": {"$ref": "#/components/schemas/VoteOption"}},
+      "from": {"name": "from", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "to": {"name": "to", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+      "sort": {"name": "sort", "in": "query", "schema": {"type": "string", "enum": ["org", "vote", "time", "-org", "-vote", "-time"]}}
+    },
+    "responses": {
+      "error": {"description": "Error of gateway or chaincode", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    },
+    "schemas": {
+      "VoteOption": {"type": "string", "enum": ["yes", "no", "neutral"]},
+      "Artifact": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {"name": {"type": "string"}, "sha256": {"type": "string", "pattern": "^[0-9a-fA-F]{64}$"}, "commit": {"type": "string", "pattern": "^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$"}}
+      },
+      "BallotRequest": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["voteid", "repourl", "enddate", "voters"],
+        "properties": {
+          "voteid": {"type": "string"},
+          "repourl": {"type": "string", "format": "uri"},
+          "enddate": {"type": "string"},
+          "voters": {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"type": "string"}},
+          "title": {"type": "string"},
+          "description": {"type": "string"},
+          "proposer": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "artifacts": {"type": "array", "items": {"$ref": "#/components/schemas/Artifact"}},
+          "requireack": {"type": "boolean"}
+        }
+      },
+      "VoteRequest": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["vote"],
+        "properties": {
+          "vote": {"$ref": "#/components/schemas/VoteOption"},
+          "comment": {"type": "string"},
+          "ack": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "TxAnswer": {"type": "object", "properties": {"voteid": {"type": "string"}, "txid": {"type": "string"}}},
+      "Ballot": {
+        "type": "object",
+        "properties": {
+          "voteid": {"type": "string"}, "repourl": {"type": "string"}, "enddate": {"type": "string"},
+          "voters": {"type": "array", "items": {"type": "string"}},
+          "title": {"type": "string"}, "description": {"type": "string"}, "proposer": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "artifacts": {"type": "array", "items": {"$ref": "#/components/schemas/Artifact"}},
+          "requireack": {"type": "boolean"}, "closed": {"type": "boolean"}, "closedat": {"type": "string", "format": "date-time"}
+        }
+      },
+      "BallotsPage": {
+        "type": "object",
+        "properties": {
+          "items": {"type": "array", "items": {"$ref": "#/components/schemas/Ballot"}},
+          "next": {"type": "string", "description": "after of next page, missing for last page"}
+        }
+      },
+      "Receipt": {"type": "object", "description": "receipt of vote, as chaincode returns it", "additionalProperties": true},
+      "Report": {"type": "object", "description": "report of voting, as chaincode returns it", "additionalProperties": true},
+      "Error": {
+        "type": "object",
+        "properties": {
+          "code": {"type": "string"}, "status": {"type": "integer"}, "message": {"type": "string"},
+          "details": {"type": "object", "additionalProperties": {"type": "string"}}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ioserikov/cc_voting/client"
+)
+
+const maxbody = 1 << 20 // bytes of request body
+
+// body of POST /ballots
+type ballotrequest struct {
+	VoteID      string            `json:"voteid"`
+	RepoURL     string            `json:"repourl"`
+	EndDate     string            `json:"enddate"`
+	Voters      []string          `json:"voters"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Proposer    string            `json:"proposer"`
+	Tags        []string          `json:"tags"`
+	Artifacts   []client.Artifact `json:"artifacts"`
+	RequireAck  bool              `json:"requireack"`
+}
+
+// body of POST /ballots/{id}/votes
+type voterequest struct {
+	Vote    string   `json:"vote"`
+	Comment string   `json:"comment"`
+	Ack     []string `json:"ack"` // digests of reviewed artifacts
+}
+
+// decode json body, unknown fields are errors
+func decode(r *http.Request, v interface{}) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+		return errinvalid("body", "body is to be application/json")
+	}
+
+	d := json.NewDecoder(io.LimitReader(r.Body, maxbody))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return errinvalid("body", "body is not valid json: "+err.Error())
+	}
+	if d.More() {
+		return errinvalid("body", "body has more than one json value")
+	}
+	return nil
+}
+
+// checks of gateway, chaincode checks the rest
+func (b ballotrequest) validate() error {
+	if b.VoteID == "" || strings.ContainsAny(b.VoteID, "|/") {
+		return errinvalid("voteid", "voteid is required and can't contain | or /")
+	}
+	if u, err := url.Parse(b.RepoURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return errinvalid("repourl", "repourl is to be absolute url")
+	}
+	if b.EndDate == "" {
+		return errinvalid("enddate", "enddate is required")
+	}
+	if len(b.Voters) == 0 {
+		return errinvalid("voters", "voters are required")
+	}
+	seen := map[string]bool{}
+	for _, v := range b.Voters {
+		if v == "" || strings.Contains(v, "=") {
+			return errinvalid("voters", fmt.Sprintf("voter %q is empty or contains =", v))
+		}
+		if seen[v] {
+			return errinvalid("voters", fmt.Sprintf("voter %q is listed twice", v))
+		}
+		seen[v] = true
+	}
+	for _, a := range b.Artifacts {
+		if a.Name == "" || (a.SHA256 == "") == (a.Commit == "") {
+			return errinvalid("artifacts", "artifact is to have name and one of sha256 and commit")
+		}
+		if !ishex(a.SHA256, 64) && !ishex(a.Commit, 40) && !ishex(a.Commit, 64) {
+			return errinvalid("artifacts", fmt.Sprintf("digest of artifact %s is to be hex: sha256 of 64, commit of 40 or 64 symbols", a.Name))
+		}
+	}
+	return nil
+}
+
+func (b ballotrequest) startrequest() client.StartRequest {
+	return client.StartRequest{
+		VoteID: b.VoteID, RepoURL: b.RepoURL, EndDate: b.EndDate, Voters: b.Voters,
+		Title: b.Title, Description: b.Description, Proposer: b.Proposer, Tags: b.Tags,
+		Artifacts: b.Artifacts, RequireAck: b.RequireAck,
+	}
+}
+
+func (v voterequest) validate() error {
+	if !isvote(v.Vote) {
+		return errinvalid("vote", "vote is to be yes, no or neutral")
+	}
+	for _, a := range v.Ack {
+		if a == "" || strings.Contains(a, ",") {
+			return errinvalid("ack", fmt.Sprintf("digest %q is empty or contains ,", a))
+		}
+	}
+	return nil
+}
+
+func isvote(v string) bool {
+	return v == client.Yes || v == client.No || v == client.Neutral
+}
+
+func ishex(s string, n int) bool {
+	_, err := hex.DecodeString(s)
+	return len(s) == n && err == nil
+}
@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// struct for chaincode event of one valid tx
+type txevent struct {
+	Block     uint64
+	TxID      string
+	Timestamp string // tx time, RFC3339
+	Name      string
+	Payload   []byte
+}
+
+// events of chaincode of valid endorser txs of block, in order of txs
+func blockevents(block *common.Block, chaincode string) ([]txevent, error) {
+	var filter []byte // validation codes of txs, absent in blocks of orderer
+	if md := block.Metadata; md != nil && len(md.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		filter = md.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+	if block.Data == nil {
+		return nil, nil
+	}
+
+	events := []txevent{}
+	for i, data := range block.Data.Data {
+		if i < len(filter) && pb.TxValidationCode(filter[i]) != pb.TxValidationCode_VALID {
+			continue
+		}
+
+		env, err := utils.GetEnvelopeFromBlock(data)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %d: %s", block.Header.Number, i, err)
+		}
+		payload, err := utils.UnmarshalPayload(env.Payload)
+		if err != nil || payload.Header == nil {
+			return nil, fmt.Errorf("block %d tx %d: bad payload: %v", block.Header.Number, i, err)
+		}
+		chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %d: %s", block.Header.Number, i, err)
+		}
+		if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+			continue // config and other txs have no chaincode events
+		}
+
+		ev, err := txccevent(payload.Data, chaincode)
+		if err != nil {
+			return nil, fmt.Errorf("block %d tx %s: %s", block.Header.Number, chdr.TxId, err)
+		}
+		if ev == nil {
+			continue
+		}
+
+		tx := txevent{Block: block.Header.Number, TxID: chdr.TxId, Name: ev.EventName, Payload: ev.Payload}
+		if ts := chdr.Timestamp; ts != nil {
+			tx.Timestamp = time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format(time.RFC3339Nano)
+		}
+		events = append(events, tx)
+	}
+
+	return events, nil
+}
+
+// event of chaincode in actions of endorser tx, nil if there is none
+func txccevent(data []byte, chaincode string) (*pb.ChaincodeEvent, error) {
+	tx, err := utils.GetTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, action := range tx.Actions {
+		ccpayload, err := utils.GetChaincodeActionPayload(action.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if ccpayload.Action == nil {
+			continue
+		}
+		prp, err := utils.GetProposalResponsePayload(ccpayload.Action.ProposalResponsePayload)
+		if err != nil {
+			return nil, err
+		}
+		ca, err := utils.GetChaincodeAction(prp.Extension)
+		if err != nil {
+			return nil, err
+		}
+		if len(ca.Events) == 0 {
+			continue
+		}
+
+		ev, err := utils.GetChaincodeEvents(ca.Events)
+		if err != nil {
+			return nil, err
+		}
+		if ev.ChaincodeId == chaincode && ev.EventName != "" {
+			return ev, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ioserikov/cc_voting/client"
+)
+
+// Handler of queries of store:
+//
+//	GET /status                  next block
+//	GET /ballots                 ballots in order of ids
+//	GET /ballots/{id}            ballot with published result
+//	GET /ballots/{id}/votes      current votes in order of voters
+//	GET /ballots/{id}/tally      tally of current votes
+func Handler(s *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writejson(w, http.StatusMethodNotAllowed, &client.Error{Code: "METHOD_NOT_ALLOWED", Status: http.StatusMethodNotAllowed, Message: "queries are GET"})
+			return
+		}
+
+		segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+		for i, seg := range segments {
+			segments[i], _ = url.PathUnescape(seg)
+		}
+
+		switch {
+		case len(segments) == 1 && segments[0] == "status":
+			writejson(w, http.StatusOK, struct {
+				Next uint64 `json:"next"`
+			}{s.Next()})
+		case len(segments) == 1 && segments[0] == "ballots":
+			writejson(w, http.StatusOK, s.Ballots())
+		case len(segments) == 2 && segments[0] == "ballots":
+			if b, ok := s.Ballot(segments[1]); ok {
+				writejson(w, http.StatusOK, b)
+			} else {
+				writenotfound(w, segments[1])
+			}
+		case len(segments) == 3 && segments[0] == "ballots" && segments[2] == "votes":
+			if _, ok := s.Ballot(segments[1]); ok {
+				writejson(w, http.StatusOK, s.Votes(segments[1]))
+			} else {
+				writenotfound(w, segments[1])
+			}
+		case len(segments) == 3 && segments[0] == "ballots" && segments[2] == "tally":
+			if t, ok := s.Tally(segments[1]); ok {
+				writejson(w, http.StatusOK, t)
+			} else {
+				writenotfound(w, segments[1])
+			}
+		default:
+			writejson(w, http.StatusNotFound, &client.Error{Code: "NOT_FOUND", Status: http.StatusNotFound, Message: "no route " + r.URL.Path})
+		}
+	})
+}
+
+// ballot is not in projection, as BALLOT_NOT_FOUND of chaincode
+func writenotfound(w http.ResponseWriter, voteid string) {
+	writejson(w, http.StatusNotFound, &client.Error{Code: client.CodeBallotNotFound, Status: http.StatusNotFound,
+		Message: "no ballot " + voteid + " in index", Details: map[string]string{"voteid": voteid}})
+}
+
+func writejson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
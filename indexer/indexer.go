@@ -0,0 +1,74 @@
+// Package indexer keeps local projection of ballots, votes and tallies of cc_voting chaincode.
+// It subscribes on blocks of channel by DeliverClient of hlf-sdk-go, applies chaincode events of valid txs
+// to Store and resumes from block after the last processed one.
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+// Indexer of events of chaincode on channel
+type Indexer struct {
+	deliver   api.DeliverClient
+	channel   string
+	chaincode string
+	store     *Store
+	observers []func(Event) error
+}
+
+// New indexer of chaincode on channel into store
+func New(deliver api.DeliverClient, channel string, chaincode string, store *Store) *Indexer {
+	return &Indexer{deliver: deliver, channel: channel, chaincode: chaincode, store: store}
+}
+
+// Observe events of every block before block is saved in store. Error of observer stops indexer,
+// block is delivered again after restart - observer is to skip events it has seen
+func (ix *Indexer) Observe(observer func(Event) error) {
+	ix.observers = append(ix.observers, observer)
+}
+
+// Run applies blocks from next block of store until ctx is done or stream fails
+func (ix *Indexer) Run(ctx context.Context) error {
+	sub, err := ix.deliver.SubscribeBlock(ctx, ix.channel, api.SeekRange(ix.store.Next(), math.MaxUint64))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Errors():
+			return err
+		case block, ok := <-sub.Blocks():
+			if !ok {
+				return fmt.Errorf("deliver stream of %s is closed", ix.channel)
+			}
+			if err := ix.apply(block); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply block to store, blocks delivered again are skipped
+func (ix *Indexer) apply(block *common.Block) error {
+	if block.Header == nil {
+		return fmt.Errorf("block without header")
+	}
+	if block.Header.Number < ix.store.Next() {
+		return nil
+	}
+
+	events, err := blockevents(block, ix.chaincode)
+	if err != nil {
+		return err
+	}
+	return ix.store.apply(block.Header.Number, events, ix.observers)
+}
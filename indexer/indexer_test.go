@@ -0,0 +1,371 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/ioserikov/cc_voting/chaincode"
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/wallet"
+	"github.com/ioserikov/cc_voting/wallet/wallettest"
+	cckit "github.com/s7techlab/cckit/testing"
+	"github.com/s7techlab/hlf-sdk-go/api"
+)
+
+const testcc = "crocc"
+
+// chaincode names voters by common name of CA of their certificates
+const (
+	voter1 = "ca.org1.example.com"
+	voter2 = "ca.org2.example.com"
+)
+
+// tx of test block
+type testtx struct {
+	txid      string
+	invalid   bool   // marked invalid in filter of block
+	chaincode string // testcc if empty
+	event     *pb.ChaincodeEvent
+}
+
+func mustmarshal(t *testing.T, m proto.Message) []byte {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// block of endorser txs with chaincode events, as peer delivers it - with filter of validation codes
+func testblock(t *testing.T, number uint64, txs ...testtx) *common.Block {
+	block := &common.Block{
+		Header:   &common.BlockHeader{Number: number},
+		Data:     &common.BlockData{},
+		Metadata: &common.BlockMetadata{Metadata: make([][]byte, 4)},
+	}
+	filter := []byte{}
+
+	for i, tx := range txs {
+		ev := *tx.event
+		ev.TxId, ev.ChaincodeId = tx.txid, tx.chaincode
+		if ev.ChaincodeId == "" {
+			ev.ChaincodeId = testcc
+		}
+		prp := mustmarshal(t, &pb.ProposalResponsePayload{Extension: mustmarshal(t, &pb.ChaincodeAction{Events: mustmarshal(t, &ev)})})
+		ccpayload := mustmarshal(t, &pb.ChaincodeActionPayload{Action: &pb.ChaincodeEndorsedAction{ProposalResponsePayload: prp}})
+		chdr := mustmarshal(t, &common.ChannelHeader{
+			Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+			TxId:      tx.txid,
+			Timestamp: &timestamp.Timestamp{Seconds: 1556000000 + int64(number)*60 + int64(i)},
+		})
+		payload := mustmarshal(t, &common.Payload{
+			Header: &common.Header{ChannelHeader: chdr},
+			Data:   mustmarshal(t, &pb.Transaction{Actions: []*pb.TransactionAction{{Payload: ccpayload}}}),
+		})
+		block.Data.Data = append(block.Data.Data, mustmarshal(t, &common.Envelope{Payload: payload}))
+
+		code := pb.TxValidationCode_VALID
+		if tx.invalid {
+			code = pb.TxValidationCode_MVCC_READ_CONFLICT
+		}
+		filter = append(filter, byte(code))
+	}
+
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = filter
+	return block
+}
+
+// fake deliver stream of blocks, records first block of every subscription
+type fakedeliver struct {
+	blocks []*common.Block
+	starts []uint64
+}
+
+type fakesubscription struct {
+	blocks chan *common.Block
+	errors chan error
+}
+
+func (s *fakesubscription) Blocks() <-chan *common.Block { return s.blocks }
+func (s *fakesubscription) Errors() chan error           { return s.errors }
+func (s *fakesubscription) Close() error                 { return nil }
+
+func (d *fakedeliver) SubscribeBlock(ctx context.Context, channel string, seek ...api.EventCCSeekOption) (api.BlockSubscription, error) {
+	start := uint64(0)
+	if len(seek) > 0 {
+		from, _ := seek[0]()
+		start = from.GetSpecified().GetNumber()
+	}
+	d.starts = append(d.starts, start)
+
+	sub := &fakesubscription{blocks: make(chan *common.Block, len(d.blocks)), errors: make(chan error, 1)}
+	for _, b := range d.blocks {
+		if b.Header.Number >= start {
+			sub.blocks <- b
+		}
+	}
+	return sub, nil // stream stays open, as stream of peer
+}
+
+func (d *fakedeliver) SubscribeCC(ctx context.Context, channel string, cc string, seek ...api.EventCCSeekOption) (api.EventCCSubscription, error) {
+	return nil, nil
+}
+
+func (d *fakedeliver) SubscribeTx(ctx context.Context, channel string, tx api.ChaincodeTx, seek ...api.EventCCSeekOption) (api.TxSubscription, error) {
+	return nil, nil
+}
+
+// run indexer until store reaches block next
+func runindexer(t *testing.T, ix *Indexer, store *Store, next uint64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ix.Run(ctx) }()
+
+	for deadline := time.Now().Add(5 * time.Second); store.Next() < next; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("indexer stopped at block %d, expected %d", store.Next(), next)
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("indexer failed: %v", err)
+		default:
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("indexer stopped by %v", err)
+	}
+}
+
+// events of voting by clients of Org1 and Org2 on chaincode in MockStub
+func votingevents(t *testing.T) map[string]*pb.ChaincodeEvent {
+	dir, err := ioutil.TempDir("", "indexer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stub := cckit.NewMockStub(testcc, new(chaincode.SimpleChaincode))
+	invoker := cckit.NewInvoker().WithChannel("mychannel", stub)
+	clients := []*client.Client{}
+	for _, org := range []struct{ mspid, ca string }{{"Org1MSP", voter1}, {"Org2MSP", voter2}} {
+		mspdir := filepath.Join(dir, org.mspid)
+		wallettest.MSPDir(mspdir, org.ca, "User1")
+		identity, err := wallet.Load(mspdir, org.mspid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client.New(invoker, identity, "mychannel", testcc))
+	}
+	org1, org2 := clients[0], clients[1]
+
+	ctx := context.Background()
+	events := map[string]*pb.ChaincodeEvent{}
+	step := func(name string, err error) {
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		events[name] = stub.ChaincodeEvent
+	}
+
+	_, err = org1.StartBallot(ctx, client.StartRequest{VoteID: "Vote1", RepoURL: "https://git.repo", EndDate: "01.05.2099", Voters: []string{voter1, voter2}, Title: "Release", Runoff: "48h"})
+	step("start", err)
+	_, err = org1.CastVote(ctx, client.VoteRequest{VoteID: "Vote1", Vote: client.Yes})
+	step("vote1", err)
+	_, err = org2.CastVote(ctx, client.VoteRequest{VoteID: "Vote1", Vote: client.Yes})
+	step("vote2", err)
+	_, err = org2.WithdrawVote(ctx, "Vote1")
+	step("withdraw2", err)
+	_, err = org2.CastVote(ctx, client.VoteRequest{VoteID: "Vote1", Vote: client.No, Comment: "not now"})
+	step("revote2", err)
+	_, err = org1.CloseBallot(ctx, "Vote1")
+	step("close", err)
+	_, err = org1.PublishResult(ctx, "Vote1", client.Filter{})
+	step("result", err)
+
+	return events
+}
+
+func TestIndexer_Resume(t *testing.T) {
+	ev := votingevents(t)
+	blocks := []*common.Block{
+		testblock(t, 0),
+		testblock(t, 1, testtx{txid: "tx-start", event: ev["start"]}, testtx{txid: "tx-other", chaincode: "other", event: ev["vote1"]}),
+		testblock(t, 2, testtx{txid: "tx-vote1", event: ev["vote1"]}, testtx{txid: "tx-invalid", invalid: true, event: ev["withdraw2"]}, testtx{txid: "tx-vote2", event: ev["vote2"]}),
+		testblock(t, 3, testtx{txid: "tx-withdraw2", event: ev["withdraw2"]}, testtx{txid: "tx-revote2", event: ev["revote2"]}),
+		testblock(t, 4, testtx{txid: "tx-close", event: ev["close"]}, testtx{txid: "tx-result", event: ev["result"]}),
+	}
+
+	dir, err := ioutil.TempDir("", "indexer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.db")
+
+	// first run processes blocks 0-2
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliver := &fakedeliver{blocks: blocks[:3]}
+	runindexer(t, New(deliver, "mychannel", testcc, store), store, 3)
+
+	tl, ok := store.Tally("Vote1")
+	if !ok || tl.Voted != 2 || tl.Counts["yes"] != 2 || tl.Decision != "yes" || tl.Registered != 2 {
+		t.Errorf("tally after block 2: %+v", tl)
+	}
+
+	// restart resumes from block 3 of file, delivered blocks before it are skipped
+	store.Close()
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Next() != 3 {
+		t.Fatalf("reopened store at block %d", store.Next())
+	}
+	deliver.blocks = blocks
+	runindexer(t, New(deliver, "mychannel", testcc, store), store, 5)
+	if len(deliver.starts) != 2 || deliver.starts[0] != 0 || deliver.starts[1] != 3 {
+		t.Errorf("subscriptions from blocks %v, expected 0 and 3", deliver.starts)
+	}
+
+	b, ok := store.Ballot("Vote1")
+	if !ok || b.Title != "Release" || !b.Closed || b.TxID != "tx-start" || b.Block != 1 {
+		t.Errorf("ballot %+v", b)
+	}
+	if b.Result == nil || b.Result.Decision != "equal" || b.Result.TxID != "tx-result" || b.Result.Leaves != 2 {
+		t.Errorf("published result %+v", b.Result)
+	}
+	votes := store.Votes("Vote1")
+	if len(votes) != 2 || votes[1].Voter != voter2 || votes[1].Vote.Vote != client.No || votes[1].Block != 3 || votes[1].Comment != "not now" {
+		t.Errorf("votes %+v", votes)
+	}
+	if tl, _ := store.Tally("Vote1"); tl.Decision != "equal" || tl.Published == nil {
+		t.Errorf("tally %+v", tl)
+	}
+	// close of equal result opens runoff
+	if r, ok := store.Ballot("Vote1-runoff"); !ok || r.Round != 2 || r.PrevRound != "Vote1" || r.TxID != "tx-close" || r.Closed {
+		t.Errorf("runoff %+v", r)
+	}
+	if len(store.Ballots()) != 2 {
+		t.Errorf("ballots %+v", store.Ballots())
+	}
+}
+
+func TestIndexer_Gap(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "indexer")
+	defer os.RemoveAll(dir)
+	store, _ := Open(filepath.Join(dir, "index.db"))
+
+	ix := New(&fakedeliver{}, "mychannel", testcc, store)
+	if err := ix.apply(testblock(t, 1)); err == nil {
+		t.Errorf("block 1 is applied before block 0")
+	}
+	store.Close()
+	store, err := Open(filepath.Join(dir, "index.db"))
+	if err != nil {
+		t.Fatalf("store is broken by failed block: %s", err)
+	}
+	defer store.Close()
+	if store.Next() != 0 {
+		t.Errorf("store at block %d after failed block", store.Next())
+	}
+}
+
+func TestIndexer_Observe(t *testing.T) {
+	ev := votingevents(t)
+	dir, _ := ioutil.TempDir("", "indexer")
+	defer os.RemoveAll(dir)
+	store, _ := Open(filepath.Join(dir, "index.db"))
+	defer store.Close()
+	ix := New(&fakedeliver{}, "mychannel", testcc, store)
+
+	observed := []Event{}
+	fail := true
+	ix.Observe(func(e Event) error {
+		if fail && e.Name == "votecast" {
+			return fmt.Errorf("queue is full")
+		}
+		observed = append(observed, e)
+		return nil
+	})
+
+	block := testblock(t, 0, testtx{txid: "tx-start", event: ev["start"]}, testtx{txid: "tx-vote1", event: ev["vote1"]})
+	if err := ix.apply(block); err == nil {
+		t.Fatalf("block is applied with failed observer")
+	}
+	if _, ok := store.Ballot("Vote1"); ok || store.Next() != 0 {
+		t.Errorf("projection keeps block of failed observer")
+	}
+
+	fail, observed = false, nil
+	if err := ix.apply(block); err != nil {
+		t.Fatal(err)
+	}
+	if len(observed) != 2 || observed[0].Name != "ballotstarted" || observed[1].Vote == nil || observed[1].Vote.Voter != voter1 ||
+		observed[1].Ballot.Title != "Release" || observed[1].TxID != "tx-vote1" {
+		t.Errorf("observed %+v", observed)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ev := votingevents(t)
+	dir, _ := ioutil.TempDir("", "indexer")
+	defer os.RemoveAll(dir)
+	store, _ := Open(filepath.Join(dir, "index.db"))
+	defer store.Close()
+	ix := New(&fakedeliver{}, "mychannel", testcc, store)
+	if err := ix.apply(testblock(t, 0, testtx{txid: "tx-start", event: ev["start"]}, testtx{txid: "tx-vote1", event: ev["vote1"]})); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(Handler(store))
+	defer srv.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		check  func(body []byte) bool
+	}{
+		{"/status", 200, func(b []byte) bool { return string(b) == "{\"next\":1}\n" }},
+		{"/ballots", 200, func(b []byte) bool {
+			var v []Ballot
+			json.Unmarshal(b, &v)
+			return len(v) == 1 && v[0].VoteID == "Vote1"
+		}},
+		{"/ballots/Vote1/votes", 200, func(b []byte) bool { var v []Vote; json.Unmarshal(b, &v); return len(v) == 1 && v[0].Voter == voter1 }},
+		{"/ballots/Vote1/tally", 200, func(b []byte) bool { var v Tally; json.Unmarshal(b, &v); return v.Voted == 1 && v.Decision == "yes" }},
+		{"/ballots/Vote2", 404, func(b []byte) bool {
+			var e client.Error
+			json.Unmarshal(b, &e)
+			return e.Code == client.CodeBallotNotFound
+		}},
+		{"/votes", 404, func(b []byte) bool { return true }},
+	}
+	for _, c := range cases {
+		res, err := http.Get(srv.URL + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != c.status || !c.check(body) {
+			t.Errorf("%s: %d %s", c.path, res.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/tally"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Ballot - projection of ballot: metadata of last event of ballot and published result
+type Ballot struct {
+	client.Ballot
+	TxID   string  `json:"txid"`  // tx of votestart
+	Block  uint64  `json:"block"` // block of votestart
+	Result *Result `json:"result,omitempty"`
+}
+
+// Vote - projection of current vote of voter, withdrawn votes are removed
+type Vote struct {
+	client.Vote
+	Block uint64 `json:"block"`
+}
+
+// Result published by voteresult
+type Result struct {
+	Counts     map[string]int `json:"counts"`
+	Decision   string         `json:"decision"`
+	Decided    bool           `json:"decided"` // decision is not equal and reaches majority of ballot
+	MerkleRoot string         `json:"merkleroot"`
+	Leaves     int            `json:"leaves"`
+	TxID       string         `json:"txid"`
+	Block      uint64         `json:"block"`
+}
+
+// Tally of current votes of ballot, with published result if there is one
+type Tally struct {
+	VoteID     string         `json:"voteid"`
+	Counts     map[string]int `json:"counts"`
+	Registered int            `json:"registered"`
+	Voted      int            `json:"voted"`
+	Decision   string         `json:"decision"`
+	Published  *Result        `json:"published,omitempty"`
+}
+
+// Event - chaincode event applied to projection, with ballot as it is after event
+type Event struct {
+	Name      string  `json:"name"` // name of chaincode event: ballotstarted, votecast...
+	VoteID    string  `json:"voteid"`
+	Block     uint64  `json:"block"`
+	TxID      string  `json:"txid"`
+	Timestamp string  `json:"timestamp"` // tx time, RFC3339
+	Ballot    Ballot  `json:"ballot"`
+	Vote      *Vote   `json:"vote,omitempty"`   // vote cast
+	Voter     string  `json:"voter,omitempty"`  // voter of withdrawn vote
+	Runoff    *Ballot `json:"runoff,omitempty"` // runoff opened by close of ballot
+}
+
+// buckets of store: number of next block, ballots by voteid, votes in bucket of voteid by voter
+var (
+	bucketmeta    = []byte("meta")
+	bucketballots = []byte("ballots")
+	bucketvotes   = []byte("votes")
+	keynext       = []byte("next")
+)
+
+// Store - projection in bbolt file. Events of block are applied in one transaction of it,
+// so crash or failed event leaves projection of previous block
+type Store struct {
+	db *bolt.DB
+}
+
+// Open store of file, empty store of block 0 if file does not exist
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %s", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketmeta, bucketballots, bucketvotes} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store %s: %s", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close file of store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Next - number of block to process next
+func (s *Store) Next() uint64 {
+	next := uint64(0)
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucketmeta).Get(keynext); len(v) == 8 {
+			next = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	return next
+}
+
+// Ballots in order of ids
+func (s *Store) Ballots() []Ballot {
+	ballots := []Ballot{}
+	s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketballots).ForEach(func(k, v []byte) error {
+			var b Ballot
+			if err := json.Unmarshal(v, &b); err == nil {
+				ballots = append(ballots, b)
+			}
+			return nil
+		})
+	})
+	return ballots
+}
+
+// Ballot by id
+func (s *Store) Ballot(voteid string) (Ballot, bool) {
+	var b Ballot
+	ok := false
+	s.db.View(func(tx *bolt.Tx) error {
+		b, ok = projection{tx}.getballot(voteid)
+		return nil
+	})
+	return b, ok
+}
+
+// Votes of ballot in order of voters
+func (s *Store) Votes(voteid string) []Vote {
+	votes := []Vote{}
+	s.db.View(func(tx *bolt.Tx) error {
+		votes = projection{tx}.votes(voteid)
+		return nil
+	})
+	return votes
+}
+
+// Tally of current votes of ballot
+func (s *Store) Tally(voteid string) (Tally, bool) {
+	var b Ballot
+	ok := false
+	votes := []string{}
+	s.db.View(func(tx *bolt.Tx) error {
+		p := projection{tx}
+		if b, ok = p.getballot(voteid); ok {
+			for _, v := range p.votes(voteid) {
+				votes = append(votes, v.Vote.Vote)
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return Tally{}, false
+	}
+
+	counts := tally.Count(votes)
+	decision, _ := tally.Decide(counts)
+	return Tally{VoteID: voteid, Counts: counts, Registered: len(b.Voters), Voted: len(votes), Decision: decision, Published: b.Result}, true
+}
+
+// apply events of block and pass them to observers in one transaction of store. Block is to be the next one.
+// If event or observer fails, transaction is rolled back - block is to be applied again
+func (s *Store) apply(number uint64, events []txevent, observers []func(Event) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(bucketmeta)
+		next := uint64(0)
+		if v := meta.Get(keynext); len(v) == 8 {
+			next = binary.BigEndian.Uint64(v)
+		}
+		if number != next {
+			return fmt.Errorf("block %d, expected block %d", number, next)
+		}
+
+		p := projection{tx}
+		for _, e := range events {
+			ev, err := p.apply(e)
+			if err == nil {
+				for _, observe := range observers {
+					if err = observe(ev); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("block %d tx %s: %s", number, e.TxID, err)
+			}
+		}
+
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, number+1)
+		return meta.Put(keynext, v)
+	})
+}
+
+// projection in transaction of store
+type projection struct {
+	tx *bolt.Tx
+}
+
+// ballot of id, false if projection has no such ballot
+func (p projection) getballot(voteid string) (Ballot, bool) {
+	v := p.tx.Bucket(bucketballots).Get([]byte(voteid))
+	if v == nil {
+		return Ballot{}, false
+	}
+	var b Ballot
+	if err := json.Unmarshal(v, &b); err != nil {
+		return Ballot{}, false
+	}
+	return b, true
+}
+
+func (p projection) putballot(b Ballot) error {
+	v, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return p.tx.Bucket(bucketballots).Put([]byte(b.VoteID), v)
+}
+
+// ballot of projection, created for events of ballots started before chaincode set events
+func (p projection) ballot(voteid string) Ballot {
+	b, ok := p.getballot(voteid)
+	if !ok {
+		b = Ballot{Ballot: client.Ballot{VoteID: voteid}}
+	}
+	return b
+}
+
+// votes of ballot in order of voters
+func (p projection) votes(voteid string) []Vote {
+	votes := []Vote{}
+	bucket := p.tx.Bucket(bucketvotes).Bucket([]byte(voteid))
+	if bucket == nil {
+		return votes
+	}
+	bucket.ForEach(func(k, v []byte) error {
+		var vote Vote
+		if err := json.Unmarshal(v, &vote); err == nil {
+			votes = append(votes, vote)
+		}
+		return nil
+	})
+	return votes
+}
+
+// payload of chaincode events, as chaincode sets it
+type ccevent struct {
+	VoteID string         `json:"voteid"`
+	Ballot *client.Ballot `json:"ballot"`
+	Runoff *client.Ballot `json:"runoff"` // runoff of ballotclosed, with its voteid
+	Vote   *client.Vote   `json:"vote"`
+	Voter  string         `json:"voter"`
+	Result *Result        `json:"result"`
+}
+
+// apply one event, events of other names change nothing
+func (p projection) apply(tx txevent) (Event, error) {
+	var ev ccevent
+	if err := json.Unmarshal(tx.Payload, &ev); err != nil {
+		return Event{}, fmt.Errorf("event %s: %s", tx.Name, err)
+	}
+	if ev.VoteID == "" {
+		return Event{}, fmt.Errorf("event %s without voteid", tx.Name)
+	}
+	applied := Event{Name: tx.Name, VoteID: ev.VoteID, Block: tx.Block, TxID: tx.TxID, Timestamp: tx.Timestamp}
+
+	switch tx.Name {
+	case "ballotstarted", "ballotclosed", "ballotcancelled", "ballotarchived", "ballotextended", "extensionrequested":
+		if ev.Ballot == nil {
+			return Event{}, fmt.Errorf("event %s without ballot", tx.Name)
+		}
+		b := p.ballot(ev.VoteID)
+		b.Ballot = *ev.Ballot
+		b.VoteID = ev.VoteID
+		if tx.Name == "ballotstarted" {
+			b.TxID, b.Block = tx.TxID, tx.Block
+		}
+		if err := p.putballot(b); err != nil {
+			return Event{}, err
+		}
+		if ev.Runoff != nil && ev.Runoff.VoteID != "" {
+			r := p.ballot(ev.Runoff.VoteID)
+			r.Ballot = *ev.Runoff
+			r.TxID, r.Block = tx.TxID, tx.Block
+			if err := p.putballot(r); err != nil {
+				return Event{}, err
+			}
+			applied.Runoff = &r
+		}
+	case "votecast":
+		if ev.Vote == nil {
+			return Event{}, fmt.Errorf("event %s without vote", tx.Name)
+		}
+		if err := p.putballot(p.ballot(ev.VoteID)); err != nil {
+			return Event{}, err
+		}
+		bucket, err := p.tx.Bucket(bucketvotes).CreateBucketIfNotExists([]byte(ev.VoteID))
+		if err != nil {
+			return Event{}, err
+		}
+		vote := Vote{Vote: *ev.Vote, Block: tx.Block}
+		v, err := json.Marshal(vote)
+		if err != nil {
+			return Event{}, err
+		}
+		if err := bucket.Put([]byte(ev.Vote.Voter), v); err != nil {
+			return Event{}, err
+		}
+		applied.Vote = &vote
+	case "votewithdrawn":
+		if bucket := p.tx.Bucket(bucketvotes).Bucket([]byte(ev.VoteID)); bucket != nil {
+			if err := bucket.Delete([]byte(ev.Voter)); err != nil {
+				return Event{}, err
+			}
+		}
+		applied.Voter = ev.Voter
+	case "resultpublished":
+		if ev.Result == nil {
+			return Event{}, fmt.Errorf("event %s without result", tx.Name)
+		}
+		ev.Result.TxID, ev.Result.Block = tx.TxID, tx.Block
+		b := p.ballot(ev.VoteID)
+		b.Result = ev.Result
+		if err := p.putballot(b); err != nil {
+			return Event{}, err
+		}
+	}
+
+	if b, ok := p.getballot(ev.VoteID); ok {
+		applied.Ballot = b
+	}
+	return applied, nil
+}
@@ -0,0 +1,269 @@
+// Package notifier pushes notifications of cc_voting ballots to webhooks: ballot opened, vote cast,
+// deadline in 24 hours and final result. It observes chaincode events applied by indexer, keeps deliveries
+// in persisted queue and POSTs json signed by HMAC-SHA256 of secret of hook, with retries and backoff.
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/indexer"
+	"github.com/ioserikov/cc_voting/tally"
+)
+
+// types of notifications
+const (
+	TypeBallotOpened   = "ballot.opened"
+	TypeVoteCast       = "vote.cast"
+	TypeBallotDeadline = "ballot.deadline"
+	TypeResultFinal    = "result.final"
+)
+
+// headers of delivery
+const (
+	HeaderEvent     = "X-Crocc-Event"     // type of notification
+	HeaderDelivery  = "X-Crocc-Delivery"  // id of notification, the same for retries
+	HeaderSignature = "X-Crocc-Signature" // sha256=<hex of HMAC-SHA256 of body by secret of hook>
+)
+
+// reminder of deadline is sent this time before it
+const remindbefore = 24 * time.Hour
+
+// Hook - webhook with filters, empty filter passes all
+type Hook struct {
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret"`
+	Types   []string `json:"types,omitempty"`   // types of notifications
+	Ballots []string `json:"ballots,omitempty"` // ids of ballots
+}
+
+// Notification - body of delivery
+type Notification struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	VoteID  string          `json:"voteid"`
+	Title   string          `json:"title,omitempty"`
+	EndDate string          `json:"enddate,omitempty"`
+	Time    string          `json:"time"`            // tx time, time of reminder for deadline, RFC3339
+	TxID    string          `json:"txid,omitempty"`  // tx of event
+	Block   uint64          `json:"block,omitempty"` // block of event
+	Voter   string          `json:"voter,omitempty"` // voter of vote.cast, vote itself is secret until result
+	Org     string          `json:"org,omitempty"`   // MSP ID of voter
+	Result  *indexer.Result `json:"result,omitempty"`
+}
+
+// LoadHooks reads json array of hooks of file
+func LoadHooks(path string) ([]Hook, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hooks := []Hook{}
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("hooks %s: %s", path, err)
+	}
+	return hooks, nil
+}
+
+// Sign body by secret, as HeaderSignature carries it
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify signature of body - for receivers of webhooks
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func (h Hook) matches(n Notification) bool {
+	return (len(h.Types) == 0 || contains(h.Types, n.Type)) && (len(h.Ballots) == 0 || contains(h.Ballots, n.VoteID))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier of hooks
+type Notifier struct {
+	hooks   []Hook
+	queue   *queue
+	ballots func() []indexer.Ballot
+	sender  *sender
+}
+
+// New notifier of hooks with queue in file. Ballots are checked for deadlines, Store.Ballots of indexer gives them
+func New(hooks []Hook, queuepath string, ballots func() []indexer.Ballot) (*Notifier, error) {
+	for _, h := range hooks {
+		if !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "https://") {
+			return nil, fmt.Errorf("hook %q is not http url", h.URL)
+		}
+		if h.Secret == "" {
+			return nil, fmt.Errorf("hook %s has no secret", h.URL)
+		}
+	}
+	q, err := openqueue(queuepath)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{hooks: hooks, queue: q, ballots: ballots, sender: newsender()}, nil
+}
+
+// Observe event of indexer - enqueue notification of it. Events delivered again are skipped
+func (n *Notifier) Observe(ev indexer.Event) error {
+	note := Notification{VoteID: ev.VoteID, Title: ev.Ballot.Title, EndDate: ev.Ballot.EndDate, Time: ev.Timestamp, TxID: ev.TxID, Block: ev.Block}
+	switch ev.Name {
+	case "ballotstarted":
+		if ev.Ballot.State == client.StateDraft {
+			return nil // draft is announced when proposer opens it
+		}
+		note.Type = TypeBallotOpened
+	case "votecast":
+		note.Type = TypeVoteCast
+		if ev.Vote != nil {
+			note.Voter, note.Org = ev.Vote.Voter, ev.Vote.Org
+		}
+	case "ballotclosed":
+		if ev.Runoff == nil {
+			return nil
+		}
+		// runoff opened by close is announced as opened ballot
+		note = Notification{
+			Type: TypeBallotOpened, VoteID: ev.Runoff.VoteID, Title: ev.Runoff.Title, EndDate: ev.Runoff.EndDate,
+			Time: ev.Timestamp, TxID: ev.TxID, Block: ev.Block,
+		}
+	case "resultpublished":
+		if !ev.Ballot.Closed || ev.Ballot.State == client.StateCancelled || ev.Ballot.Result == nil {
+			return nil // result of open or cancelled ballot is not final
+		}
+		note.Type = TypeResultFinal
+		note.Result = ev.Ballot.Result
+		// voteresult publishes result by every call, final result is notified once for ballot
+		note.ID = note.Type + ":" + ev.VoteID
+		return n.enqueue(note, time.Now())
+	default:
+		return nil
+	}
+	note.ID = note.Type + ":" + ev.TxID
+	return n.enqueue(note, time.Now())
+}
+
+// Remind of deadlines of open ballots, which are in 24 hours of now. Reminder is once for end date,
+// extended ballot is reminded of its new end date
+func (n *Notifier) Remind(now time.Time) error {
+	for _, b := range n.ballots() {
+		deadline, ok := Deadline(b.EndDate)
+		if b.CurrentState() != client.StateOpen || !ok || now.Before(deadline.Add(-remindbefore)) || !now.Before(deadline) {
+			continue
+		}
+		note := Notification{
+			ID: TypeBallotDeadline + ":" + b.VoteID + ":" + b.EndDate, Type: TypeBallotDeadline, VoteID: b.VoteID,
+			Title: b.Title, EndDate: b.EndDate, Time: now.UTC().Format(time.RFC3339),
+		}
+		if err := n.enqueue(note, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue delivery of notification to every matching hook
+func (n *Notifier) enqueue(note Notification, now time.Time) error {
+	body, _ := json.Marshal(note)
+	deliveries := []Delivery{}
+	for _, h := range n.hooks {
+		if h.matches(note) {
+			deliveries = append(deliveries, Delivery{ID: note.ID, Type: note.Type, URL: h.URL, Body: body, NextAt: now})
+		}
+	}
+	return n.queue.add(note.ID, deliveries)
+}
+
+// Flush sends deliveries which are due at now
+func (n *Notifier) Flush(ctx context.Context, now time.Time) error {
+	for _, d := range n.queue.due(now) {
+		secret := ""
+		for _, h := range n.hooks {
+			if h.URL == d.URL {
+				secret = h.Secret
+			}
+		}
+		if secret == "" {
+			d.Error = "hook is not configured any more"
+			if err := n.queue.done(d, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		retry, err := n.sender.send(ctx, d, secret)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var qerr error
+		switch {
+		case err == nil:
+			qerr = n.queue.done(d, true)
+		case retry:
+			qerr = n.queue.retry(d, err, now, n.sender)
+		default:
+			d.Error = err.Error()
+			qerr = n.queue.done(d, false)
+		}
+		if qerr != nil {
+			return qerr
+		}
+	}
+	return nil
+}
+
+// Run reminds and flushes every interval until ctx is done
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if err := n.Remind(now); err != nil {
+			return err
+		}
+		if err := n.Flush(ctx, now); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pending deliveries of queue
+func (n *Notifier) Pending() int {
+	return n.queue.pending()
+}
+
+// Failed deliveries of queue - rejected by receiver or out of attempts
+func (n *Notifier) Failed() []Delivery {
+	return n.queue.failed()
+}
+
+// Deadline of end date of ballot, as chaincode reads it
+func Deadline(enddate string) (time.Time, bool) {
+	return tally.Deadline(enddate)
+}
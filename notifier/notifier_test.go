@@ -0,0 +1,257 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ioserikov/cc_voting/client"
+	"github.com/ioserikov/cc_voting/indexer"
+)
+
+// receiver of webhooks, checks signature by secret and answers statuses in turn, then 200
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	got      []Notification
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if !Verify(rc.secret, body, r.Header.Get(HeaderSignature)) {
+		rc.t.Errorf("bad signature %q of %s", r.Header.Get(HeaderSignature), body)
+	}
+	if len(rc.statuses) > 0 {
+		status := rc.statuses[0]
+		rc.statuses = rc.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	var n Notification
+	json.Unmarshal(body, &n)
+	if r.Header.Get(HeaderEvent) != n.Type || r.Header.Get(HeaderDelivery) != n.ID {
+		rc.t.Errorf("headers %v of %s", r.Header, body)
+	}
+	rc.got = append(rc.got, n)
+}
+
+func (rc *receiver) received() []Notification {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Notification{}, rc.got...)
+}
+
+func tempqueue(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "queue.json"), func() { os.RemoveAll(dir) }
+}
+
+func noballots() []indexer.Ballot { return nil }
+
+func ballot(voteid string, enddate string, closed bool) indexer.Ballot {
+	return indexer.Ballot{Ballot: client.Ballot{VoteID: voteid, EndDate: enddate, Title: "Release", Closed: closed}}
+}
+
+func TestNotifier_Deliver(t *testing.T) {
+	all := &receiver{t: t, secret: "s1"}
+	vote2 := &receiver{t: t, secret: "s2"}
+	votes := &receiver{t: t, secret: "s3"}
+	servers := []*httptest.Server{httptest.NewServer(all), httptest.NewServer(vote2), httptest.NewServer(votes)}
+	for _, s := range servers {
+		defer s.Close()
+	}
+	path, cleanup := tempqueue(t)
+	defer cleanup()
+
+	n, err := New([]Hook{
+		{URL: servers[0].URL, Secret: "s1"},
+		{URL: servers[1].URL, Secret: "s2", Ballots: []string{"Vote2"}},
+		{URL: servers[2].URL, Secret: "s3", Types: []string{TypeVoteCast}},
+	}, path, noballots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &indexer.Result{Decision: "yes", Counts: map[string]int{"yes": 1}}
+	open, closed := ballot("Vote1", "01.05.2019", false), ballot("Vote1", "01.05.2019", true)
+	runoff := ballot("Vote3-runoff", "03.05.2019", false)
+	open.Result, closed.Result = result, result
+	events := []indexer.Event{
+		{Name: "ballotstarted", VoteID: "Vote1", TxID: "tx1", Ballot: ballot("Vote1", "01.05.2019", false)},
+		{Name: "votecast", VoteID: "Vote1", TxID: "tx2", Ballot: open, Vote: &indexer.Vote{Vote: client.Vote{Voter: "ca.org1.example.com", Org: "Org1MSP", Vote: "yes"}}},
+		{Name: "votecast", VoteID: "Vote1", TxID: "tx2", Ballot: open}, // delivered again by indexer
+		{Name: "votewithdrawn", VoteID: "Vote1", TxID: "tx3", Ballot: open},
+		{Name: "resultpublished", VoteID: "Vote1", TxID: "tx4", Ballot: open}, // not final
+		{Name: "ballotclosed", VoteID: "Vote1", TxID: "tx5", Ballot: closed},
+		{Name: "resultpublished", VoteID: "Vote1", TxID: "tx6", Ballot: closed},
+		{Name: "resultpublished", VoteID: "Vote1", TxID: "tx9", Ballot: closed}, // voteresult called again
+		{Name: "ballotstarted", VoteID: "Vote2", TxID: "tx7", Ballot: ballot("Vote2", "01.05.2019", false)},
+		{Name: "ballotclosed", VoteID: "Vote3", TxID: "tx8", Ballot: ballot("Vote3", "01.05.2019", true), Runoff: &runoff},
+	}
+	for _, ev := range events {
+		if err := n.Observe(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n.Pending() != 7 {
+		t.Errorf("pending %d, expected 7", n.Pending())
+	}
+
+	if err := n.Flush(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if n.Pending() != 0 || len(n.Failed()) != 0 {
+		t.Errorf("pending %d, failed %v after flush", n.Pending(), n.Failed())
+	}
+
+	got := all.received()
+	types := []string{TypeBallotOpened, TypeVoteCast, TypeResultFinal, TypeBallotOpened, TypeBallotOpened}
+	if len(got) != len(types) {
+		t.Fatalf("hook of all got %+v", got)
+	}
+	for i, tp := range types {
+		if got[i].Type != tp {
+			t.Errorf("notification %d: %s, expected %s", i, got[i].Type, tp)
+		}
+	}
+	if got[1].Voter != "ca.org1.example.com" || got[1].Org != "Org1MSP" || got[2].Result == nil || got[2].Result.Decision != "yes" || got[4].VoteID != "Vote3-runoff" {
+		t.Errorf("notifications %+v", got)
+	}
+	if got := vote2.received(); len(got) != 1 || got[0].VoteID != "Vote2" {
+		t.Errorf("hook of Vote2 got %+v", got)
+	}
+	if got := votes.received(); len(got) != 1 || got[0].Type != TypeVoteCast {
+		t.Errorf("hook of votes got %+v", got)
+	}
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	flaky := &receiver{t: t, secret: "s1", statuses: []int{503, 429, 200}}
+	rejecting := &receiver{t: t, secret: "s2", statuses: []int{400}}
+	s1, s2 := httptest.NewServer(flaky), httptest.NewServer(rejecting)
+	defer s1.Close()
+	defer s2.Close()
+	path, cleanup := tempqueue(t)
+	defer cleanup()
+
+	hooks := []Hook{{URL: s1.URL, Secret: "s1"}, {URL: s2.URL, Secret: "s2"}}
+	n, err := New(hooks, path, noballots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Observe(indexer.Event{Name: "ballotstarted", VoteID: "Vote1", TxID: "tx1"})
+
+	ctx, now := context.Background(), time.Now()
+	n.Flush(ctx, now)
+	if n.Pending() != 1 || len(n.Failed()) != 1 || n.Failed()[0].URL != s2.URL {
+		t.Fatalf("after first attempt: pending %d, failed %+v", n.Pending(), n.Failed())
+	}
+
+	// queue is persisted: notifier of the same file goes on with retries
+	n, err = New(hooks, path, noballots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Flush(ctx, now.Add(5*time.Second)) // before backoff
+	if len(flaky.received()) != 0 || n.Pending() != 1 {
+		t.Fatalf("delivery before backoff")
+	}
+	n.Flush(ctx, now.Add(10*time.Second)) // 429, next backoff is 20s
+	n.Flush(ctx, now.Add(25*time.Second))
+	if n.Pending() != 1 || len(flaky.received()) != 0 {
+		t.Fatalf("delivery before second backoff")
+	}
+	n.Flush(ctx, now.Add(30*time.Second))
+	if n.Pending() != 0 || len(flaky.received()) != 1 {
+		t.Errorf("delivery after retries: pending %d, received %d", n.Pending(), len(flaky.received()))
+	}
+
+	// out of attempts
+	n.sender.attempts = 1
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(500) }))
+	down.Close()
+	n.hooks = []Hook{{URL: down.URL, Secret: "s3"}}
+	n.Observe(indexer.Event{Name: "ballotstarted", VoteID: "Vote2", TxID: "tx2"})
+	n.Flush(ctx, time.Now())
+	if failed := n.Failed(); n.Pending() != 0 || len(failed) != 2 || failed[1].Attempts != 1 || failed[1].Error == "" {
+		t.Errorf("delivery out of attempts: pending %d, failed %+v", n.Pending(), failed)
+	}
+}
+
+func TestNotifier_Remind(t *testing.T) {
+	rc := &receiver{t: t, secret: "s1"}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	path, cleanup := tempqueue(t)
+	defer cleanup()
+
+	now := time.Date(2019, 4, 30, 12, 0, 0, 0, time.UTC)
+	soon := "01.05.2019.10.00"
+	ballots := func() []indexer.Ballot {
+		return []indexer.Ballot{
+			ballot("Soon", soon, false),
+			ballot("EndOfDay", "30.04.2019", false),
+			ballot("Closed", "01.05.2019.10.00", true),
+			ballot("Later", "03.05.2019", false),
+			ballot("Past", "29.04.2019", false),
+			ballot("Legacy", "someday", false),
+		}
+	}
+	n, err := New([]Hook{{URL: srv.URL, Secret: "s1", Types: []string{TypeBallotDeadline}}}, path, ballots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Remind(now)
+	n.Remind(now.Add(time.Hour)) // reminded once
+	n.Flush(context.Background(), now.Add(time.Hour))
+
+	got := rc.received()
+	if len(got) != 2 || got[0].VoteID != "Soon" || got[1].VoteID != "EndOfDay" || got[0].Type != TypeBallotDeadline {
+		t.Errorf("reminders %+v", got)
+	}
+
+	// extended ballot is reminded of its new end date
+	soon = "02.05.2019.10.00"
+	later := now.Add(24 * time.Hour)
+	n.Remind(later)
+	n.Flush(context.Background(), later)
+	if got := rc.received(); len(got) != 3 || got[2].VoteID != "Soon" || got[2].EndDate != soon {
+		t.Errorf("reminders after extension %+v", got)
+	}
+}
+
+func TestNew_Hooks(t *testing.T) {
+	path, cleanup := tempqueue(t)
+	defer cleanup()
+
+	for _, h := range []Hook{{URL: "ftp://hooks", Secret: "s"}, {URL: "https://hooks"}} {
+		if _, err := New([]Hook{h}, path, noballots); err == nil {
+			t.Errorf("hook %+v is accepted", h)
+		}
+	}
+
+	ioutil.WriteFile(path+".hooks", []byte(`[{"url":"https://hooks","secret":"s","ballots":["Vote1"]}]`), 0644)
+	hooks, err := LoadHooks(path + ".hooks")
+	if err != nil || len(hooks) != 1 || hooks[0].Ballots[0] != "Vote1" {
+		t.Errorf("hooks %+v %v", hooks, err)
+	}
+}
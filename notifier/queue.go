@@ -0,0 +1,211 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Delivery of notification to hook
+type Delivery struct {
+	ID       string          `json:"id"` // id of notification
+	Type     string          `json:"type"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+	Attempts int             `json:"attempts"`
+	NextAt   time.Time       `json:"nextat"`
+	Error    string          `json:"error,omitempty"` // of last attempt
+}
+
+// struct for file of queue
+type queuestate struct {
+	Pending []Delivery      `json:"pending"`
+	Failed  []Delivery      `json:"failed"`
+	Seen    map[string]bool `json:"seen"` // ids of enqueued notifications
+}
+
+// queue of deliveries in json file, written after every change
+type queue struct {
+	path string
+	mu   sync.Mutex
+	s    queuestate
+}
+
+func openqueue(path string) (*queue, error) {
+	q := &queue{path: path, s: queuestate{Seen: map[string]bool{}}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.s); err != nil {
+		return nil, fmt.Errorf("queue %s: %s", path, err)
+	}
+	if q.s.Seen == nil {
+		q.s.Seen = map[string]bool{}
+	}
+	return q, nil
+}
+
+// add deliveries of notification, once per id of notification
+func (q *queue) add(id string, deliveries []Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.s.Seen[id] {
+		return nil
+	}
+	q.s.Seen[id] = true
+	q.s.Pending = append(q.s.Pending, deliveries...)
+	return q.save()
+}
+
+// deliveries due at now
+func (q *queue) due(now time.Time) []Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := []Delivery{}
+	for _, d := range q.s.Pending {
+		if !d.NextAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	return due
+}
+
+// remove delivery of pending, keep it in failed if it failed
+func (q *queue) done(d Delivery, ok bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.remove(d)
+	if !ok {
+		d.Attempts++
+		q.s.Failed = append(q.s.Failed, d)
+	}
+	return q.save()
+}
+
+// schedule next attempt with backoff, or fail delivery out of attempts
+func (q *queue) retry(d Delivery, err error, now time.Time, s *sender) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.remove(d)
+	d.Attempts++
+	d.Error = err.Error()
+	if d.Attempts >= s.attempts {
+		q.s.Failed = append(q.s.Failed, d)
+	} else {
+		d.NextAt = now.Add(s.backoff(d.Attempts))
+		q.s.Pending = append(q.s.Pending, d)
+	}
+	return q.save()
+}
+
+func (q *queue) remove(d Delivery) {
+	for i, p := range q.s.Pending {
+		if p.ID == d.ID && p.URL == d.URL {
+			q.s.Pending = append(q.s.Pending[:i], q.s.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *queue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.s.Pending)
+}
+
+func (q *queue) failed() []Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Delivery{}, q.s.Failed...)
+}
+
+// write file of queue by rename of temp file
+func (q *queue) save() error {
+	data, err := json.Marshal(q.s)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), q.path)
+}
+
+// sender of deliveries
+type sender struct {
+	client   *http.Client
+	base     time.Duration // backoff after first attempt, doubled after every next one
+	max      time.Duration
+	attempts int
+}
+
+func newsender() *sender {
+	return &sender{client: &http.Client{Timeout: 10 * time.Second}, base: 10 * time.Second, max: time.Hour, attempts: 8}
+}
+
+func (s *sender) backoff(attempts int) time.Duration {
+	b := s.base
+	for i := 1; i < attempts && b < s.max; i++ {
+		b *= 2
+	}
+	if b > s.max {
+		b = s.max
+	}
+	return b
+}
+
+// POST delivery signed by secret. Errors of network, 5xx, 408 and 429 are to be retried
+func (s *sender) send(ctx context.Context, d Delivery, secret string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "crocc-notifier")
+	req.Header.Set(HeaderEvent, d.Type)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderSignature, Sign(secret, d.Body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode >= 500 || res.StatusCode == http.StatusRequestTimeout || res.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("%s: status %d", d.URL, res.StatusCode)
+	}
+	return false, fmt.Errorf("%s: rejected with status %d", d.URL, res.StatusCode)
+}
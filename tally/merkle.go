@@ -0,0 +1,106 @@
+package tally
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strconv"
+)
+
+// Merkle tree over counted votes, as of RFC 6962: leaf hash is sha256(0x00 || data),
+// node hash is sha256(0x01 || left || right), tree of n leaves is split at largest power of 2 below n
+
+// Algorithm of Merkle tree in certificates of results
+const Algorithm = "sha256-rfc6962"
+
+// Canonical encoding of counted vote - netstrings of fields in fixed order:
+// voteid, voter, org, vote, comment, txid, timestamp
+func Canonical(voteid, voter, org, vote, comment, txid, timestamp string) []byte {
+	buf := new(bytes.Buffer)
+	for _, field := range []string{voteid, voter, org, vote, comment, txid, timestamp} {
+		buf.WriteString(strconv.Itoa(len(field)))
+		buf.WriteByte(':')
+		buf.WriteString(field)
+		buf.WriteByte(',')
+	}
+	return buf.Bytes()
+}
+
+// Leaf hash of data
+func Leaf(data []byte) []byte {
+	h := sha256.Sum256(append([]byte{0x00}, data...))
+	return h[:]
+}
+
+// Node hash of two children
+func Node(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, 0x01)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	h := sha256.Sum256(buf)
+	return h[:]
+}
+
+// largest power of 2 less than n, n > 1
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// Root of tree over leaf hashes
+func Root(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leaves[0]
+	}
+
+	k := split(len(leaves))
+	return Node(Root(leaves[:k]), Root(leaves[k:]))
+}
+
+// Path - audit path of leaf with index, from leaf to root
+func Path(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return [][]byte{}
+	}
+
+	k := split(len(leaves))
+	if index < k {
+		return append(Path(leaves[:k], index), Root(leaves[k:]))
+	}
+	return append(Path(leaves[k:], index-k), Root(leaves[:k]))
+}
+
+// Verify audit path of leaf against root, as of RFC 9162 2.1.3.2
+func Verify(leaf []byte, index, size int, path [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range path {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = Node(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = Node(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(r, root)
+}
@@ -0,0 +1,40 @@
+package tally
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestMerkle_Paths(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := [][]byte{}
+		for i := 0; i < size; i++ {
+			leaves = append(leaves, Leaf([]byte(fmt.Sprintf("vote %d", i))))
+		}
+		root := Root(leaves)
+
+		for i := range leaves {
+			path := Path(leaves, i)
+			if !Verify(leaves[i], i, size, path, root) {
+				t.Errorf("size %d index %d: proof is not verified", size, i)
+			}
+			if Verify(Leaf([]byte("forged")), i, size, path, root) {
+				t.Errorf("size %d index %d: forged leaf is verified", size, i)
+			}
+			if size > 1 && Verify(leaves[i], (i+1)%size, size, path, root) {
+				t.Errorf("size %d index %d: proof is verified for other index", size, i)
+			}
+		}
+	}
+}
+
+func TestMerkle_RFC6962(t *testing.T) {
+	// root of empty tree and tree of one empty leaf, RFC 6962 2.1
+	if hex.EncodeToString(Root(nil)) != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("wrong root of empty tree")
+	}
+	if hex.EncodeToString(Root([][]byte{Leaf(nil)})) != "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d" {
+		t.Errorf("wrong root of tree with empty leaf")
+	}
+}
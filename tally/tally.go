@@ -0,0 +1,115 @@
+// Package tally holds rules of counting votes and formats of records of cc_voting chaincode,
+// shared by chaincode and offline tools, so both count the same way
+package tally
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Options of vote in order of reports
+var Options = []string{"yes", "no", "neutral"}
+
+// Count votes per option, every option is in map
+func Count(votes []string) map[string]int {
+	counts := map[string]int{}
+	for _, option := range Options {
+		counts[option] = 0
+	}
+	for _, v := range votes {
+		counts[v]++
+	}
+	return counts
+}
+
+// Decide resolution of voting by counts: yes minus no, equal \ no \ yes
+func Decide(counts map[string]int) (string, error) {
+	resolution := ""
+	err := fmt.Errorf("Error during count results ")
+
+	delta := counts["yes"] - counts["no"]
+	switch {
+	case delta == 0:
+		err = nil
+		resolution = "equal"
+	case delta < 0:
+		err = nil
+		resolution = "no"
+	case delta > 0:
+		err = nil
+		resolution = "yes"
+	}
+
+	return resolution, err
+}
+
+// Decides - decision of counts decides ballot: it is not equal and its option has majority percent
+// of counted votes, any share if majority is 0
+func Decides(counts map[string]int, decision string, majority float64) bool {
+	if decision == "equal" {
+		return false
+	}
+	if majority == 0 {
+		return true
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return float64(counts[decision])*100 >= majority*float64(total)
+}
+
+// Deadline of end date of ballot: dd.mm.yyyy.hh.mm or dd.mm.yyyy (end of day), UTC, or RFC3339
+func Deadline(enddate string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, enddate); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"02.01.2006.15.04", "02.01.2006 15:04"} {
+		if t, err := time.Parse(layout, enddate); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.Parse("02.01.2006", enddate); err == nil {
+		return t.AddDate(0, 0, 1), true
+	}
+	return time.Time{}, false
+}
+
+// Opening of start date of ballot: formats of Deadline, dd.mm.yyyy is start of day
+func Opening(startdate string) (time.Time, bool) {
+	if t, err := time.Parse("02.01.2006", startdate); err == nil {
+		return t, true
+	}
+	return Deadline(startdate)
+}
+
+const trimstring = "{}[]" // symbols of %v formatting
+
+// ParseLegacyBallot - ballot written as {repourl enddate [voters]} before json records
+func ParseLegacyBallot(b []byte) (repourl string, enddate string, voters []string) {
+	fields := strings.Split(string(b), " ")
+
+	repourl = strings.Trim(fields[0], trimstring)
+	if len(fields) > 1 {
+		enddate = fields[1]
+	}
+	for i := 2; i < len(fields); i++ {
+		voters = append(voters, strings.Trim(fields[i], trimstring))
+	}
+	return repourl, enddate, voters
+}
+
+// ParseLegacyVote - vote written as {voter vote comment} before json records
+func ParseLegacyVote(b []byte) (voter string, vote string, comment string) {
+	fields := strings.SplitN(strings.Trim(string(b), trimstring), " ", 3)
+
+	voter = fields[0]
+	if len(fields) > 1 {
+		vote = strings.ToLower(fields[1])
+	}
+	if len(fields) > 2 {
+		comment = fields[2]
+	}
+	return voter, vote, comment
+}
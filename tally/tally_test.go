@@ -0,0 +1,76 @@
+package tally
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	for _, c := range []struct {
+		votes    []string
+		decision string
+	}{
+		{[]string{}, "equal"},
+		{[]string{"yes", "no", "neutral"}, "equal"},
+		{[]string{"yes", "neutral", "neutral"}, "yes"},
+		{[]string{"yes", "no", "no"}, "no"},
+	} {
+		decision, err := Decide(Count(c.votes))
+		if err != nil || decision != c.decision {
+			t.Errorf("%v: expected %s, got %s %v", c.votes, c.decision, decision, err)
+		}
+	}
+
+	for _, c := range []struct {
+		votes    []string
+		majority float64
+		decides  bool
+	}{
+		{[]string{"yes", "no"}, 0, false},
+		{[]string{"yes", "yes", "no"}, 0, true},
+		{[]string{"yes", "yes", "no"}, 70, false},
+		{[]string{"yes", "yes", "no"}, 60, true},
+	} {
+		counts := Count(c.votes)
+		decision, _ := Decide(counts)
+		if decides := Decides(counts, decision, c.majority); decides != c.decides {
+			t.Errorf("%v of majority %v: expected decides %v", c.votes, c.majority, c.decides)
+		}
+	}
+
+	if counts := Count(nil); !reflect.DeepEqual(counts, map[string]int{"yes": 0, "no": 0, "neutral": 0}) {
+		t.Errorf("every option is to be counted: %v", counts)
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	repourl, enddate, voters := ParseLegacyBallot([]byte("{https://git.repo 10.04.2019.10.00 [ca.org1.example.com org2.smple.for.test]}"))
+	if repourl != "https://git.repo" || enddate != "10.04.2019.10.00" || !reflect.DeepEqual(voters, []string{"ca.org1.example.com", "org2.smple.for.test"}) {
+		t.Errorf("wrong ballot: %s %s %v", repourl, enddate, voters)
+	}
+
+	voter, vote, comment := ParseLegacyVote([]byte("{ca.org1.example.com No Because I can}"))
+	if voter != "ca.org1.example.com" || vote != "no" || comment != "Because I can" {
+		t.Errorf("wrong vote: %s %s %s", voter, vote, comment)
+	}
+}
+
+func TestOpening(t *testing.T) {
+	day := time.Date(2019, 4, 10, 0, 0, 0, 0, time.UTC)
+	for date, expected := range map[string]time.Time{
+		"10.04.2019":           day,
+		"10.04.2019.10.00":     day.Add(10 * time.Hour),
+		"2019-04-10T10:00:00Z": day.Add(10 * time.Hour),
+	} {
+		if opening, ok := Opening(date); !ok || !opening.Equal(expected) {
+			t.Errorf("opening of %s: %s, expected %s", date, opening, expected)
+		}
+	}
+	if deadline, _ := Deadline("10.04.2019"); !deadline.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("deadline of day is its end: %s", deadline)
+	}
+	if _, ok := Opening("soon"); ok {
+		t.Errorf("opening of soon")
+	}
+}
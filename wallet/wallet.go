@@ -0,0 +1,235 @@
+// Package wallet loads signing identities of Fabric from MSP directories, as cryptogen and fabric-ca
+// write them, for clients of cc_voting chaincode. Identity signs as Fabric expects: ECDSA over sha256, low-S.
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/fabric/msp"
+	pmsp "github.com/hyperledger/fabric/protos/msp"
+)
+
+// Identity - certificate and private key of MSP member, implements msp.SigningIdentity
+type Identity struct {
+	MspID string
+	Cert  *x509.Certificate
+	key   *ecdsa.PrivateKey
+}
+
+// Load identity of MSP directory: certificate of signcerts and its key of keystore
+func Load(dir string, mspid string) (*Identity, error) {
+	if mspid == "" {
+		return nil, fmt.Errorf("MSP ID of %s is not set", dir)
+	}
+
+	certs, err := pemfiles(filepath.Join(dir, "signcerts"))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate in %s", filepath.Join(dir, "signcerts"))
+	}
+	cert, err := x509.ParseCertificate(certs[0].Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("certificate of %s: %s", dir, err)
+	}
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("certificate of %s: key is not ECDSA", dir)
+	}
+
+	keys, err := pemfiles(filepath.Join(dir, "keystore"))
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range keys {
+		key, err := privatekey(block)
+		if err != nil {
+			continue
+		}
+		if key.X.Cmp(pub.X) == 0 && key.Y.Cmp(pub.Y) == 0 {
+			return &Identity{MspID: mspid, Cert: cert, key: key}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key of certificate %s in %s", cert.Subject.CommonName, filepath.Join(dir, "keystore"))
+}
+
+// Open loads all identities of wallet directory, laid out as dir/<MSP ID>/<name>/ MSP directories.
+// Identities are named <MSP ID>/<name>
+func Open(dir string) (map[string]*Identity, error) {
+	orgs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := map[string]*Identity{}
+	for _, org := range orgs {
+		if !org.IsDir() {
+			continue
+		}
+		members, err := ioutil.ReadDir(filepath.Join(dir, org.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if !m.IsDir() {
+				continue
+			}
+			i, err := Load(filepath.Join(dir, org.Name(), m.Name()), org.Name())
+			if err != nil {
+				return nil, fmt.Errorf("identity %s/%s: %s", org.Name(), m.Name(), err)
+			}
+			identities[org.Name()+"/"+m.Name()] = i
+		}
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no identities in %s", dir)
+	}
+	return identities, nil
+}
+
+// pem blocks of all files of dir
+func pemfiles(dir string) ([]*pem.Block, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []*pem.Block{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if block, _ := pem.Decode(data); block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+// ECDSA key of PKCS8 (cryptogen, fabric-ca) or SEC1 pem block
+func privatekey(block *pem.Block) (*ecdsa.PrivateKey, error) {
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	eckey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key is not ECDSA")
+	}
+	return eckey, nil
+}
+
+// PEM of certificate
+func (i *Identity) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.Cert.Raw})
+}
+
+// ExpiresAt - end of validity of certificate
+func (i *Identity) ExpiresAt() time.Time {
+	return i.Cert.NotAfter
+}
+
+// GetIdentifier - MSP ID and hex of sha256 of certificate
+func (i *Identity) GetIdentifier() *msp.IdentityIdentifier {
+	h := sha256.Sum256(i.Cert.Raw)
+	return &msp.IdentityIdentifier{Mspid: i.MspID, Id: hex.EncodeToString(h[:])}
+}
+
+// GetMSPIdentifier - MSP ID of identity
+func (i *Identity) GetMSPIdentifier() string {
+	return i.MspID
+}
+
+// Validate checks only validity period - chain of certificate is checked by peers
+func (i *Identity) Validate() error {
+	if now := time.Now(); now.Before(i.Cert.NotBefore) || now.After(i.Cert.NotAfter) {
+		return fmt.Errorf("certificate %s is not valid at %s", i.Cert.Subject.CommonName, now.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// GetOrganizationalUnits - OUs of subject of certificate
+func (i *Identity) GetOrganizationalUnits() []*msp.OUIdentifier {
+	ous := []*msp.OUIdentifier{}
+	for _, ou := range i.Cert.Subject.OrganizationalUnit {
+		ous = append(ous, &msp.OUIdentifier{OrganizationalUnitIdentifier: ou})
+	}
+	return ous
+}
+
+// Anonymous is false, identity has certificate
+func (i *Identity) Anonymous() bool {
+	return false
+}
+
+// struct for DER of ECDSA signature
+type ecdsasignature struct {
+	R, S *big.Int
+}
+
+// Verify signature of msg by key of certificate
+func (i *Identity) Verify(msg []byte, sig []byte) error {
+	var s ecdsasignature
+	if _, err := asn1.Unmarshal(sig, &s); err != nil {
+		return fmt.Errorf("bad signature: %s", err)
+	}
+	digest := sha256.Sum256(msg)
+	if !ecdsa.Verify(i.Cert.PublicKey.(*ecdsa.PublicKey), digest[:], s.R, s.S) {
+		return fmt.Errorf("signature is not valid")
+	}
+	return nil
+}
+
+// Serialize as SerializedIdentity - creator of tx
+func (i *Identity) Serialize() ([]byte, error) {
+	return msp.NewSerializedIdentity(i.MspID, i.PEM())
+}
+
+// SatisfiesPrincipal is checked by MSP of peers, not by client
+func (i *Identity) SatisfiesPrincipal(principal *pmsp.MSPPrincipal) error {
+	return fmt.Errorf("principals are not checked by wallet identity")
+}
+
+// Sign msg: ECDSA over sha256 with low S, as Fabric requires
+func (i *Identity) Sign(msg []byte) ([]byte, error) {
+	if i.key == nil {
+		return nil, fmt.Errorf("identity %s has no key", i.Cert.Subject.CommonName)
+	}
+	digest := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, i.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	halforder := new(big.Int).Rsh(i.key.Params().N, 1)
+	if s.Cmp(halforder) > 0 {
+		s.Sub(i.key.Params().N, s)
+	}
+	return asn1.Marshal(ecdsasignature{R: r, S: s})
+}
+
+// GetPublicVersion - identity without key
+func (i *Identity) GetPublicVersion() msp.Identity {
+	return &Identity{MspID: i.MspID, Cert: i.Cert}
+}
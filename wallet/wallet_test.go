@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pmsp "github.com/hyperledger/fabric/protos/msp"
+	"github.com/ioserikov/cc_voting/wallet/wallettest"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := wallettest.MSPDir(dir, "ca.org1.example.com", "User1@org1.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(dir, ""); err == nil {
+		t.Errorf("identity without MSP ID is loaded")
+	}
+
+	i, err := Load(dir, "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Cert.Issuer.CommonName != "ca.org1.example.com" || i.GetMSPIdentifier() != "Org1MSP" || i.Validate() != nil {
+		t.Errorf("wrong identity %s of %s", i.Cert.Subject.CommonName, i.Cert.Issuer.CommonName)
+	}
+
+	sig, err := i.Sign([]byte("proposal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Verify([]byte("proposal"), sig); err != nil {
+		t.Errorf("signature is not verified: %s", err)
+	}
+	if err := i.GetPublicVersion().Verify([]byte("forged"), sig); err == nil {
+		t.Errorf("signature of other message is verified")
+	}
+
+	b, err := i.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := &pmsp.SerializedIdentity{}
+	if err := proto.Unmarshal(b, sid); err != nil || sid.Mspid != "Org1MSP" || string(sid.IdBytes) != string(i.PEM()) {
+		t.Errorf("wrong serialized identity %v %v", sid, err)
+	}
+
+	// key of other certificate is not taken
+	other, _ := ioutil.TempDir("", "wallet")
+	defer os.RemoveAll(other)
+	wallettest.MSPDir(other, "ca.org2.example.com", "User1@org2.example.com")
+	os.Rename(filepath.Join(other, "keystore", "priv_sk"), filepath.Join(dir, "keystore", "priv_sk"))
+	if _, err := Load(dir, "Org1MSP"); err == nil {
+		t.Errorf("identity with key of other certificate is loaded")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := Open(dir); err == nil {
+		t.Errorf("empty wallet is opened")
+	}
+
+	wallettest.MSPDir(filepath.Join(dir, "Org1MSP", "User1"), "ca.org1.example.com", "User1@org1.example.com")
+	wallettest.MSPDir(filepath.Join(dir, "Org2MSP", "Admin"), "ca.org2.example.com", "Admin@org2.example.com")
+	identities, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 2 || identities["Org1MSP/User1"].MspID != "Org1MSP" || identities["Org2MSP/Admin"].Cert.Subject.CommonName != "Admin@org2.example.com" {
+		t.Errorf("wrong identities %v", identities)
+	}
+
+	os.MkdirAll(filepath.Join(dir, "Org3MSP", "Broken"), 0755)
+	if _, err := Open(dir); err == nil {
+		t.Errorf("wallet with broken identity is opened")
+	}
+}
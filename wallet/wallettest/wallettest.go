@@ -0,0 +1,74 @@
+// Package wallettest makes MSP directories for tests of clients of cc_voting chaincode
+package wallettest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MSPDir writes MSP directory in dir: CA with common name ca in cacerts, member certificate
+// signed by it in signcerts and key of member in keystore. Chaincode names voter by common name of CA
+func MSPDir(dir string, ca string, member string) error {
+	cakey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	catemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: ca},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cacert, err := x509.CreateCertificate(rand.Reader, catemplate, catemplate, &cakey.PublicKey, cakey)
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: member, OrganizationalUnit: []string{"client"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, catemplate, &key.PublicKey, cakey)
+	if err != nil {
+		return err
+	}
+	keybytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]*pem.Block{
+		filepath.Join("cacerts", "ca-cert.pem"):        {Type: "CERTIFICATE", Bytes: cacert},
+		filepath.Join("signcerts", member+"-cert.pem"): {Type: "CERTIFICATE", Bytes: cert},
+		filepath.Join("keystore", "priv_sk"):           {Type: "PRIVATE KEY", Bytes: keybytes},
+	}
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}